@@ -28,11 +28,11 @@ func NewAverager(name, desc string, reg Registerer) (Averager, error) {
 func NewAveragerWithErrs(name, desc string, reg Registerer, errs *Errs) Averager {
 	a := averager{
 		count: NewCounter(CounterOpts{
-			Name: AppendNamespace(name, "count"),
+			Name: name + "_count",
 			Help: "Total # of observations of " + desc,
 		}),
 		sum: NewGauge(GaugeOpts{
-			Name: AppendNamespace(name, "sum"),
+			Name: name + "_sum",
 			Help: "Sum of " + desc,
 		}),
 	}
@@ -59,10 +59,8 @@ func NewNoAverager() Averager {
 
 func (noAverager) Observe(float64) {}
 
-// AppendNamespace appends a namespace to a metric name if needed
+// AppendNamespace appends a namespace to a metric name if needed, joined
+// with the separator set by SetNameSeparator.
 func AppendNamespace(namespace, name string) string {
-	if namespace == "" {
-		return name
-	}
-	return namespace + "_" + name
+	return prefixedName(namespace, name)
 }
@@ -0,0 +1,42 @@
+// Copyright (C) 2020-2026, Lux Industries Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package metric
+
+import "fmt"
+
+// BackendKind selects the implementation behind a Factory.
+type BackendKind int
+
+const (
+	// BackendNative is the in-process registry returned by NewRegistry. It
+	// records values when built with the metrics tag and is a no-op otherwise.
+	BackendNative BackendKind = iota
+	// BackendNoop discards everything regardless of build tags.
+	BackendNoop
+)
+
+func (k BackendKind) String() string {
+	switch k {
+	case BackendNative:
+		return "native"
+	case BackendNoop:
+		return "noop"
+	default:
+		return fmt.Sprintf("BackendKind(%d)", int(k))
+	}
+}
+
+// NewFactoryForBackend returns a Factory for the given backend, so
+// config-driven code can select one in a single call. It panics on an
+// unknown kind.
+func NewFactoryForBackend(kind BackendKind) Factory {
+	switch kind {
+	case BackendNative:
+		return NewFactory()
+	case BackendNoop:
+		return NewNoOpFactory()
+	default:
+		panic(fmt.Sprintf("metric: unknown backend %s", kind))
+	}
+}
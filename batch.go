@@ -0,0 +1,110 @@
+// Copyright (C) 2020-2026, Lux Industries Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package metric
+
+import "fmt"
+
+// MetricSpec declares one metric for RegisterBatch. Kind selects the type;
+// a non-empty LabelNames makes it a vec. Buckets and Objectives apply to
+// histograms and summaries as in the matching constructors.
+type MetricSpec struct {
+	Kind       MetricType
+	Name       string
+	Help       string
+	LabelNames []string
+	Buckets    []float64
+	Objectives map[float64]float64
+}
+
+// RegisterBatch creates every metric in specs under a single lock and returns
+// their handles in order: Counter, CounterVec, Gauge, GaugeVec, Histogram,
+// HistogramVec, Summary, SummaryVec, or for MetricTypeUntyped a Gauge. The
+// whole batch is checked first, so on error nothing is registered. As with
+// the constructors, a name already registered with the same type yields the
+// existing metric.
+func (hpr *registry) RegisterBatch(specs []MetricSpec) ([]interface{}, error) {
+	hpr.mu.Lock()
+	defer hpr.mu.Unlock()
+
+	kinds := make(map[string]MetricType, len(specs))
+	helps := make(map[string]string, len(specs))
+	for _, spec := range specs {
+		if spec.Kind < MetricTypeCounter || spec.Kind > MetricTypeUntyped {
+			return nil, fmt.Errorf("metric %q: unknown kind %d", spec.Name, spec.Kind)
+		}
+		existing, ok := kinds[spec.Name]
+		if !ok {
+			existing, ok = hpr.types[spec.Name]
+		}
+		if ok && existing != spec.Kind {
+			return nil, fmt.Errorf("metric %q already registered as %s, not %s", spec.Name, existing.String(), spec.Kind.String())
+		}
+		kinds[spec.Name] = spec.Kind
+		if help, ok := helps[spec.Name]; ok && help != spec.Help {
+			return nil, fmt.Errorf("metric %q declared with help %q and %q", spec.Name, help, spec.Help)
+		}
+		if err := hpr.helpConflictLocked(spec.Name, spec.Help); err != nil {
+			return nil, err
+		}
+		helps[spec.Name] = spec.Help
+		if err := validateLabelNames(spec.Name, spec.LabelNames); err != nil {
+			return nil, err
+		}
+		if spec.Kind == MetricTypeSummary {
+			if err := validateObjectives(spec.Name, spec.Objectives); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	handles := make([]interface{}, len(specs))
+	for i, spec := range specs {
+		hpr.mustClaimName(spec.Name, spec.Kind, spec.Help)
+		handles[i] = hpr.createLocked(spec)
+	}
+	return handles, nil
+}
+
+// createLocked creates the metric spec describes. Callers must hold hpr.mu
+// and have claimed spec.Name.
+func (hpr *registry) createLocked(spec MetricSpec) interface{} {
+	vec := len(spec.LabelNames) > 0
+	if vec {
+		hpr.recordVecLocked(spec.Name, vecSchema{
+			help:       spec.Help,
+			labelNames: spec.LabelNames,
+			buckets:    spec.Buckets,
+			objectives: spec.Objectives,
+		})
+	}
+	switch spec.Kind {
+	case MetricTypeCounter:
+		if vec {
+			return newCounterVec(hpr, spec.Name, spec.Help, spec.LabelNames)
+		}
+		return hpr.counterLocked(spec.Name, spec.Help)
+	case MetricTypeGauge:
+		if vec {
+			return newGaugeVec(hpr, spec.Name, spec.Help, spec.LabelNames)
+		}
+		return hpr.gaugeLocked(spec.Name, spec.Help)
+	case MetricTypeHistogram:
+		if vec {
+			return newHistogramVec(hpr, spec.Name, spec.Help, spec.LabelNames, spec.Buckets)
+		}
+		return hpr.histogramLocked(spec.Name, spec.Help, spec.Buckets)
+	case MetricTypeSummary:
+		if vec {
+			return newSummaryVec(hpr, spec.Name, spec.Help, spec.LabelNames, spec.Objectives)
+		}
+		return hpr.summaryLocked(spec.Name, spec.Help, spec.Objectives)
+	default:
+		if existing, ok := hpr.untyped[spec.Name]; ok {
+			return existing
+		}
+		untyped := &metricUntyped{newGauge(spec.Name, spec.Help)}
+		hpr.untyped[spec.Name] = untyped
+		return untyped
+	}
+}
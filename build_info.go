@@ -0,0 +1,23 @@
+// Copyright (C) 2020-2026, Lux Industries Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package metric
+
+const buildInfoHelp = "A metric with a constant '1' value labeled by build information."
+
+// buildInfoCollector is the Collector returned by NewBuildInfoCollector. The
+// registry turns it into a single labeled gauge on Register.
+type buildInfoCollector struct {
+	name   string
+	labels Labels
+}
+
+// NewBuildInfoCollector returns a Collector that, once registered, exposes
+// a <namespace>_build_info gauge with value 1 labeled by info (e.g. version,
+// commit, goversion). An empty namespace exposes plain build_info.
+func NewBuildInfoCollector(namespace string, info map[string]string) Collector {
+	return &buildInfoCollector{
+		name:   prefixedName(namespace, "build_info"),
+		labels: cloneLabels(info),
+	}
+}
@@ -0,0 +1,76 @@
+// Copyright (C) 2020-2026, Lux Industries Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package metric
+
+import "math"
+
+// ClampedHistogram is a histogram that clamps observations into [min, max]
+// before bucketing, so negative latencies from clock skew or absurd outliers
+// can't pollute the distribution. Each clamped observation is counted in a
+// companion <name>_clamped_total counter.
+type ClampedHistogram struct {
+	h        *metricHistogram
+	clamped  *metricCounter
+	min, max float64
+}
+
+// NewHistogramClamped creates a histogram that clamps observations into
+// [min, max] and registers it and its clamp counter, like NewHistogram, on
+// DefaultRegistry. Creating the same name twice returns the existing
+// histogram. The bounds are swapped if given in the wrong order.
+func NewHistogramClamped(name, help string, buckets []float64, min, max float64) *ClampedHistogram {
+	if min > max {
+		min, max = max, min
+	}
+	return newDerived(name, func() *ClampedHistogram {
+		return &ClampedHistogram{
+			h:       newHistogram(name, help, buckets),
+			clamped: newCounter(name+"_clamped_total", "Observations clamped into range by "+name),
+			min:     min,
+			max:     max,
+		}
+	})
+}
+
+func (ch *ClampedHistogram) registerLocked(hpr *registry) {
+	hpr.mustClaimName(ch.h.name, MetricTypeHistogram, ch.h.help)
+	hpr.mustClaimName(ch.clamped.name, MetricTypeCounter, ch.clamped.help)
+	hpr.histograms[ch.h.name] = map[string]*labeledHistogram{"": {histogram: ch.h}}
+	hpr.counters[ch.clamped.name] = map[string]*labeledCounter{"": {counter: ch.clamped}}
+}
+
+// Observe records val, clamped into [min, max]. NaN is passed through and
+// dropped by the histogram as usual.
+func (ch *ClampedHistogram) Observe(val float64) {
+	if clamped := math.Min(math.Max(val, ch.min), ch.max); clamped != val && !math.IsNaN(val) {
+		ch.clamped.Inc()
+		val = clamped
+	}
+	ch.h.Observe(val)
+}
+
+// Clamped returns the counter of clamped observations.
+func (ch *ClampedHistogram) Clamped() Counter {
+	return ch.clamped
+}
+
+// GetCount returns the total count.
+func (ch *ClampedHistogram) GetCount() uint64 {
+	return ch.h.GetCount()
+}
+
+// GetSum returns the sum of the clamped observations.
+func (ch *ClampedHistogram) GetSum() float64 {
+	return ch.h.GetSum()
+}
+
+// GetCumulativeCounts returns the cumulative bucket counts.
+func (ch *ClampedHistogram) GetCumulativeCounts() []Bucket {
+	return ch.h.GetCumulativeCounts()
+}
+
+// ToMetric returns a Metric representation for exposition.
+func (ch *ClampedHistogram) ToMetric(labels []LabelPair) Metric {
+	return ch.h.ToMetric(labels)
+}
@@ -6,6 +6,7 @@ package metric
 import (
 	"bufio"
 	"bytes"
+	"compress/gzip"
 	"context"
 	"fmt"
 	"io"
@@ -21,20 +22,83 @@ type Client struct {
 	uri string
 }
 
+// DefaultMetricsPath is the path NewClient scrapes.
+const DefaultMetricsPath = "/ext/metrics"
+
 // NewClient returns a new Metrics API Client
 func NewClient(uri string) *Client {
+	return NewClientWithPath(uri, DefaultMetricsPath)
+}
+
+// NewClientWithPath returns a Client that scrapes path on uri, for nodes
+// behind a reverse proxy that mounts metrics elsewhere. An empty path means
+// DefaultMetricsPath.
+func NewClientWithPath(uri, path string) *Client {
+	if path == "" {
+		path = DefaultMetricsPath
+	}
+	if !strings.HasPrefix(path, "/") {
+		path = "/" + path
+	}
 	return &Client{
-		uri: uri + "/ext/metrics",
+		uri: strings.TrimSuffix(uri, "/") + path,
 	}
 }
 
 // GetMetrics returns the metrics from the connected node. The metrics are
 // returned as a map of metric family name to the metric family.
 func (c *Client) GetMetrics(ctx context.Context) (map[string]*MetricFamily, error) {
-	uri, err := url.Parse(c.uri)
+	body, _, err := c.GetRaw(ctx)
 	if err != nil {
 		return nil, err
 	}
+	return ParseText(bytes.NewReader(body))
+}
+
+// GetMetricsFiltered returns only the named metric families. The names are
+// sent as name[] query parameters for endpoints that filter server-side, and
+// the parsed result is filtered as well for endpoints that don't. With no
+// names it returns every family, like GetMetrics.
+func (c *Client) GetMetricsFiltered(ctx context.Context, names ...string) (map[string]*MetricFamily, error) {
+	if len(names) == 0 {
+		return c.GetMetrics(ctx)
+	}
+	query := url.Values{}
+	for _, name := range names {
+		query.Add("name[]", name)
+	}
+	body, _, err := c.getRaw(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	families, err := ParseText(bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	filtered := make(map[string]*MetricFamily, len(names))
+	for _, name := range names {
+		if mf, ok := families[name]; ok {
+			filtered[name] = mf
+		}
+	}
+	return filtered, nil
+}
+
+// GetRaw returns the scrape body from the connected node, without parsing,
+// along with its content type. A gzip-encoded response is decompressed.
+func (c *Client) GetRaw(ctx context.Context) ([]byte, string, error) {
+	return c.getRaw(ctx, nil)
+}
+
+// getRaw is GetRaw with query appended to the request URI.
+func (c *Client) getRaw(ctx context.Context, query url.Values) ([]byte, string, error) {
+	uri, err := url.Parse(c.uri)
+	if err != nil {
+		return nil, "", err
+	}
+	if len(query) > 0 {
+		uri.RawQuery = query.Encode()
+	}
 
 	request, err := http.NewRequestWithContext(
 		ctx,
@@ -43,20 +107,34 @@ func (c *Client) GetMetrics(ctx context.Context) (map[string]*MetricFamily, erro
 		bytes.NewReader(nil),
 	)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create request: %w", err)
+		return nil, "", fmt.Errorf("failed to create request: %w", err)
 	}
+	request.Header.Set("Accept-Encoding", "gzip")
 
 	resp, err := http.DefaultClient.Do(request)
 	if err != nil {
-		return nil, fmt.Errorf("failed to issue request: %w", err)
+		return nil, "", fmt.Errorf("failed to issue request: %w", err)
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("unexpected response code: %d", resp.StatusCode)
+		return nil, "", fmt.Errorf("unexpected response code: %d", resp.StatusCode)
 	}
 
-	return ParseText(resp.Body)
+	var body io.Reader = resp.Body
+	if strings.EqualFold(resp.Header.Get("Content-Encoding"), "gzip") {
+		gz, err := gzip.NewReader(resp.Body)
+		if err != nil {
+			return nil, "", fmt.Errorf("failed to decode gzip response: %w", err)
+		}
+		defer gz.Close()
+		body = gz
+	}
+	raw, err := io.ReadAll(body)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to read response: %w", err)
+	}
+	return raw, resp.Header.Get("Content-Type"), nil
 }
 
 // TextParser parses the metrics text format.
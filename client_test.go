@@ -0,0 +1,112 @@
+// Copyright (C) 2020-2026, Lux Industries Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package metric
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+func TestClientGetRawGzip(t *testing.T) {
+	const body = "# TYPE up gauge\nup 1\n"
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/ext/metrics" {
+			http.NotFound(w, r)
+			return
+		}
+		if r.Header.Get("Accept-Encoding") != "gzip" {
+			t.Errorf("Accept-Encoding = %q, want gzip", r.Header.Get("Accept-Encoding"))
+		}
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+		w.Header().Set("Content-Encoding", "gzip")
+		gz := gzip.NewWriter(w)
+		_, _ = gz.Write([]byte(body))
+		_ = gz.Close()
+	}))
+	defer srv.Close()
+
+	c := NewClient(srv.URL)
+	raw, contentType, err := c.GetRaw(context.Background())
+	if err != nil {
+		t.Fatalf("GetRaw: %v", err)
+	}
+	if !bytes.Equal(raw, []byte(body)) {
+		t.Fatalf("body = %q, want %q", raw, body)
+	}
+	if contentType != "text/plain; version=0.0.4" {
+		t.Fatalf("content type = %q", contentType)
+	}
+
+	families, err := c.GetMetrics(context.Background())
+	if err != nil {
+		t.Fatalf("GetMetrics: %v", err)
+	}
+	if mf, ok := families["up"]; !ok || len(mf.Metrics) != 1 || mf.Metrics[0].Value.Value != 1 {
+		t.Fatalf("unexpected families: %+v", families)
+	}
+}
+
+func TestClientGetMetricsFiltered(t *testing.T) {
+	const body = "# TYPE up gauge\nup 1\n" +
+		"# TYPE go_goroutines gauge\ngo_goroutines 12\n" +
+		"# TYPE queue_depth gauge\nqueue_depth{queue=\"a\"} 3\n"
+	var gotQuery []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Ignore the filter, like an endpoint without server-side support.
+		gotQuery = r.URL.Query()["name[]"]
+		_, _ = w.Write([]byte(body))
+	}))
+	defer srv.Close()
+
+	families, err := NewClient(srv.URL).GetMetricsFiltered(context.Background(), "up", "queue_depth", "missing")
+	if err != nil {
+		t.Fatalf("GetMetricsFiltered: %v", err)
+	}
+	if want := []string{"up", "queue_depth", "missing"}; !reflect.DeepEqual(gotQuery, want) {
+		t.Fatalf("name[] params = %v, want %v", gotQuery, want)
+	}
+	if len(families) != 2 || families["up"] == nil || families["queue_depth"] == nil {
+		t.Fatalf("unexpected families: %+v", families)
+	}
+	if families["queue_depth"].Metrics[0].Value.Value != 3 {
+		t.Fatalf("queue_depth = %+v", families["queue_depth"].Metrics[0])
+	}
+
+	all, err := NewClient(srv.URL).GetMetricsFiltered(context.Background())
+	if err != nil {
+		t.Fatalf("GetMetricsFiltered without names: %v", err)
+	}
+	if len(all) != 3 || gotQuery != nil {
+		t.Fatalf("without names: got %d families and name[] %v, want all 3 and no filter", len(all), gotQuery)
+	}
+}
+
+func TestNewClientWithPath(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/custom/metrics" {
+			http.NotFound(w, r)
+			return
+		}
+		_, _ = w.Write([]byte("# TYPE up gauge\nup 1\n"))
+	}))
+	defer srv.Close()
+
+	for _, path := range []string{"/custom/metrics", "custom/metrics"} {
+		families, err := NewClientWithPath(srv.URL+"/", path).GetMetrics(context.Background())
+		if err != nil {
+			t.Fatalf("path %q: %v", path, err)
+		}
+		if families["up"] == nil {
+			t.Fatalf("path %q: missing up in %+v", path, families)
+		}
+	}
+	if _, err := NewClient(srv.URL).GetMetrics(context.Background()); err == nil {
+		t.Fatal("expected the default path to miss the custom mount")
+	}
+}
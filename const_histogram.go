@@ -0,0 +1,71 @@
+// Copyright (C) 2020-2026, Lux Industries Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package metric
+
+import (
+	"fmt"
+	"math"
+	"sort"
+)
+
+// NewConstHistogram registers a histogram series whose state was computed
+// elsewhere, e.g. from a database query at scrape time, in the manner of
+// prometheus.MustNewConstHistogram. buckets holds cumulative counts; a +Inf
+// bucket is optional since count is the total. Calling it again for the same
+// name and labels replaces the series.
+func (hpr *registry) NewConstHistogram(name, help string, buckets []Bucket, count uint64, sum float64, labels Labels) error {
+	histogram, err := newConstHistogram(name, help, buckets, count, sum)
+	if err != nil {
+		return err
+	}
+	labels, err = hpr.seriesLabels(labels)
+	if err != nil {
+		return err
+	}
+
+	hpr.mu.Lock()
+	defer hpr.mu.Unlock()
+	if err := hpr.helpConflictLocked(name, help); err != nil {
+		return err
+	}
+	if err := hpr.claimName(name, MetricTypeHistogram, help); err != nil {
+		return err
+	}
+	if hpr.histograms[name] == nil {
+		hpr.histograms[name] = make(map[string]*labeledHistogram)
+	}
+	hpr.histograms[name][labelsKeyFromLabels(labels)] = &labeledHistogram{labels: cloneLabels(labels), histogram: histogram}
+	return nil
+}
+
+// newConstHistogram builds a histogram holding the given cumulative counts.
+func newConstHistogram(name, help string, buckets []Bucket, count uint64, sum float64) (*metricHistogram, error) {
+	sorted := make([]Bucket, 0, len(buckets))
+	for _, b := range buckets {
+		if !math.IsInf(b.UpperBound, 1) {
+			sorted = append(sorted, b)
+		}
+	}
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].UpperBound < sorted[j].UpperBound })
+
+	h := &metricHistogram{
+		name:         name,
+		help:         help,
+		buckets:      make([]float64, len(sorted)),
+		bucketCounts: make([]uint64, len(sorted)+1),
+		count:        count,
+		sum:          sum,
+	}
+	var prev uint64
+	for i, b := range sorted {
+		if b.CumulativeCount < prev || b.CumulativeCount > count {
+			return nil, fmt.Errorf("histogram %q: bucket le=%v count %d is not cumulative within total %d", name, b.UpperBound, b.CumulativeCount, count)
+		}
+		h.buckets[i] = b.UpperBound
+		h.bucketCounts[i] = b.CumulativeCount - prev
+		prev = b.CumulativeCount
+	}
+	h.bucketCounts[len(sorted)] = count - prev
+	return h, nil
+}
@@ -5,7 +5,47 @@
 
 package metric
 
-import dto "github.com/luxfi/metric/client"
+import (
+	"encoding/json"
+	"math"
+
+	"google.golang.org/protobuf/encoding/protojson"
+
+	dto "github.com/luxfi/metric/client"
+)
+
+// MarshalFamilies encodes families as a JSON array of client DTO messages,
+// for sending metrics over an existing gRPC or JSON channel. Each element is
+// protojson, so non-finite values such as the +Inf bucket survive.
+func MarshalFamilies(families []*MetricFamily) ([]byte, error) {
+	dtoFamilies := NativeToDTO(families)
+	raw := make([]json.RawMessage, 0, len(dtoFamilies))
+	for _, mf := range dtoFamilies {
+		b, err := protojson.Marshal(mf)
+		if err != nil {
+			return nil, err
+		}
+		raw = append(raw, b)
+	}
+	return json.Marshal(raw)
+}
+
+// UnmarshalFamilies decodes the output of MarshalFamilies.
+func UnmarshalFamilies(data []byte) ([]*MetricFamily, error) {
+	var raw []json.RawMessage
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, err
+	}
+	dtoFamilies := make([]*dto.MetricFamily, 0, len(raw))
+	for _, b := range raw {
+		mf := &dto.MetricFamily{}
+		if err := protojson.Unmarshal(b, mf); err != nil {
+			return nil, err
+		}
+		dtoFamilies = append(dtoFamilies, mf)
+	}
+	return DTOToNative(dtoFamilies), nil
+}
 
 // DTOToNative converts wire MetricFamily slice to native MetricFamily slice.
 // This is used at the RPC boundary when receiving metrics from gRPC.
@@ -21,6 +61,7 @@ func DTOToNative(dtoFamilies []*dto.MetricFamily) []*MetricFamily {
 		mf := &MetricFamily{
 			Name: dtoMF.GetName(),
 			Help: dtoMF.GetHelp(),
+			Unit: dtoMF.GetUnit(),
 			Type: dtoTypeToNative(dtoMF.GetType()),
 		}
 		for _, dtoM := range dtoMF.GetMetric() {
@@ -28,8 +69,9 @@ func DTOToNative(dtoFamilies []*dto.MetricFamily) []*MetricFamily {
 				continue
 			}
 			m := Metric{
-				Labels: dtoLabelsToNative(dtoM.GetLabel()),
-				Value:  dtoValueToNative(dtoM, mf.Type),
+				Labels:      dtoLabelsToNative(dtoM.GetLabel()),
+				Value:       dtoValueToNative(dtoM, mf.Type),
+				TimestampMs: dtoM.GetTimestampMs(),
 			}
 			mf.Metrics = append(mf.Metrics, m)
 		}
@@ -54,6 +96,9 @@ func NativeToDTO(families []*MetricFamily) []*dto.MetricFamily {
 			Help: ptrStr(mf.Help),
 			Type: nativeTypeToDTo(mf.Type),
 		}
+		if mf.Unit != "" {
+			dtoMF.Unit = ptrStr(mf.Unit)
+		}
 		for _, m := range mf.Metrics {
 			dtoM := nativeMetricToDTO(m, mf.Type)
 			dtoMF.Metric = append(dtoMF.Metric, dtoM)
@@ -149,6 +194,7 @@ func dtoValueToNative(m *dto.Metric, t MetricType) MetricValue {
 					})
 				}
 			}
+			dtoNativeHistogramToNative(&v, h)
 		}
 	case MetricTypeSummary:
 		if s := m.GetSummary(); s != nil {
@@ -178,6 +224,9 @@ func nativeMetricToDTO(m Metric, t MetricType) *dto.Metric {
 	dtoM := &dto.Metric{
 		Label: nativeLabelsToDTO(m.Labels),
 	}
+	if m.TimestampMs != 0 {
+		dtoM.TimestampMs = &m.TimestampMs
+	}
 	switch t {
 	case MetricTypeCounter:
 		dtoM.Counter = &dto.Counter{
@@ -192,12 +241,29 @@ func nativeMetricToDTO(m Metric, t MetricType) *dto.Metric {
 			SampleCount: ptrUint64(m.Value.SampleCount),
 			SampleSum:   ptrFloat(m.Value.SampleSum),
 		}
+		if m.Value.IsNativeHistogram() {
+			nativeHistogramToDTO(h, m.Value)
+			dtoM.Histogram = h
+			break
+		}
+		hasInf := false
 		for _, b := range m.Value.Buckets {
+			if math.IsInf(b.UpperBound, 1) {
+				hasInf = true
+			}
 			h.Bucket = append(h.Bucket, &dto.Bucket{
 				UpperBound:      ptrFloat(b.UpperBound),
 				CumulativeCount: ptrUint64(b.CumulativeCount),
 			})
 		}
+		// The wire format requires a terminal +Inf bucket equal to the
+		// sample count; synthesize it when the native family omits it.
+		if !hasInf {
+			h.Bucket = append(h.Bucket, &dto.Bucket{
+				UpperBound:      ptrFloat(math.Inf(1)),
+				CumulativeCount: ptrUint64(m.Value.SampleCount),
+			})
+		}
 		dtoM.Histogram = h
 	case MetricTypeSummary:
 		s := &dto.Summary{
@@ -220,6 +286,52 @@ func nativeMetricToDTO(m Metric, t MetricType) *dto.Metric {
 	return dtoM
 }
 
+// nativeHistogramToDTO copies the native histogram fields of v onto h.
+func nativeHistogramToDTO(h *dto.Histogram, v MetricValue) {
+	h.Schema = &v.Schema
+	h.ZeroThreshold = ptrFloat(v.ZeroThreshold)
+	h.ZeroCount = ptrUint64(v.ZeroCount)
+	h.PositiveSpan = nativeSpansToDTO(v.PositiveSpans)
+	h.PositiveDelta = v.PositiveDeltas
+	h.NegativeSpan = nativeSpansToDTO(v.NegativeSpans)
+	h.NegativeDelta = v.NegativeDeltas
+}
+
+// dtoNativeHistogramToNative is the reverse of nativeHistogramToDTO.
+func dtoNativeHistogramToNative(v *MetricValue, h *dto.Histogram) {
+	v.Schema = h.GetSchema()
+	v.ZeroThreshold = h.GetZeroThreshold()
+	v.ZeroCount = h.GetZeroCount()
+	v.PositiveSpans = dtoSpansToNative(h.GetPositiveSpan())
+	v.PositiveDeltas = h.GetPositiveDelta()
+	v.NegativeSpans = dtoSpansToNative(h.GetNegativeSpan())
+	v.NegativeDeltas = h.GetNegativeDelta()
+}
+
+func dtoSpansToNative(spans []*dto.BucketSpan) []BucketSpan {
+	if len(spans) == 0 {
+		return nil
+	}
+	result := make([]BucketSpan, 0, len(spans))
+	for _, s := range spans {
+		if s != nil {
+			result = append(result, BucketSpan{Offset: s.GetOffset(), Length: s.GetLength()})
+		}
+	}
+	return result
+}
+
+func nativeSpansToDTO(spans []BucketSpan) []*dto.BucketSpan {
+	if len(spans) == 0 {
+		return nil
+	}
+	result := make([]*dto.BucketSpan, len(spans))
+	for i, s := range spans {
+		result[i] = &dto.BucketSpan{Offset: &s.Offset, Length: &s.Length}
+	}
+	return result
+}
+
 func ptrStr(s string) *string {
 	return &s
 }
@@ -0,0 +1,170 @@
+//go:build grpc
+
+// Copyright (C) 2020-2026, Lux Industries Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package metric
+
+import (
+	"bufio"
+	"bytes"
+	"math"
+	"reflect"
+	"testing"
+
+	"google.golang.org/protobuf/encoding/protodelim"
+
+	dto "github.com/luxfi/metric/client"
+)
+
+func TestNativeToDTOSynthesizesInfBucket(t *testing.T) {
+	families := []*MetricFamily{{
+		Name: "latency_seconds",
+		Type: MetricTypeHistogram,
+		Metrics: []Metric{{Value: MetricValue{
+			SampleCount: 5,
+			SampleSum:   2.5,
+			Buckets:     []Bucket{{UpperBound: 0.1, CumulativeCount: 2}, {UpperBound: 1, CumulativeCount: 4}},
+		}}},
+	}}
+
+	buckets := NativeToDTO(families)[0].GetMetric()[0].GetHistogram().GetBucket()
+	if len(buckets) != 3 {
+		t.Fatalf("expected 3 buckets, got %d", len(buckets))
+	}
+	last := buckets[len(buckets)-1]
+	if !math.IsInf(last.GetUpperBound(), 1) {
+		t.Fatalf("last bucket upper bound: got %v, want +Inf", last.GetUpperBound())
+	}
+	if last.GetCumulativeCount() != 5 {
+		t.Fatalf("+Inf bucket count: got %d, want sample count 5", last.GetCumulativeCount())
+	}
+
+	// An explicit +Inf bucket must not be duplicated.
+	families[0].Metrics[0].Value.Buckets = append(families[0].Metrics[0].Value.Buckets, Bucket{UpperBound: math.Inf(1), CumulativeCount: 5})
+	if got := len(NativeToDTO(families)[0].GetMetric()[0].GetHistogram().GetBucket()); got != 3 {
+		t.Fatalf("expected explicit +Inf to be kept as-is, got %d buckets", got)
+	}
+}
+
+func TestNativeToDTONativeHistogram(t *testing.T) {
+	nh := NewNativeHistogram("native_dto_seconds", "latency", 2, 0)
+	nh.Observe(1)
+	nh.Observe(-3)
+	families := []*MetricFamily{{
+		Name:    "native_dto_seconds",
+		Type:    MetricTypeHistogram,
+		Metrics: []Metric{nh.ToMetric(nil)},
+	}}
+
+	h := NativeToDTO(families)[0].GetMetric()[0].GetHistogram()
+	if h.GetSchema() != 2 {
+		t.Fatalf("schema: got %d, want 2", h.GetSchema())
+	}
+	if len(h.GetBucket()) != 0 {
+		t.Fatalf("native histogram should carry no classic buckets, got %d", len(h.GetBucket()))
+	}
+	if len(h.GetPositiveSpan()) != 1 || len(h.GetNegativeSpan()) != 1 {
+		t.Fatalf("spans: got %d positive, %d negative", len(h.GetPositiveSpan()), len(h.GetNegativeSpan()))
+	}
+}
+
+func TestNativeToDTOTimestamp(t *testing.T) {
+	families := []*MetricFamily{{
+		Name:    "backfill_total",
+		Type:    MetricTypeCounter,
+		Metrics: []Metric{{Value: MetricValue{Value: 1}, TimestampMs: 1700000000000}},
+	}}
+	dtoFamilies := NativeToDTO(families)
+	if got := dtoFamilies[0].GetMetric()[0].GetTimestampMs(); got != 1700000000000 {
+		t.Fatalf("timestamp: got %d, want 1700000000000", got)
+	}
+	if got := DTOToNative(dtoFamilies)[0].Metrics[0].TimestampMs; got != 1700000000000 {
+		t.Fatalf("round-trip timestamp: got %d, want 1700000000000", got)
+	}
+}
+
+func TestEncodeProtobuf(t *testing.T) {
+	var buf bytes.Buffer
+	if err := encodeFormat(&buf, FormatProtobuf, testFamilies()); err != nil {
+		t.Fatalf("encode: %v", err)
+	}
+	var mf dto.MetricFamily
+	if err := protodelim.UnmarshalFrom(bufio.NewReader(&buf), &mf); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if mf.GetName() != "requests_total" || mf.GetMetric()[0].GetCounter().GetValue() != 1 {
+		t.Fatalf("decoded %v", &mf)
+	}
+}
+
+func TestMarshalFamiliesRoundTrip(t *testing.T) {
+	families := []*MetricFamily{
+		{
+			Name: "requests_total", Help: "requests", Type: MetricTypeCounter,
+			Metrics: []Metric{{Labels: []LabelPair{{Name: "code", Value: "200"}}, Value: MetricValue{Value: 3}}},
+		},
+		{
+			Name: "latency_seconds", Help: "latency", Type: MetricTypeHistogram, Unit: "seconds",
+			Metrics: []Metric{{Value: MetricValue{
+				SampleCount: 4,
+				SampleSum:   2.5,
+				Buckets: []Bucket{
+					{UpperBound: 0.5, CumulativeCount: 2},
+					{UpperBound: math.Inf(1), CumulativeCount: 4},
+				},
+			}}},
+		},
+		{
+			Name: "rtt_seconds", Help: "rtt", Type: MetricTypeHistogram,
+			Metrics: []Metric{{Value: MetricValue{
+				SampleCount:    6,
+				SampleSum:      3,
+				Schema:         3,
+				ZeroThreshold:  1e-9,
+				ZeroCount:      1,
+				PositiveSpans:  []BucketSpan{{Offset: -2, Length: 2}, {Offset: 1, Length: 1}},
+				PositiveDeltas: []int64{2, -1, 1},
+				NegativeSpans:  []BucketSpan{{Offset: 0, Length: 1}},
+				NegativeDeltas: []int64{1},
+			}}},
+		},
+	}
+
+	data, err := MarshalFamilies(families)
+	if err != nil {
+		t.Fatalf("marshal: %v", err)
+	}
+	got, err := UnmarshalFamilies(data)
+	if err != nil {
+		t.Fatalf("unmarshal: %v\n%s", err, data)
+	}
+	if len(got) != 3 {
+		t.Fatalf("got %d families, want 3", len(got))
+	}
+	counter := got[0]
+	if counter.Name != "requests_total" || counter.Type != MetricTypeCounter || counter.Help != "requests" {
+		t.Fatalf("counter family = %+v", counter)
+	}
+	if m := counter.Metrics[0]; m.Value.Value != 3 || len(m.Labels) != 1 || m.Labels[0] != (LabelPair{Name: "code", Value: "200"}) {
+		t.Fatalf("counter metric = %+v", m)
+	}
+	hist := got[1]
+	if hist.Type != MetricTypeHistogram || hist.Unit != "seconds" {
+		t.Fatalf("histogram family = %+v", hist)
+	}
+	v := hist.Metrics[0].Value
+	if v.SampleCount != 4 || v.SampleSum != 2.5 {
+		t.Fatalf("histogram value = %+v", v)
+	}
+	if last := v.Buckets[len(v.Buckets)-1]; !math.IsInf(last.UpperBound, 1) || last.CumulativeCount != 4 {
+		t.Fatalf("+Inf bucket = %+v", last)
+	}
+	if native := got[2].Metrics[0].Value; !reflect.DeepEqual(native, families[2].Metrics[0].Value) {
+		t.Fatalf("native histogram = %+v, want %+v", native, families[2].Metrics[0].Value)
+	}
+
+	if _, err := UnmarshalFamilies([]byte("{")); err == nil {
+		t.Fatal("expected an error for malformed input")
+	}
+}
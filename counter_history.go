@@ -0,0 +1,88 @@
+// Copyright (C) 2020-2026, Lux Industries Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package metric
+
+import (
+	"sync"
+	"time"
+)
+
+// Sample is a value at a point in time, such as one recorded counter
+// increment.
+type Sample struct {
+	Time  time.Time
+	Value float64
+}
+
+// HistoryCounter is a counter that remembers its most recent increments in a
+// fixed-size ring, for tracking down what caused a spike.
+type HistoryCounter struct {
+	c *metricCounter
+
+	mu   sync.Mutex
+	ring []Sample
+	next int
+	full bool
+	now  func() time.Time
+}
+
+// NewCounterWithHistory creates a counter that keeps the last historySize
+// increments and registers it, like NewCounter, on DefaultRegistry. Creating
+// the same name twice returns the existing counter. A historySize below 1
+// keeps one.
+func NewCounterWithHistory(name, help string, historySize int) *HistoryCounter {
+	if historySize < 1 {
+		historySize = 1
+	}
+	return newDerived(name, func() *HistoryCounter {
+		return &HistoryCounter{
+			c:    newCounter(name, help),
+			ring: make([]Sample, historySize),
+			now:  time.Now,
+		}
+	})
+}
+
+func (hc *HistoryCounter) registerLocked(hpr *registry) {
+	hpr.mustClaimName(hc.c.name, MetricTypeCounter, hc.c.help)
+	hpr.counters[hc.c.name] = map[string]*labeledCounter{"": {counter: hc.c}}
+}
+
+// Inc increments the counter by 1.
+func (hc *HistoryCounter) Inc() {
+	hc.Add(1)
+}
+
+// Add adds val to the counter and records it, overwriting the oldest sample
+// once the ring is full.
+func (hc *HistoryCounter) Add(val float64) {
+	hc.c.Add(val)
+	now := hc.now()
+
+	hc.mu.Lock()
+	hc.ring[hc.next] = Sample{Time: now, Value: val}
+	hc.next++
+	if hc.next == len(hc.ring) {
+		hc.next = 0
+		hc.full = true
+	}
+	hc.mu.Unlock()
+}
+
+// Get returns the current value.
+func (hc *HistoryCounter) Get() float64 {
+	return hc.c.Get()
+}
+
+// History returns the recorded increments, oldest first.
+func (hc *HistoryCounter) History() []Sample {
+	hc.mu.Lock()
+	defer hc.mu.Unlock()
+	if !hc.full {
+		return append([]Sample(nil), hc.ring[:hc.next]...)
+	}
+	history := make([]Sample, 0, len(hc.ring))
+	history = append(history, hc.ring[hc.next:]...)
+	return append(history, hc.ring[:hc.next]...)
+}
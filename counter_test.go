@@ -2,7 +2,11 @@
 
 package metric
 
-import "testing"
+import (
+	"sync"
+	"testing"
+	"time"
+)
 
 func TestCounterBasic(t *testing.T) {
 	reg := NewRegistry()
@@ -42,3 +46,190 @@ func TestCounterVec(t *testing.T) {
 		t.Fatalf("missing POST/500 metric")
 	}
 }
+
+func TestCounterVecGetMetricWith(t *testing.T) {
+	reg := NewRegistry()
+	cv := reg.NewCounterVec("checked_total", "checked", []string{"method", "code"})
+
+	if _, err := cv.GetMetricWithLabelValues("GET"); err == nil {
+		t.Fatal("expected an error for too few label values")
+	}
+	if _, err := cv.GetMetricWith(Labels{"method": "GET", "status": "200"}); err == nil {
+		t.Fatal("expected an error for an unknown label name")
+	}
+	c, err := cv.GetMetricWithLabelValues("GET", "200")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	c.Inc()
+	if same, _ := cv.GetMetricWith(Labels{"method": "GET", "code": "200"}); same != c {
+		t.Fatal("expected GetMetricWith to return the existing child")
+	}
+
+	cur := cv.MustCurryWith(Labels{"method": "POST"})
+	if _, err := cur.GetMetricWithLabelValues("200", "extra"); err == nil {
+		t.Fatal("expected an error for too many curried label values")
+	}
+}
+
+func TestCounterVecConcurrentSameLabels(t *testing.T) {
+	const (
+		goroutines = 64
+		incs       = 1000
+	)
+	reg := NewRegistry()
+	vec := reg.NewCounterVec("hits_total", "hits", []string{"route", "code"})
+
+	var (
+		wg     sync.WaitGroup
+		first  = make([]Counter, goroutines)
+		gather sync.WaitGroup
+		stop   = make(chan struct{})
+	)
+	gather.Add(1)
+	go func() {
+		defer gather.Done()
+		for {
+			select {
+			case <-stop:
+				return
+			default:
+				if _, err := reg.Gather(); err != nil {
+					t.Errorf("gather: %v", err)
+					return
+				}
+			}
+		}
+	}()
+	for g := 0; g < goroutines; g++ {
+		wg.Add(1)
+		go func(g int) {
+			defer wg.Done()
+			for i := 0; i < incs; i++ {
+				var c Counter
+				if i%2 == 0 {
+					c = vec.WithLabelValues("/api", "200")
+				} else {
+					c = vec.With(Labels{"route": "/api", "code": "200"})
+				}
+				if i == 0 {
+					first[g] = c
+				}
+				c.Inc()
+			}
+		}(g)
+	}
+	wg.Wait()
+	close(stop)
+	gather.Wait()
+
+	for g, c := range first {
+		if c != first[0] {
+			t.Fatalf("goroutine %d got a different counter for the same labels", g)
+		}
+	}
+	if got := first[0].Get(); got != goroutines*incs {
+		t.Fatalf("counter = %v, want %d", got, goroutines*incs)
+	}
+}
+
+func TestLabelsKeyFromValues(t *testing.T) {
+	names := []string{"method", "code", "a"}
+	order := sortedLabelOrder(names)
+	for _, values := range [][]string{
+		{"GET", "200", "x"},
+		{"GET"},
+		{"GET", "200", "x", "extra"},
+		{},
+	} {
+		want := labelsKeyFromLabels(labelsFromValues(names, values))
+		if got := labelsKeyFromValues(names, order, values); got != want {
+			t.Fatalf("labelsKeyFromValues(%q) = %q, want %q", values, got, want)
+		}
+	}
+}
+
+func BenchmarkCounterVecWithLabelValues(b *testing.B) {
+	cv := NewRegistry().NewCounterVec("bench_total", "bench", []string{"method", "code"})
+	cv.WithLabelValues("GET", "200")
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		cv.WithLabelValues("GET", "200").Inc()
+	}
+}
+
+func BenchmarkCurriedCounterVecWithLabelValues(b *testing.B) {
+	cv := NewRegistry().NewCounterVec("bench_total", "bench", []string{"method", "code"})
+	cur := cv.MustCurryWith(Labels{"method": "GET"})
+	cur.WithLabelValues("200")
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		cur.WithLabelValues("200").Inc()
+	}
+}
+
+func TestCounterWithHistory(t *testing.T) {
+	hc := NewCounterWithHistory("events_total", "events", 3)
+	clock := time.Unix(1000, 0)
+	hc.now = func() time.Time {
+		clock = clock.Add(time.Second)
+		return clock
+	}
+
+	hc.Add(1)
+	hc.Add(2)
+	if got := hc.History(); len(got) != 2 || got[0].Value != 1 || got[1].Value != 2 {
+		t.Fatalf("partial history = %+v", got)
+	}
+
+	for _, v := range []float64{3, 4, 5} {
+		hc.Add(v)
+	}
+	got := hc.History()
+	if len(got) != 3 {
+		t.Fatalf("history length = %d, want 3", len(got))
+	}
+	for i, want := range []float64{3, 4, 5} {
+		if got[i].Value != want {
+			t.Fatalf("sample %d = %v, want %v (history %+v)", i, got[i].Value, want, got)
+		}
+		if wantTime := time.Unix(1000+int64(want), 0); !got[i].Time.Equal(wantTime) {
+			t.Fatalf("sample %d time = %v, want %v", i, got[i].Time, wantTime)
+		}
+	}
+	if hc.Get() != 15 {
+		t.Fatalf("value = %v, want 15", hc.Get())
+	}
+	if again := NewCounterWithHistory("events_total", "events", 3); again != hc {
+		t.Fatal("creating the same name twice should return the existing counter")
+	}
+	if got := findFamily(t, gatherFamilies(t, DefaultRegistry), "events_total").Metrics[0].Value.Value; got != 15 {
+		t.Fatalf("gathered value = %v, want 15", got)
+	}
+}
+
+func TestCounterVecPrewarm(t *testing.T) {
+	reg := NewRegistry()
+	cv := reg.NewCounterVec("prewarmed_total", "help", []string{"method", "code"})
+	sets := [][]string{{"get", "200"}, {"get", "500"}, {"post", "200"}}
+	if err := cv.Prewarm(sets); err != nil {
+		t.Fatalf("Prewarm: %v", err)
+	}
+
+	f := findFamily(t, gatherFamilies(t, reg), "prewarmed_total")
+	if len(f.Metrics) != len(sets) {
+		t.Fatalf("expected %d prewarmed series, got %+v", len(sets), f.Metrics)
+	}
+	for _, set := range sets {
+		m, ok := findMetricWithLabels(f, Labels{"method": set[0], "code": set[1]})
+		if !ok || m.Value.Value != 0 {
+			t.Fatalf("series %v: got %+v (found %v), want value 0", set, m, ok)
+		}
+	}
+
+	if err := cv.Prewarm([][]string{{"get"}}); err == nil {
+		t.Fatal("expected an error for a short label value set")
+	}
+}
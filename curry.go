@@ -34,94 +34,229 @@ func curryRemaining(all []string, fixed Labels) []string {
 	return rem
 }
 
+// curriedValues maps a curried vec's remaining label values onto the base
+// vec's full value list, so WithLabelValues can take the base vec's map-free
+// lookup instead of building and merging two Labels maps per call.
+type curriedValues struct {
+	template []string // base label values with the fixed ones filled in
+	slots    []int    // base index of each remaining label
+}
+
+// newCurriedValues returns nil when fixed names a label the base vec does not
+// declare, leaving such vecs on the map-based path.
+func newCurriedValues(all []string, fixed Labels) *curriedValues {
+	cv := &curriedValues{template: make([]string, len(all))}
+	found := 0
+	for i, name := range all {
+		if value, ok := fixed[name]; ok {
+			cv.template[i] = value
+			found++
+		} else {
+			cv.slots = append(cv.slots, i)
+		}
+	}
+	if found != len(fixed) {
+		return nil
+	}
+	return cv
+}
+
+// fill returns the base vec's values for the given remaining values, or false
+// when the fast path doesn't apply.
+func (cv *curriedValues) fill(values []string) ([]string, bool) {
+	if cv == nil || len(values) != len(cv.slots) {
+		return nil, false
+	}
+	full := make([]string, len(cv.template))
+	copy(full, cv.template)
+	for i, slot := range cv.slots {
+		full[slot] = values[i]
+	}
+	return full, true
+}
+
 // --- counter ---
 
 func (v *counterVec) MustCurryWith(labels Labels) CounterVec {
-	return &curriedCounterVec{base: v, fixed: cloneLabels(labels), remaining: curryRemaining(v.labelNames, labels)}
+	return &curriedCounterVec{
+		base:      v,
+		fixed:     cloneLabels(labels),
+		remaining: curryRemaining(v.labelNames, labels),
+		values:    newCurriedValues(v.labelNames, labels),
+	}
 }
 
 type curriedCounterVec struct {
 	base      *counterVec
 	fixed     Labels
 	remaining []string
+	values    *curriedValues
 }
 
 func (c *curriedCounterVec) With(labels Labels) Counter {
 	return c.base.With(mergeLabels(c.fixed, labels))
 }
 func (c *curriedCounterVec) WithLabelValues(values ...string) Counter {
+	if full, ok := c.values.fill(values); ok {
+		return c.base.WithLabelValues(full...)
+	}
 	return c.base.With(mergeLabels(c.fixed, labelsFromValues(c.remaining, values)))
 }
+func (c *curriedCounterVec) GetMetricWith(labels Labels) (Counter, error) {
+	return c.base.GetMetricWith(mergeLabels(c.fixed, labels))
+}
+func (c *curriedCounterVec) GetMetricWithLabelValues(values ...string) (Counter, error) {
+	labels, err := labelsFromValuesChecked(c.remaining, values)
+	if err != nil {
+		return nil, err
+	}
+	return c.base.GetMetricWith(mergeLabels(c.fixed, labels))
+}
 func (c *curriedCounterVec) MustCurryWith(labels Labels) CounterVec {
 	return c.base.MustCurryWith(mergeLabels(c.fixed, labels))
 }
+func (c *curriedCounterVec) DeletePartialMatch(labels Labels) int {
+	return c.base.DeletePartialMatch(mergeLabels(c.fixed, labels))
+}
 func (c *curriedCounterVec) Reset() { c.base.Reset() }
+func (c *curriedCounterVec) Prewarm(valueSets [][]string) error {
+	return prewarm(valueSets, c.GetMetricWithLabelValues)
+}
 
 // --- gauge ---
 
 func (v *gaugeVec) MustCurryWith(labels Labels) GaugeVec {
-	return &curriedGaugeVec{base: v, fixed: cloneLabels(labels), remaining: curryRemaining(v.labelNames, labels)}
+	return &curriedGaugeVec{
+		base:      v,
+		fixed:     cloneLabels(labels),
+		remaining: curryRemaining(v.labelNames, labels),
+		values:    newCurriedValues(v.labelNames, labels),
+	}
 }
 
 type curriedGaugeVec struct {
 	base      *gaugeVec
 	fixed     Labels
 	remaining []string
+	values    *curriedValues
 }
 
 func (c *curriedGaugeVec) With(labels Labels) Gauge {
 	return c.base.With(mergeLabels(c.fixed, labels))
 }
 func (c *curriedGaugeVec) WithLabelValues(values ...string) Gauge {
+	if full, ok := c.values.fill(values); ok {
+		return c.base.WithLabelValues(full...)
+	}
 	return c.base.With(mergeLabels(c.fixed, labelsFromValues(c.remaining, values)))
 }
+func (c *curriedGaugeVec) GetMetricWith(labels Labels) (Gauge, error) {
+	return c.base.GetMetricWith(mergeLabels(c.fixed, labels))
+}
+func (c *curriedGaugeVec) GetMetricWithLabelValues(values ...string) (Gauge, error) {
+	labels, err := labelsFromValuesChecked(c.remaining, values)
+	if err != nil {
+		return nil, err
+	}
+	return c.base.GetMetricWith(mergeLabels(c.fixed, labels))
+}
 func (c *curriedGaugeVec) MustCurryWith(labels Labels) GaugeVec {
 	return c.base.MustCurryWith(mergeLabels(c.fixed, labels))
 }
+func (c *curriedGaugeVec) DeletePartialMatch(labels Labels) int {
+	return c.base.DeletePartialMatch(mergeLabels(c.fixed, labels))
+}
 func (c *curriedGaugeVec) Reset() { c.base.Reset() }
+func (c *curriedGaugeVec) Prewarm(valueSets [][]string) error {
+	return prewarm(valueSets, c.GetMetricWithLabelValues)
+}
 
 // --- histogram ---
 
 func (v *histogramVec) MustCurryWith(labels Labels) HistogramVec {
-	return &curriedHistogramVec{base: v, fixed: cloneLabels(labels), remaining: curryRemaining(v.labelNames, labels)}
+	return &curriedHistogramVec{
+		base:      v,
+		fixed:     cloneLabels(labels),
+		remaining: curryRemaining(v.labelNames, labels),
+		values:    newCurriedValues(v.labelNames, labels),
+	}
 }
 
 type curriedHistogramVec struct {
 	base      *histogramVec
 	fixed     Labels
 	remaining []string
+	values    *curriedValues
 }
 
 func (c *curriedHistogramVec) With(labels Labels) Histogram {
 	return c.base.With(mergeLabels(c.fixed, labels))
 }
 func (c *curriedHistogramVec) WithLabelValues(values ...string) Histogram {
+	if full, ok := c.values.fill(values); ok {
+		return c.base.WithLabelValues(full...)
+	}
 	return c.base.With(mergeLabels(c.fixed, labelsFromValues(c.remaining, values)))
 }
+func (c *curriedHistogramVec) GetMetricWith(labels Labels) (Histogram, error) {
+	return c.base.GetMetricWith(mergeLabels(c.fixed, labels))
+}
+func (c *curriedHistogramVec) GetMetricWithLabelValues(values ...string) (Histogram, error) {
+	labels, err := labelsFromValuesChecked(c.remaining, values)
+	if err != nil {
+		return nil, err
+	}
+	return c.base.GetMetricWith(mergeLabels(c.fixed, labels))
+}
 func (c *curriedHistogramVec) MustCurryWith(labels Labels) HistogramVec {
 	return c.base.MustCurryWith(mergeLabels(c.fixed, labels))
 }
 func (c *curriedHistogramVec) Reset() { c.base.Reset() }
+func (c *curriedHistogramVec) Prewarm(valueSets [][]string) error {
+	return prewarm(valueSets, c.GetMetricWithLabelValues)
+}
 
 // --- summary ---
 
 func (v *summaryVec) MustCurryWith(labels Labels) SummaryVec {
-	return &curriedSummaryVec{base: v, fixed: cloneLabels(labels), remaining: curryRemaining(v.labelNames, labels)}
+	return &curriedSummaryVec{
+		base:      v,
+		fixed:     cloneLabels(labels),
+		remaining: curryRemaining(v.labelNames, labels),
+		values:    newCurriedValues(v.labelNames, labels),
+	}
 }
 
 type curriedSummaryVec struct {
 	base      *summaryVec
 	fixed     Labels
 	remaining []string
+	values    *curriedValues
 }
 
 func (c *curriedSummaryVec) With(labels Labels) Summary {
 	return c.base.With(mergeLabels(c.fixed, labels))
 }
 func (c *curriedSummaryVec) WithLabelValues(values ...string) Summary {
+	if full, ok := c.values.fill(values); ok {
+		return c.base.WithLabelValues(full...)
+	}
 	return c.base.With(mergeLabels(c.fixed, labelsFromValues(c.remaining, values)))
 }
+func (c *curriedSummaryVec) GetMetricWith(labels Labels) (Summary, error) {
+	return c.base.GetMetricWith(mergeLabels(c.fixed, labels))
+}
+func (c *curriedSummaryVec) GetMetricWithLabelValues(values ...string) (Summary, error) {
+	labels, err := labelsFromValuesChecked(c.remaining, values)
+	if err != nil {
+		return nil, err
+	}
+	return c.base.GetMetricWith(mergeLabels(c.fixed, labels))
+}
 func (c *curriedSummaryVec) MustCurryWith(labels Labels) SummaryVec {
 	return c.base.MustCurryWith(mergeLabels(c.fixed, labels))
 }
 func (c *curriedSummaryVec) Reset() { c.base.Reset() }
+func (c *curriedSummaryVec) Prewarm(valueSets [][]string) error {
+	return prewarm(valueSets, c.GetMetricWithLabelValues)
+}
@@ -74,3 +74,23 @@ func TestDefaultRegistererGatherer(t *testing.T) {
 		t.Fatal("instrumented handler must be non-nil")
 	}
 }
+
+// TestCurriedWithLabelValuesIdentity verifies that repeated WithLabelValues
+// calls on a curried vec return the same child as the base vec, including
+// when the curried label is not the first declared one.
+func TestCurriedWithLabelValuesIdentity(t *testing.T) {
+	reg := NewRegistry()
+	vec := reg.NewHistogramVec("curry_seconds", "help", []string{"route", "code", "method"}, nil)
+
+	cur := vec.MustCurryWith(Labels{"code": "200"})
+	first := cur.WithLabelValues("/api", "GET")
+	if again := cur.WithLabelValues("/api", "GET"); again != first {
+		t.Fatal("repeated WithLabelValues returned a different child")
+	}
+	if base := vec.WithLabelValues("/api", "200", "GET"); base != first {
+		t.Fatal("curried child differs from the base vec's child")
+	}
+	if other := cur.WithLabelValues("/api", "POST"); other == first {
+		t.Fatal("different label values returned the same child")
+	}
+}
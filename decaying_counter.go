@@ -0,0 +1,79 @@
+// Copyright (C) 2020-2026, Lux Industries Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package metric
+
+import (
+	"math"
+	"sync"
+	"time"
+)
+
+// DecayingCounter is a counter whose value halves every half-life, giving a
+// cheap "recent activity" signal, e.g. for spike detection, without a rate()
+// query. Decay is applied lazily from the elapsed time whenever the value is
+// changed or read. It is exposed as a gauge.
+type DecayingCounter struct {
+	halfLife time.Duration
+	now      func() time.Time
+	gauge    *metricGauge // exposes Get on DefaultRegistry, nil in tests
+
+	mu    sync.Mutex
+	value float64
+	last  time.Time
+}
+
+// NewDecayingCounter creates a decaying counter and registers it, like
+// NewGauge, as a gauge named name that reports the decayed value at each
+// gather. Creating the same name twice returns the existing counter. A
+// non-positive halfLife disables decay.
+func NewDecayingCounter(name, help string, halfLife time.Duration) *DecayingCounter {
+	return newDerived(name, func() *DecayingCounter {
+		dc := newDecayingCounter(halfLife, time.Now)
+		dc.gauge = newGauge(name, help)
+		dc.gauge.read = dc.Get
+		return dc
+	})
+}
+
+func (dc *DecayingCounter) registerLocked(hpr *registry) {
+	hpr.mustClaimName(dc.gauge.name, MetricTypeGauge, dc.gauge.help)
+	hpr.gauges[dc.gauge.name] = map[string]*labeledGauge{"": {gauge: dc.gauge}}
+}
+
+func newDecayingCounter(halfLife time.Duration, now func() time.Time) *DecayingCounter {
+	return &DecayingCounter{halfLife: halfLife, now: now, last: now()}
+}
+
+// Inc increments the counter by 1.
+func (dc *DecayingCounter) Inc() {
+	dc.Add(1)
+}
+
+// Add decays the counter to now and adds val.
+func (dc *DecayingCounter) Add(val float64) {
+	dc.mu.Lock()
+	defer dc.mu.Unlock()
+	dc.decayLocked()
+	dc.value += val
+}
+
+// Get returns the value decayed to now.
+func (dc *DecayingCounter) Get() float64 {
+	dc.mu.Lock()
+	defer dc.mu.Unlock()
+	dc.decayLocked()
+	return dc.value
+}
+
+// decayLocked applies the decay since the last update. Callers must hold
+// dc.mu.
+func (dc *DecayingCounter) decayLocked() {
+	now := dc.now()
+	elapsed := now.Sub(dc.last)
+	if elapsed <= 0 || dc.halfLife <= 0 {
+		return
+	}
+	dc.value *= math.Exp2(-float64(elapsed) / float64(dc.halfLife))
+	dc.last = now
+}
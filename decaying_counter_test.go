@@ -0,0 +1,53 @@
+// Copyright (C) 2020-2026, Lux Industries Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package metric
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestDecayingCounter(t *testing.T) {
+	clock := time.Unix(1000, 0)
+	dc := newDecayingCounter(time.Minute, func() time.Time { return clock })
+
+	dc.Add(100)
+	clock = clock.Add(time.Minute)
+	if got := dc.Get(); math.Abs(got-50) > 1e-9 {
+		t.Fatalf("after one half-life: got %v, want 50", got)
+	}
+
+	// Increments land on the decayed value.
+	dc.Inc()
+	clock = clock.Add(2 * time.Minute)
+	if got := dc.Get(); math.Abs(got-51.0/4) > 1e-9 {
+		t.Fatalf("after two more half-lives: got %v, want %v", got, 51.0/4)
+	}
+}
+
+func TestDecayingCounterGathered(t *testing.T) {
+	clock := time.Unix(1000, 0)
+	dc := newDecayingCounter(time.Second, func() time.Time { return clock })
+	gauge := newGauge("recent_errors", "recent errors")
+	gauge.read = dc.Get
+
+	reg := newRegistry()
+	if err := reg.Register(gauge); err != nil {
+		t.Fatalf("Register: %v", err)
+	}
+	dc.Add(8)
+	clock = clock.Add(3 * time.Second)
+
+	families, err := reg.Gather()
+	if err != nil {
+		t.Fatalf("Gather: %v", err)
+	}
+	if len(families) != 1 || families[0].Type != MetricTypeGauge {
+		t.Fatalf("expected one gauge family, got %+v", families)
+	}
+	if got := families[0].Metrics[0].Value.Value; math.Abs(got-1) > 1e-9 {
+		t.Fatalf("gathered %v, want the decayed 1", got)
+	}
+}
@@ -3,5 +3,25 @@
 
 package metric
 
-// DefBuckets defines default histogram buckets.
-var DefBuckets = []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10}
+// defaultBuckets is the pristine default layout. It is never handed out
+// directly, so callers mutating DefBuckets or a DefaultBuckets result can't
+// change what later histograms fall back to.
+var defaultBuckets = [...]float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10}
+
+// DefBuckets defines default histogram buckets. The layout is the same as
+// prometheus.DefBuckets, and it is what a histogram created with nil or
+// empty buckets uses on every backend. Kept for compatibility; prefer
+// DefaultBuckets, which returns a private copy.
+var DefBuckets = DefaultBuckets()
+
+// DefaultBuckets returns a fresh copy of the default histogram buckets.
+func DefaultBuckets() []float64 {
+	return append([]float64(nil), defaultBuckets[:]...)
+}
+
+// DefaultObjectives returns a fresh copy of the default summary objectives,
+// mapping each quantile to its allowed error. Summaries created without
+// objectives use these.
+func DefaultObjectives() map[float64]float64 {
+	return map[float64]float64{0.5: 0.05, 0.9: 0.01, 0.99: 0.001}
+}
@@ -0,0 +1,51 @@
+// Copyright (C) 2020-2026, Lux Industries Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package metric
+
+import "fmt"
+
+// derivedMetric is a handle built on native metrics, such as a
+// SampledHistogram, that package-level constructors register on
+// DefaultRegistry. registerLocked claims its family names and adds its
+// native metrics; callers hold hpr.mu.
+type derivedMetric interface {
+	registerLocked(hpr *registry)
+}
+
+// derive returns the handle an earlier derive registered under name, or
+// registers the one create builds. Panics if name is taken by a metric that
+// didn't come from derive.
+func (hpr *registry) derive(name string, create func() derivedMetric) derivedMetric {
+	hpr.mu.Lock()
+	defer hpr.mu.Unlock()
+	if existing, ok := hpr.derived[name]; ok {
+		return existing
+	}
+	if typ, ok := hpr.types[name]; ok {
+		panic(fmt.Sprintf("metric %q already registered as %s", name, typ.String()))
+	}
+	d := create()
+	d.registerLocked(hpr)
+	hpr.derived[name] = d
+	return d
+}
+
+// newDerived registers the handle create builds on DefaultRegistry, like the
+// New* constructors: creating the same name twice returns the existing
+// handle, and a name taken by another metric panics. Registries that can't
+// hold derived metrics get an unregistered handle.
+func newDerived[T derivedMetric](name string, create func() T) T {
+	reg, ok := DefaultRegistry.(interface {
+		derive(string, func() derivedMetric) derivedMetric
+	})
+	if !ok {
+		return create()
+	}
+	d := reg.derive(name, func() derivedMetric { return create() })
+	handle, ok := d.(T)
+	if !ok {
+		panic(fmt.Sprintf("metric %q already registered as a %T", name, d))
+	}
+	return handle
+}
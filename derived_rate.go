@@ -0,0 +1,94 @@
+// Copyright (C) 2020-2026, Lux Industries Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package metric
+
+import (
+	"sync"
+	"time"
+)
+
+// DerivedRateGauge exposes the per-second rate of a counter as a gauge,
+// sampling the counter once per window, for dashboards that want a
+// precomputed rate.
+type DerivedRateGauge struct {
+	gauge  Gauge
+	source Counter
+	now    func() time.Time
+
+	mu       sync.Mutex
+	last     float64
+	lastTime time.Time
+
+	stop     chan struct{}
+	stopOnce sync.Once
+}
+
+// NewDerivedRateGauge creates a gauge, like NewGauge, that is set every
+// window to the per-second increase of source over that window. A decrease
+// of source is taken as a counter reset, as Prometheus rate() does: the
+// increase over that window is the counter's new value. Call Stop to end
+// sampling.
+func NewDerivedRateGauge(name, help string, source Counter, window time.Duration) *DerivedRateGauge {
+	d := newDerivedRateGauge(NewGauge(GaugeOpts{Name: name, Help: help}), source, time.Now)
+	if window > 0 {
+		go d.run(window)
+	}
+	return d
+}
+
+func newDerivedRateGauge(gauge Gauge, source Counter, now func() time.Time) *DerivedRateGauge {
+	return &DerivedRateGauge{
+		gauge:    gauge,
+		source:   source,
+		now:      now,
+		last:     source.Get(),
+		lastTime: now(),
+		stop:     make(chan struct{}),
+	}
+}
+
+func (d *DerivedRateGauge) run(window time.Duration) {
+	ticker := time.NewTicker(window)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			d.Sample()
+		case <-d.stop:
+			return
+		}
+	}
+}
+
+// Sample reads the source counter and updates the gauge with the rate since
+// the previous sample. It is called every window, and may be called directly
+// to refresh the rate early.
+func (d *DerivedRateGauge) Sample() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	now, value := d.now(), d.source.Get()
+	elapsed := now.Sub(d.lastTime).Seconds()
+	if elapsed <= 0 {
+		return
+	}
+	increase := value - d.last
+	if value < d.last {
+		// The counter was reset and restarted from zero, so everything it
+		// holds now was added since the previous sample.
+		increase = value
+	}
+	d.gauge.Set(increase / elapsed)
+	d.last, d.lastTime = value, now
+}
+
+// Gauge returns the gauge holding the rate.
+func (d *DerivedRateGauge) Gauge() Gauge {
+	return d.gauge
+}
+
+// Stop ends periodic sampling. It is safe to call more than once.
+func (d *DerivedRateGauge) Stop() {
+	d.stopOnce.Do(func() { close(d.stop) })
+}
@@ -0,0 +1,49 @@
+// Copyright (C) 2020-2026, Lux Industries Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package metric
+
+import (
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestDerivedRateGauge(t *testing.T) {
+	clock := time.Unix(1000, 0)
+	source := newCounter("requests_total", "requests")
+	d := newDerivedRateGauge(newGauge("requests_per_second", "rate"), source, func() time.Time { return clock })
+
+	advance := func(by time.Duration, incs float64) {
+		clock = clock.Add(by)
+		source.Add(incs)
+		d.Sample()
+	}
+
+	advance(10*time.Second, 50)
+	if got := d.Gauge().Get(); got != 5 {
+		t.Fatalf("rate = %v, want 5", got)
+	}
+	advance(5*time.Second, 5)
+	if got := d.Gauge().Get(); got != 1 {
+		t.Fatalf("rate = %v, want 1", got)
+	}
+
+	// After a reset the counter's new value is the increase, not a negative
+	// rate.
+	atomic.StoreUint64(&source.value, 0)
+	advance(5*time.Second, 10)
+	if got := d.Gauge().Get(); got != 2 {
+		t.Fatalf("rate after reset = %v, want 2", got)
+	}
+	advance(2*time.Second, 8)
+	if got := d.Gauge().Get(); got != 4 {
+		t.Fatalf("rate after new baseline = %v, want 4", got)
+	}
+}
+
+func TestDerivedRateGaugeStop(t *testing.T) {
+	d := NewDerivedRateGauge("stop_rate", "rate", newCounter("stop_total", ""), time.Millisecond)
+	d.Stop()
+	d.Stop()
+}
@@ -0,0 +1,88 @@
+// Copyright (C) 2020-2026, Lux Industries Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package metric
+
+import "sort"
+
+// Describe returns the schema of every family, sorted by name, without
+// reading any values. Vecs report the label names they were created with,
+// even before their first series; other families report the union of their
+// series' label names.
+func (hpr *registry) Describe() []MetricDescriptor {
+	hpr.mu.RLock()
+	defer hpr.mu.RUnlock()
+
+	kinds := make(map[string]MetricType, len(hpr.types))
+	help := make(map[string]string, len(hpr.types))
+	series := make(map[string][]Labels)
+	for name, typ := range hpr.types {
+		kinds[name] = typ
+	}
+	for name, entries := range hpr.counters {
+		for _, e := range entries {
+			kinds[name], help[name] = MetricTypeCounter, e.counter.help
+			series[name] = append(series[name], e.labels)
+		}
+	}
+	for name, entries := range hpr.gauges {
+		for _, e := range entries {
+			kinds[name], help[name] = MetricTypeGauge, e.gauge.help
+			series[name] = append(series[name], e.labels)
+		}
+	}
+	for name, entries := range hpr.histograms {
+		for _, e := range entries {
+			kinds[name], help[name] = MetricTypeHistogram, e.histogram.help
+			series[name] = append(series[name], e.labels)
+		}
+	}
+	for name, entries := range hpr.summaries {
+		for _, e := range entries {
+			kinds[name], help[name] = MetricTypeSummary, e.summary.help
+			series[name] = append(series[name], e.labels)
+		}
+	}
+	for name, untyped := range hpr.untyped {
+		kinds[name], help[name] = MetricTypeUntyped, untyped.help
+	}
+	for name, nh := range hpr.natives {
+		kinds[name], help[name] = MetricTypeHistogram, nh.help
+	}
+
+	descriptors := make([]MetricDescriptor, 0, len(kinds))
+	for _, name := range sortedKeys(kinds) {
+		vec, isVec := hpr.vecs[name]
+		if isVec {
+			help[name] = vec.help
+		}
+		d := MetricDescriptor{Name: name, Help: hpr.familyHelp(name, help[name]), Type: kinds[name]}
+		if isVec {
+			d.LabelNames = append([]string(nil), vec.labelNames...)
+		} else {
+			d.LabelNames = seriesLabelNames(series[name])
+		}
+		descriptors = append(descriptors, d)
+	}
+	return descriptors
+}
+
+// seriesLabelNames returns the sorted union of the label names in series, or
+// nil if there are none.
+func seriesLabelNames(series []Labels) []string {
+	seen := make(map[string]struct{})
+	for _, labels := range series {
+		for name := range labels {
+			seen[name] = struct{}{}
+		}
+	}
+	if len(seen) == 0 {
+		return nil
+	}
+	names := make([]string, 0, len(seen))
+	for name := range seen {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,160 @@
+// Copyright (C) 2020-2026, Lux Industries Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package metric
+
+import (
+	"encoding/json"
+	"io"
+	"mime"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Format is an exposition format, identified by its response content type.
+type Format string
+
+const (
+	// FormatText is the Prometheus text format, served to any client that
+	// doesn't ask for something else.
+	FormatText Format = "text/plain; version=0.0.4; charset=utf-8"
+	// FormatOpenMetrics is the OpenMetrics text format.
+	FormatOpenMetrics Format = OpenMetricsContentType
+	// FormatProtobuf is length-delimited io.prometheus.client.MetricFamily
+	// messages. It is only negotiated in builds with the grpc tag, which
+	// carry the protobuf types.
+	FormatProtobuf Format = "application/vnd.google.protobuf; proto=io.prometheus.client.MetricFamily; encoding=delimited"
+	// FormatJSON is the JSON encoding written by EncodeJSON. It is never
+	// negotiated; the handler serves it for ?debug=json.
+	FormatJSON Format = "application/json; charset=utf-8"
+)
+
+// debugFormat returns the format forced by a ?debug= query, for reading a
+// scrape in a browser: "text" or "prometheus" for the text format and "json"
+// for EncodeJSON.
+func debugFormat(r *http.Request) (Format, bool) {
+	switch r.URL.Query().Get("debug") {
+	case "text", "prometheus":
+		return FormatText, true
+	case "json":
+		return FormatJSON, true
+	default:
+		return "", false
+	}
+}
+
+// NegotiateFormat picks the exposition format for r from its Accept header,
+// preferring higher q values and then header order. OpenMetrics is only
+// chosen when enableOpenMetrics is set; anything unsupported falls back to
+// FormatText.
+func NegotiateFormat(r *http.Request, enableOpenMetrics bool) Format {
+	type candidate struct {
+		format Format
+		q      float64
+	}
+	var candidates []candidate
+	for _, part := range strings.Split(r.Header.Get("Accept"), ",") {
+		mediaType, params, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err != nil {
+			continue
+		}
+		q := 1.0
+		if v, ok := params["q"]; ok {
+			if q, err = strconv.ParseFloat(v, 64); err != nil || q <= 0 {
+				continue
+			}
+		}
+		var format Format
+		switch {
+		case mediaType == "application/vnd.google.protobuf" &&
+			params["proto"] == "io.prometheus.client.MetricFamily" &&
+			params["encoding"] == "delimited":
+			if !canEncodeProtobuf {
+				continue
+			}
+			format = FormatProtobuf
+		case mediaType == "application/openmetrics-text":
+			if !enableOpenMetrics {
+				continue
+			}
+			format = FormatOpenMetrics
+		case mediaType == "text/plain", mediaType == "text/*", mediaType == "*/*":
+			format = FormatText
+		default:
+			continue
+		}
+		candidates = append(candidates, candidate{format: format, q: q})
+	}
+	sort.SliceStable(candidates, func(i, j int) bool { return candidates[i].q > candidates[j].q })
+	if len(candidates) == 0 {
+		return FormatText
+	}
+	return candidates[0].format
+}
+
+// encodeFormat encodes families in format.
+func encodeFormat(w io.Writer, format Format, families []*MetricFamily) error {
+	switch format {
+	case FormatOpenMetrics:
+		return EncodeOpenMetrics(w, families)
+	case FormatProtobuf:
+		return encodeProtobuf(w, families)
+	case FormatJSON:
+		return EncodeJSON(w, families)
+	default:
+		return EncodeText(w, families)
+	}
+}
+
+// encodeFormatFlushing is encodeFormat that flushes f after each family, so
+// the client receives the first families while the rest are still being
+// encoded. JSON is a single document and is flushed only by the server.
+func encodeFormatFlushing(w io.Writer, f http.Flusher, format Format, families []*MetricFamily) error {
+	switch format {
+	case FormatOpenMetrics:
+		return encodeOpenMetrics(w, families, f.Flush)
+	case FormatJSON:
+		return EncodeJSON(w, families)
+	}
+	for _, mf := range families {
+		if mf == nil {
+			continue
+		}
+		if err := encodeFormat(w, format, []*MetricFamily{mf}); err != nil {
+			return err
+		}
+		f.Flush()
+	}
+	return nil
+}
+
+// encodeFamily writes a single family in format, for callers that stream a
+// gather. OpenMetrics output still needs its "# EOF" line once the last
+// family is written. JSON is a single document and can't be streamed.
+func encodeFamily(w io.Writer, format Format, mf *MetricFamily) error {
+	if format != FormatOpenMetrics {
+		return encodeFormat(w, format, []*MetricFamily{mf})
+	}
+	if err := mf.validateUnit(); err != nil {
+		return err
+	}
+	ew := &errWriter{w: w}
+	writeOpenMetricsFamily(ew, mf)
+	return ew.err
+}
+
+// EncodeJSON writes families as a JSON array in the MetricFamilyWire shape
+// the ZAP exporter ships.
+func EncodeJSON(w io.Writer, families []*MetricFamily) error {
+	wire := make([]MetricFamilyWire, 0, len(families))
+	for _, mf := range families {
+		if mf != nil {
+			wire = append(wire, translateFamily(mf))
+		}
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(wire)
+}
@@ -0,0 +1,25 @@
+//go:build grpc
+
+// Copyright (C) 2020-2026, Lux Industries Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package metric
+
+import (
+	"io"
+
+	"google.golang.org/protobuf/encoding/protodelim"
+)
+
+// canEncodeProtobuf reports whether FormatProtobuf can be served.
+const canEncodeProtobuf = true
+
+// encodeProtobuf writes families as length-delimited MetricFamily messages.
+func encodeProtobuf(w io.Writer, families []*MetricFamily) error {
+	for _, mf := range NativeToDTO(families) {
+		if _, err := protodelim.MarshalTo(w, mf); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,19 @@
+//go:build !grpc
+
+// Copyright (C) 2020-2026, Lux Industries Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package metric
+
+import (
+	"errors"
+	"io"
+)
+
+// canEncodeProtobuf reports whether FormatProtobuf can be served. Without the
+// grpc tag the protobuf types aren't built in.
+const canEncodeProtobuf = false
+
+func encodeProtobuf(io.Writer, []*MetricFamily) error {
+	return errors.New("protobuf exposition requires the grpc build tag")
+}
@@ -0,0 +1,99 @@
+// Copyright (C) 2020-2026, Lux Industries Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package metric
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestNegotiateFormat(t *testing.T) {
+	const (
+		protobufAccept = "application/vnd.google.protobuf;proto=io.prometheus.client.MetricFamily;encoding=delimited;q=0.7,text/plain;version=0.0.4;q=0.3"
+		openMetrics    = "application/openmetrics-text;version=1.0.0;q=0.9,text/plain;q=0.5"
+	)
+	wantProtobuf := FormatText
+	if canEncodeProtobuf {
+		wantProtobuf = FormatProtobuf
+	}
+	tests := []struct {
+		name              string
+		accept            string
+		enableOpenMetrics bool
+		want              Format
+	}{
+		{name: "no accept", want: FormatText},
+		{name: "text", accept: "text/plain;version=0.0.4", want: FormatText},
+		{name: "openmetrics", accept: openMetrics, enableOpenMetrics: true, want: FormatOpenMetrics},
+		{name: "openmetrics disabled", accept: openMetrics, want: FormatText},
+		{name: "protobuf", accept: protobufAccept, want: wantProtobuf},
+		{name: "q ordering", accept: "text/plain;q=0.2," + openMetrics, enableOpenMetrics: true, want: FormatOpenMetrics},
+		{name: "unsupported", accept: "application/json", want: FormatText},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/metrics", nil)
+			if tt.accept != "" {
+				r.Header.Set("Accept", tt.accept)
+			}
+			if got := NegotiateFormat(r, tt.enableOpenMetrics); got != tt.want {
+				t.Fatalf("NegotiateFormat = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHandlerServesOpenMetrics(t *testing.T) {
+	h := HandlerForWithOpts(testFamilies(), HandlerOpts{EnableOpenMetrics: true})
+	r := httptest.NewRequest(http.MethodGet, "/metrics", nil)
+	r.Header.Set("Accept", "application/openmetrics-text;version=1.0.0")
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, r)
+	if got := rec.Header().Get("Content-Type"); got != string(FormatOpenMetrics) {
+		t.Fatalf("Content-Type = %q", got)
+	}
+	if !strings.HasSuffix(rec.Body.String(), "# EOF\n") {
+		t.Fatalf("body is not OpenMetrics:\n%s", rec.Body.String())
+	}
+}
+
+func TestHandlerDebugQuery(t *testing.T) {
+	h := HandlerForWithOpts(testFamilies(), HandlerOpts{EnableOpenMetrics: true})
+	serve := func(query string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(http.MethodGet, "/metrics"+query, nil)
+		r.Header.Set("Accept", "application/openmetrics-text;version=1.0.0")
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, r)
+		return rec
+	}
+
+	for _, query := range []string{"?debug=text", "?debug=prometheus"} {
+		rec := serve(query)
+		if got := rec.Header().Get("Content-Type"); got != string(FormatText) {
+			t.Fatalf("%s: Content-Type = %q", query, got)
+		}
+		if strings.Contains(rec.Body.String(), "# EOF") {
+			t.Fatalf("%s: got OpenMetrics despite the debug query:\n%s", query, rec.Body.String())
+		}
+	}
+
+	rec := serve("?debug=json")
+	if got := rec.Header().Get("Content-Type"); got != string(FormatJSON) {
+		t.Fatalf("Content-Type = %q", got)
+	}
+	var families []MetricFamilyWire
+	if err := json.Unmarshal(rec.Body.Bytes(), &families); err != nil {
+		t.Fatalf("body is not JSON: %v\n%s", err, rec.Body.String())
+	}
+	if len(families) != 1 || families[0].Name != "requests_total" || *families[0].Metrics[0].Value != 1 {
+		t.Fatalf("unexpected families: %+v", families)
+	}
+
+	if got := serve("?debug=other").Header().Get("Content-Type"); got != string(FormatOpenMetrics) {
+		t.Fatalf("unknown debug value should negotiate as usual, got %q", got)
+	}
+}
@@ -4,6 +4,8 @@
 package metric
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"sort"
 	"sync"
@@ -14,6 +16,34 @@ type Gatherer interface {
 	Gather() ([]*MetricFamily, error)
 }
 
+// StreamingGatherer is a Gatherer that can also hand out families one at a
+// time, so large registries can be encoded without building the full slice.
+type StreamingGatherer interface {
+	Gatherer
+	// GatherFunc calls fn with each family in Gather order. An error from fn
+	// stops iteration and is returned.
+	GatherFunc(fn func(*MetricFamily) error) error
+}
+
+// GatherResult is the outcome of a deadline-bounded gather.
+type GatherResult struct {
+	Families []*MetricFamily
+	// Partial reports that some gatherers hadn't finished when the context
+	// ended; Families holds only the ones that had.
+	Partial bool
+}
+
+// ContextGatherer is a Gatherer that can stop at a context's end and return
+// what it has so far. The MultiGatherers in this package implement it.
+type ContextGatherer interface {
+	Gatherer
+	GatherContext(ctx context.Context) (GatherResult, error)
+}
+
+// GatherIncompleteMetricName is the gauge family the handler appends when a
+// ContextGatherer returns a partial result.
+const GatherIncompleteMetricName = "metric_gather_incomplete"
+
 // Gatherers is a helper type for slices of gatherers.
 type Gatherers []Gatherer
 
@@ -39,20 +69,39 @@ func NewMultiGatherer() MultiGatherer {
 	}
 }
 
+// NewBestEffortMultiGatherer returns a MultiGatherer like NewMultiGatherer
+// that keeps going when a gatherer fails: it returns the families of every
+// healthy gatherer along with the failures joined by errors.Join, so one
+// broken source leaves the rest of a dashboard populated.
+func NewBestEffortMultiGatherer() MultiGatherer {
+	return &multiGatherer{
+		gatherers:  make(map[string]Gatherer),
+		bestEffort: true,
+	}
+}
+
 type multiGatherer struct {
-	lock      sync.RWMutex
-	gatherers map[string]Gatherer
+	lock       sync.RWMutex
+	gatherers  map[string]Gatherer
+	bestEffort bool // continue past failing gatherers
 }
 
 func (g *multiGatherer) Gather() ([]*MetricFamily, error) {
 	g.lock.RLock()
 	defer g.lock.RUnlock()
 
-	var result []*MetricFamily
-	for _, gatherer := range g.gatherers {
+	var (
+		result []*MetricFamily
+		errs   []error
+	)
+	for namespace, gatherer := range g.gatherers {
 		metrics, err := gatherer.Gather()
 		if err != nil {
-			return nil, err
+			if !g.bestEffort {
+				return nil, err
+			}
+			errs = append(errs, fmt.Errorf("gathering %q: %w", namespace, err))
+			continue
 		}
 		result = append(result, metrics...)
 	}
@@ -61,7 +110,64 @@ func (g *multiGatherer) Gather() ([]*MetricFamily, error) {
 		return result[i].Name < result[j].Name
 	})
 
-	return result, nil
+	return result, errors.Join(errs...)
+}
+
+// GatherContext gathers every registered gatherer concurrently and returns
+// once all have finished or ctx ends, whichever is first. Gatherers still
+// running are abandoned and the result is marked Partial. Failures are
+// handled as in Gather.
+func (g *multiGatherer) GatherContext(ctx context.Context) (GatherResult, error) {
+	return g.gatherContext(ctx, func(_ string, gatherer Gatherer) ([]*MetricFamily, error) {
+		return gatherer.Gather()
+	})
+}
+
+// gatherContext runs gather for each registered gatherer in its own
+// goroutine. The channel is buffered so abandoned gathers can still exit.
+func (g *multiGatherer) gatherContext(ctx context.Context, gather func(namespace string, gatherer Gatherer) ([]*MetricFamily, error)) (GatherResult, error) {
+	type result struct {
+		namespace string
+		families  []*MetricFamily
+		err       error
+	}
+	g.lock.RLock()
+	done := make(chan result, len(g.gatherers))
+	for namespace, gatherer := range g.gatherers {
+		go func() {
+			families, err := gather(namespace, gatherer)
+			done <- result{namespace: namespace, families: families, err: err}
+		}()
+	}
+	pending := len(g.gatherers)
+	g.lock.RUnlock()
+
+	var (
+		res  GatherResult
+		errs []error
+	)
+collect:
+	for ; pending > 0; pending-- {
+		select {
+		case r := <-done:
+			if r.err != nil {
+				if !g.bestEffort {
+					return GatherResult{}, r.err
+				}
+				errs = append(errs, fmt.Errorf("gathering %q: %w", r.namespace, r.err))
+				continue
+			}
+			res.Families = append(res.Families, r.families...)
+		case <-ctx.Done():
+			res.Partial = true
+			break collect
+		}
+	}
+
+	sort.Slice(res.Families, func(i, j int) bool {
+		return res.Families[i].Name < res.Families[j].Name
+	})
+	return res, errors.Join(errs...)
 }
 
 func (g *multiGatherer) Register(namespace string, gatherer Gatherer) error {
@@ -112,14 +218,10 @@ func (g *prefixGatherer) Gather() ([]*MetricFamily, error) {
 
 	var result []*MetricFamily
 	for namespace, gatherer := range g.gatherers {
-		metrics, err := gatherer.Gather()
+		metrics, err := gatherPrefixed(namespace, gatherer)
 		if err != nil {
 			return nil, err
 		}
-		for _, mf := range metrics {
-			prefixedName := namespace + "_" + mf.Name
-			mf.Name = prefixedName
-		}
 		result = append(result, metrics...)
 	}
 
@@ -129,3 +231,202 @@ func (g *prefixGatherer) Gather() ([]*MetricFamily, error) {
 
 	return result, nil
 }
+
+// GatherContext is Gather bounded by ctx, as in multiGatherer.GatherContext.
+func (g *prefixGatherer) GatherContext(ctx context.Context) (GatherResult, error) {
+	return g.gatherContext(ctx, gatherPrefixed)
+}
+
+// gatherPrefixed gathers from gatherer and prefixes each family name with
+// namespace. The families are copied, so a gatherer that hands out the same
+// families on every call isn't prefixed twice.
+func gatherPrefixed(namespace string, gatherer Gatherer) ([]*MetricFamily, error) {
+	metrics, err := gatherer.Gather()
+	if err != nil {
+		return nil, err
+	}
+	result := make([]*MetricFamily, 0, len(metrics))
+	for _, mf := range metrics {
+		prefixed := *mf
+		prefixed.Name = namespace + "_" + mf.Name
+		result = append(result, &prefixed)
+	}
+	return result, nil
+}
+
+// NewLabelsGatherer returns a new MultiGatherer that adds labels to every
+// metric of every registered gatherer in one pass, e.g. region and zone for a
+// whole process. Gather fails if a metric already carries one of the labels
+// or a label name is invalid.
+func NewLabelsGatherer(labels Labels) MultiGatherer {
+	return &labelsGatherer{
+		multiGatherer: multiGatherer{
+			gatherers: make(map[string]Gatherer),
+		},
+		labels: labelsToLabelPairs(labels),
+	}
+}
+
+type labelsGatherer struct {
+	multiGatherer
+	labels []LabelPair // sorted by name
+}
+
+func (g *labelsGatherer) Gather() ([]*MetricFamily, error) {
+	for _, l := range g.labels {
+		if err := ValidateLabelName(l.Name); err != nil {
+			return nil, err
+		}
+	}
+
+	families, err := g.multiGatherer.Gather()
+	if err != nil {
+		return nil, err
+	}
+	return addLabels(families, g.labels)
+}
+
+// GatherContext is Gather bounded by ctx, as in multiGatherer.GatherContext.
+func (g *labelsGatherer) GatherContext(ctx context.Context) (GatherResult, error) {
+	for _, l := range g.labels {
+		if err := ValidateLabelName(l.Name); err != nil {
+			return GatherResult{}, err
+		}
+	}
+
+	res, err := g.multiGatherer.GatherContext(ctx)
+	if err != nil {
+		return GatherResult{}, err
+	}
+	if res.Families, err = addLabels(res.Families, g.labels); err != nil {
+		return GatherResult{}, err
+	}
+	return res, nil
+}
+
+// addLabels returns copies of families with labels added to every metric. A
+// metric that already carries one of the labels is an error.
+func addLabels(families []*MetricFamily, labels []LabelPair) ([]*MetricFamily, error) {
+	result := make([]*MetricFamily, 0, len(families))
+	for _, mf := range families {
+		labeled := *mf
+		labeled.Metrics = make([]Metric, len(mf.Metrics))
+		for i, m := range mf.Metrics {
+			for _, existing := range m.Labels {
+				for _, l := range labels {
+					if existing.Name == l.Name {
+						return nil, fmt.Errorf("metric %q already has label %q", mf.Name, l.Name)
+					}
+				}
+			}
+			m.Labels = append(append(make([]LabelPair, 0, len(m.Labels)+len(labels)), m.Labels...), labels...)
+			labeled.Metrics[i] = m
+		}
+		result = append(result, &labeled)
+	}
+	return result, nil
+}
+
+// TimedGatherer returns a Gatherer that records how long each Gather of g
+// takes, in seconds, into h, passing its result through. Wrapping each
+// source of a MultiGatherer shows which one dominates scrape time.
+func TimedGatherer(g Gatherer, h Histogram) Gatherer {
+	return &timedGatherer{gatherer: g, histogram: h}
+}
+
+type timedGatherer struct {
+	gatherer  Gatherer
+	histogram Histogram
+}
+
+func (g *timedGatherer) Gather() ([]*MetricFamily, error) {
+	defer StartTimer(g.histogram)()
+	return g.gatherer.Gather()
+}
+
+// SeriesTruncatedMetricName is the gauge family LimitGatherer appends when it
+// drops series.
+const SeriesTruncatedMetricName = "metric_series_truncated"
+
+// LimitGatherer returns a Gatherer that stops emitting series once maxSeries
+// have been gathered from g, so one runaway subsystem can't blow up a shared
+// scrape. When series are dropped a SeriesTruncatedMetricName gauge with
+// value 1 is appended. A non-positive maxSeries disables the limit.
+func LimitGatherer(g Gatherer, maxSeries int) Gatherer {
+	return &limitGatherer{gatherer: g, maxSeries: maxSeries}
+}
+
+type limitGatherer struct {
+	gatherer  Gatherer
+	maxSeries int
+}
+
+func (g *limitGatherer) Gather() ([]*MetricFamily, error) {
+	families, err := g.gatherer.Gather()
+	if err != nil || g.maxSeries <= 0 {
+		return families, err
+	}
+
+	var (
+		result    = make([]*MetricFamily, 0, len(families))
+		remaining = g.maxSeries
+		truncated bool
+	)
+	for _, mf := range families {
+		if mf == nil {
+			continue
+		}
+		if len(mf.Metrics) > remaining {
+			if remaining == 0 {
+				truncated = true
+				break
+			}
+			clipped := *mf
+			clipped.Metrics = mf.Metrics[:remaining]
+			mf = &clipped
+			truncated = true
+		}
+		remaining -= len(mf.Metrics)
+		result = append(result, mf)
+	}
+
+	if truncated {
+		result = append(result, &MetricFamily{
+			Name:    SeriesTruncatedMetricName,
+			Help:    "1 if series were dropped because the scrape exceeded its series limit.",
+			Type:    MetricTypeGauge,
+			Metrics: []Metric{{Value: MetricValue{Value: 1}}},
+		})
+	}
+	return result, nil
+}
+
+// MergeFamilies unions two gathers by family name, for combining exposition
+// from independent registries when the slices are already in hand. Metrics of
+// same-named families are concatenated, a before b; the first family's help
+// and unit win. Families of the same name but different types are an error.
+// The result is sorted by name and the inputs are not modified.
+func MergeFamilies(a, b []*MetricFamily) ([]*MetricFamily, error) {
+	byName := make(map[string]*MetricFamily, len(a)+len(b))
+	result := make([]*MetricFamily, 0, len(a)+len(b))
+	for _, families := range [][]*MetricFamily{a, b} {
+		for _, mf := range families {
+			if mf == nil {
+				continue
+			}
+			merged, ok := byName[mf.Name]
+			if !ok {
+				merged = &MetricFamily{Name: mf.Name, Help: mf.Help, Type: mf.Type, Unit: mf.Unit}
+				byName[mf.Name] = merged
+				result = append(result, merged)
+			} else if merged.Type != mf.Type {
+				return nil, fmt.Errorf("metric %q is both %s and %s", mf.Name, merged.Type, mf.Type)
+			}
+			merged.Metrics = append(merged.Metrics, mf.Metrics...)
+		}
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Name < result[j].Name
+	})
+	return result, nil
+}
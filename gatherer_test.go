@@ -0,0 +1,243 @@
+// Copyright (C) 2020-2026, Lux Industries Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package metric
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestLimitGatherer(t *testing.T) {
+	series := func(n int) []Metric {
+		metrics := make([]Metric, n)
+		for i := range metrics {
+			metrics[i] = Metric{Labels: []LabelPair{{Name: "i", Value: string(rune('a' + i))}}}
+		}
+		return metrics
+	}
+	g := staticGatherer{
+		{Name: "a", Type: MetricTypeGauge, Metrics: series(3)},
+		{Name: "b", Type: MetricTypeGauge, Metrics: series(3)},
+		{Name: "c", Type: MetricTypeGauge, Metrics: series(3)},
+	}
+
+	families, err := LimitGatherer(g, 5).Gather()
+	if err != nil {
+		t.Fatalf("Gather: %v", err)
+	}
+	if len(families) != 3 {
+		t.Fatalf("families: got %d, want 3 (a, truncated b, signal)", len(families))
+	}
+	if n := len(families[0].Metrics) + len(families[1].Metrics); n != 5 {
+		t.Fatalf("series kept: got %d, want 5", n)
+	}
+	if len(g[1].Metrics) != 3 {
+		t.Fatal("LimitGatherer mutated the wrapped gatherer's family")
+	}
+	signal := families[2]
+	if signal.Name != SeriesTruncatedMetricName || signal.Metrics[0].Value.Value != 1 {
+		t.Fatalf("expected %s=1, got %+v", SeriesTruncatedMetricName, signal)
+	}
+
+	// Under the cap nothing is dropped or signaled.
+	families, err = LimitGatherer(g, 9).Gather()
+	if err != nil {
+		t.Fatalf("Gather: %v", err)
+	}
+	if len(families) != 3 || families[2].Name != "c" {
+		t.Fatalf("expected all families untouched, got %d", len(families))
+	}
+}
+
+// failingGatherer always fails with err.
+type failingGatherer struct{ err error }
+
+func (g failingGatherer) Gather() ([]*MetricFamily, error) { return nil, g.err }
+
+func TestBestEffortMultiGatherer(t *testing.T) {
+	errBroken := errors.New("broken source")
+	healthy := staticGatherer{{Name: "up", Type: MetricTypeGauge, Metrics: []Metric{{Value: MetricValue{Value: 1}}}}}
+
+	g := NewBestEffortMultiGatherer()
+	if err := g.Register("healthy", healthy); err != nil {
+		t.Fatalf("register: %v", err)
+	}
+	if err := g.Register("broken", failingGatherer{err: errBroken}); err != nil {
+		t.Fatalf("register: %v", err)
+	}
+
+	families, err := g.Gather()
+	if !errors.Is(err, errBroken) {
+		t.Fatalf("err = %v, want it to wrap %v", err, errBroken)
+	}
+	if len(families) != 1 || families[0].Name != "up" {
+		t.Fatalf("families = %+v, want the healthy gatherer's", families)
+	}
+
+	strict := NewMultiGatherer()
+	_ = strict.Register("healthy", healthy)
+	_ = strict.Register("broken", failingGatherer{err: errBroken})
+	if families, err := strict.Gather(); err == nil || families != nil {
+		t.Fatalf("NewMultiGatherer should fail fast, got %v, %v", families, err)
+	}
+}
+
+func TestMergeFamilies(t *testing.T) {
+	gauge := func(name string, values ...float64) *MetricFamily {
+		mf := &MetricFamily{Name: name, Type: MetricTypeGauge}
+		for _, v := range values {
+			mf.Metrics = append(mf.Metrics, Metric{Value: MetricValue{Value: v}})
+		}
+		return mf
+	}
+
+	t.Run("disjoint", func(t *testing.T) {
+		merged, err := MergeFamilies([]*MetricFamily{gauge("b", 1)}, []*MetricFamily{gauge("a", 2)})
+		if err != nil {
+			t.Fatalf("MergeFamilies: %v", err)
+		}
+		if len(merged) != 2 || merged[0].Name != "a" || merged[1].Name != "b" {
+			t.Fatalf("merged = %+v, want a and b in order", merged)
+		}
+	})
+
+	t.Run("overlapping", func(t *testing.T) {
+		a := []*MetricFamily{gauge("up", 1)}
+		merged, err := MergeFamilies(a, []*MetricFamily{gauge("up", 2, 3)})
+		if err != nil {
+			t.Fatalf("MergeFamilies: %v", err)
+		}
+		if len(merged) != 1 || len(merged[0].Metrics) != 3 {
+			t.Fatalf("merged = %+v, want one family with 3 metrics", merged)
+		}
+		if merged[0].Metrics[0].Value.Value != 1 || merged[0].Metrics[2].Value.Value != 3 {
+			t.Fatalf("metrics out of order: %+v", merged[0].Metrics)
+		}
+		if len(a[0].Metrics) != 1 {
+			t.Fatal("MergeFamilies modified its input")
+		}
+	})
+
+	t.Run("conflicting", func(t *testing.T) {
+		counter := &MetricFamily{Name: "up", Type: MetricTypeCounter}
+		if _, err := MergeFamilies([]*MetricFamily{gauge("up", 1)}, []*MetricFamily{counter}); err == nil {
+			t.Fatal("expected a type conflict error")
+		}
+	})
+}
+
+func TestLabelsGatherer(t *testing.T) {
+	g := NewLabelsGatherer(Labels{"region": "eu", "zone": "eu-1a"})
+	app := staticGatherer{{
+		Name: "requests_total",
+		Type: MetricTypeCounter,
+		Metrics: []Metric{
+			{Labels: []LabelPair{{Name: "code", Value: "200"}}, Value: MetricValue{Value: 1}},
+			{Value: MetricValue{Value: 2}},
+		},
+	}}
+	runtime := staticGatherer{{Name: "goroutines", Type: MetricTypeGauge, Metrics: []Metric{{Value: MetricValue{Value: 8}}}}}
+	if err := g.Register("app", app); err != nil {
+		t.Fatalf("register: %v", err)
+	}
+	if err := g.Register("runtime", runtime); err != nil {
+		t.Fatalf("register: %v", err)
+	}
+
+	families, err := g.Gather()
+	if err != nil {
+		t.Fatalf("Gather: %v", err)
+	}
+	var series int
+	for _, mf := range families {
+		for _, m := range mf.Metrics {
+			series++
+			got := Labels{}
+			for _, l := range m.Labels {
+				got[l.Name] = l.Value
+			}
+			if got["region"] != "eu" || got["zone"] != "eu-1a" {
+				t.Fatalf("%s: labels %v missing region/zone", mf.Name, m.Labels)
+			}
+		}
+	}
+	if series != 3 {
+		t.Fatalf("got %d series, want 3", series)
+	}
+	if len(app[0].Metrics[1].Labels) != 0 {
+		t.Fatal("NewLabelsGatherer modified the wrapped gatherer's metrics")
+	}
+
+	conflict := NewLabelsGatherer(Labels{"code": "500"})
+	_ = conflict.Register("app", app)
+	if _, err := conflict.Gather(); err == nil {
+		t.Fatal("expected an error for a label the metric already has")
+	}
+}
+
+func TestMultiGathererGatherContext(t *testing.T) {
+	slow := &blockingGatherer{started: make(chan struct{}, 1), release: make(chan struct{})}
+	defer close(slow.release)
+	for name, mg := range map[string]MultiGatherer{
+		"multi":  NewMultiGatherer(),
+		"prefix": NewPrefixGatherer(),
+		"labels": NewLabelsGatherer(Labels{"zone": "a"}),
+	} {
+		t.Run(name, func(t *testing.T) {
+			if err := mg.Register("fast", staticGatherer{{Name: "fast_total", Type: MetricTypeCounter, Metrics: []Metric{{}}}}); err != nil {
+				t.Fatal(err)
+			}
+			cg := mg.(ContextGatherer)
+			res, err := cg.GatherContext(context.Background())
+			if err != nil || res.Partial || len(res.Families) != 1 {
+				t.Fatalf("complete gather = %+v, %v", res, err)
+			}
+
+			if err := mg.Register("slow", slow); err != nil {
+				t.Fatal(err)
+			}
+			ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+			defer cancel()
+			res, err = cg.GatherContext(ctx)
+			if err != nil {
+				t.Fatalf("GatherContext: %v", err)
+			}
+			if !res.Partial || len(res.Families) != 1 {
+				t.Fatalf("expected only the fast family, marked partial; got %+v", res)
+			}
+			mf := res.Families[0]
+			switch name {
+			case "prefix":
+				if mf.Name != "fast_fast_total" {
+					t.Fatalf("name = %q, want the namespace prefix", mf.Name)
+				}
+			case "labels":
+				if len(mf.Metrics[0].Labels) != 1 || mf.Metrics[0].Labels[0].Name != "zone" {
+					t.Fatalf("labels = %+v, want zone", mf.Metrics[0].Labels)
+				}
+			}
+		})
+	}
+}
+
+func TestTimedGatherer(t *testing.T) {
+	h := &recordingObserver{}
+	g := TimedGatherer(testFamilies(), h)
+	families, err := g.Gather()
+	if err != nil || len(families) != 1 || families[0].Name != "requests_total" {
+		t.Fatalf("Gather() = %+v, %v; want the wrapped families", families, err)
+	}
+	if got := h.observed(t); got < 0 {
+		t.Fatalf("observed %v, want a non-negative duration", got)
+	}
+
+	wantErr := errors.New("boom")
+	h = &recordingObserver{}
+	if _, err := TimedGatherer(failingGatherer{err: wantErr}, h).Gather(); !errors.Is(err, wantErr) {
+		t.Fatalf("error = %v, want %v", err, wantErr)
+	}
+	h.observed(t)
+}
@@ -2,7 +2,12 @@
 
 package metric
 
-import "testing"
+import (
+	"math"
+	"sync"
+	"testing"
+	"time"
+)
 
 func TestGaugeBasic(t *testing.T) {
 	reg := NewRegistry()
@@ -44,3 +49,121 @@ func TestGaugeVec(t *testing.T) {
 		t.Fatalf("missing queue b metric")
 	}
 }
+
+func TestGaugeVecGetMetricWith(t *testing.T) {
+	reg := NewRegistry()
+	gv := reg.NewGaugeVec("checked_depth", "checked", []string{"queue"})
+
+	if _, err := gv.GetMetricWithLabelValues("a", "b"); err == nil {
+		t.Fatal("expected an error for too many label values")
+	}
+	if _, err := gv.GetMetricWith(Labels{}); err == nil {
+		t.Fatal("expected an error for a missing label")
+	}
+	g, err := gv.GetMetricWith(Labels{"queue": "a"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	g.Set(3)
+	if m, ok := findMetricWithLabels(findFamily(t, gatherFamilies(t, reg), "checked_depth"), Labels{"queue": "a"}); !ok || m.Value.Value != 3 {
+		t.Fatal("missing queue a metric")
+	}
+}
+
+func TestGaugeVecDeletePartialMatch(t *testing.T) {
+	reg := NewRegistry()
+	gv := reg.NewGaugeVec("up", "up", []string{"instance", "job"})
+	gv.WithLabelValues("x", "api").Set(1)
+	gv.WithLabelValues("x", "db").Set(1)
+	gv.WithLabelValues("y", "api").Set(1)
+
+	if n := gv.DeletePartialMatch(Labels{"instance": "x"}); n != 2 {
+		t.Fatalf("removed: got %d, want 2", n)
+	}
+	f := findFamily(t, gatherFamilies(t, reg), "up")
+	if len(f.Metrics) != 1 {
+		t.Fatalf("remaining series: got %d, want 1", len(f.Metrics))
+	}
+	if _, ok := findMetricWithLabels(f, Labels{"instance": "y", "job": "api"}); !ok {
+		t.Fatal("unmatched series was removed")
+	}
+	if gv.WithLabelValues("y", "api").Get() != 1 {
+		t.Fatal("surviving child lost its value")
+	}
+
+	// Deleting the last series must leave the registry gatherable.
+	if n := gv.DeletePartialMatch(Labels{"job": "api"}); n != 1 {
+		t.Fatalf("removed: got %d, want 1", n)
+	}
+	gatherFamilies(t, reg)
+}
+
+func TestSetUp(t *testing.T) {
+	reg := NewRegistry()
+	SetUp(reg, "api", true)
+	SetUp(reg, "worker", true)
+	SetUp(reg, "worker", false)
+
+	family := findFamily(t, gatherFamilies(t, reg), UpMetricName)
+	if len(family.Metrics) != 2 {
+		t.Fatalf("got %d up series, want 2", len(family.Metrics))
+	}
+	for job, want := range map[string]float64{"api": 1, "worker": 0} {
+		m, ok := findMetricWithLabels(family, Labels{"job": job})
+		if !ok {
+			t.Fatalf("missing up{job=%q}", job)
+		}
+		if m.Value.Value != want {
+			t.Fatalf("up{job=%q} = %v, want %v", job, m.Value.Value, want)
+		}
+	}
+}
+
+func TestGaugeSetMaxSetMin(t *testing.T) {
+	reg := NewRegistry()
+	high, ok := reg.NewGauge("connections_high_water", "max connections").(MinMaxGauge)
+	if !ok {
+		t.Fatal("native gauge does not implement MinMaxGauge")
+	}
+	low := reg.NewGauge("latency_low_water", "min latency").(MinMaxGauge)
+	low.Set(math.Inf(1))
+
+	const goroutines = 32
+	var wg sync.WaitGroup
+	for g := 0; g < goroutines; g++ {
+		wg.Add(1)
+		go func(g int) {
+			defer wg.Done()
+			for i := 0; i < 1000; i++ {
+				v := float64(g*1000 + i)
+				high.SetMax(v)
+				low.SetMin(v + 1)
+			}
+		}(g)
+	}
+	wg.Wait()
+
+	if got, want := high.Get(), float64(goroutines*1000-1); got != want {
+		t.Fatalf("high water = %v, want %v", got, want)
+	}
+	if got := low.Get(); got != 1 {
+		t.Fatalf("low water = %v, want 1", got)
+	}
+
+	high.SetMax(-5)
+	high.SetMax(math.NaN())
+	if got := high.Get(); got != float64(goroutines*1000-1) {
+		t.Fatalf("SetMax lowered the gauge to %v", got)
+	}
+}
+
+func TestNewDecayingCounterTwice(t *testing.T) {
+	dc := NewDecayingCounter("decaying_spikes", "spikes", time.Hour)
+	dc.Add(4)
+	if again := NewDecayingCounter("decaying_spikes", "spikes", time.Hour); again != dc {
+		t.Fatal("creating the same name twice should return the existing counter")
+	}
+	if got := findFamily(t, gatherFamilies(t, DefaultRegistry), "decaying_spikes").Metrics[0].Value.Value; got <= 3.9 || got > 4 {
+		t.Fatalf("gathered %v, want about 4", got)
+	}
+}
@@ -0,0 +1,144 @@
+// Copyright (C) 2020-2026, Lux Industries Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package metric
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// GraphiteOpts configures a Graphite plaintext push.
+type GraphiteOpts struct {
+	// Address is the host:port of the Carbon plaintext listener.
+	Address  string
+	Gatherer Gatherer
+	// Prefix is prepended to every path, e.g. "prod.node1".
+	Prefix string
+	// UnderscoresToDots turns metric_name into metric.name in paths.
+	UnderscoresToDots bool
+	// Timeout bounds the dial and the write. Defaults to 10s.
+	Timeout time.Duration
+}
+
+// defaultGraphiteTimeout is the dial and write budget when Timeout is unset.
+const defaultGraphiteTimeout = 10 * time.Second
+
+// PushGraphite gathers metrics and writes them to a Graphite/Carbon relay in
+// the plaintext protocol ("path value timestamp\n"). Labels are folded into
+// the path as .name.value pairs in name order. Histograms expand into
+// .count, .sum and .bucket.le.<bound> paths; summaries into .count, .sum
+// and .quantile.<q> paths.
+func PushGraphite(opts GraphiteOpts) error {
+	if opts.Gatherer == nil {
+		return fmt.Errorf("missing gatherer")
+	}
+	if opts.Address == "" {
+		return fmt.Errorf("missing address")
+	}
+	timeout := opts.Timeout
+	if timeout <= 0 {
+		timeout = defaultGraphiteTimeout
+	}
+
+	families, err := opts.Gatherer.Gather()
+	if err != nil {
+		return err
+	}
+
+	var buf bytes.Buffer
+	writeGraphite(&buf, families, opts, time.Now().Unix())
+
+	conn, err := net.DialTimeout("tcp", opts.Address, timeout)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	if err := conn.SetWriteDeadline(time.Now().Add(timeout)); err != nil {
+		return err
+	}
+	_, err = conn.Write(buf.Bytes())
+	return err
+}
+
+// writeGraphite encodes families as Graphite plaintext lines stamped ts.
+func writeGraphite(buf *bytes.Buffer, families []*MetricFamily, opts GraphiteOpts, ts int64) {
+	line := func(path string, value float64) {
+		fmt.Fprintf(buf, "%s %s %d\n", path, strconv.FormatFloat(value, 'g', -1, 64), ts)
+	}
+	for _, mf := range families {
+		if mf == nil {
+			continue
+		}
+		name := mf.Name
+		if opts.UnderscoresToDots {
+			name = strings.ReplaceAll(name, "_", ".")
+		}
+		if opts.Prefix != "" {
+			name = opts.Prefix + "." + name
+		}
+		for _, m := range mf.Metrics {
+			path := name + graphiteLabels(m.Labels)
+			switch mf.Type {
+			case MetricTypeHistogram:
+				line(path+".count", float64(m.Value.SampleCount))
+				line(path+".sum", m.Value.SampleSum)
+				for _, b := range m.Value.Buckets {
+					line(path+".bucket.le."+graphiteBound(b.UpperBound), float64(b.CumulativeCount))
+				}
+			case MetricTypeSummary:
+				line(path+".count", float64(m.Value.SampleCount))
+				line(path+".sum", m.Value.SampleSum)
+				for _, q := range m.Value.Quantiles {
+					line(path+".quantile."+graphiteBound(q.Quantile), q.Value)
+				}
+			default:
+				line(path, m.Value.Value)
+			}
+		}
+	}
+}
+
+// graphiteLabels folds labels into path segments, sorted by label name.
+func graphiteLabels(labels []LabelPair) string {
+	if len(labels) == 0 {
+		return ""
+	}
+	sorted := append([]LabelPair(nil), labels...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Name < sorted[j].Name })
+	var sb strings.Builder
+	for _, l := range sorted {
+		sb.WriteString(".")
+		sb.WriteString(graphiteSegment(l.Name))
+		sb.WriteString(".")
+		sb.WriteString(graphiteSegment(l.Value))
+	}
+	return sb.String()
+}
+
+// graphiteBound formats a bucket bound or quantile as a single segment.
+func graphiteBound(v float64) string {
+	if math.IsInf(v, 1) {
+		return "inf"
+	}
+	return graphiteSegment(formatFloat(v))
+}
+
+// graphiteSegment replaces characters that would split or break a Graphite
+// path segment.
+func graphiteSegment(s string) string {
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '-', r == '_':
+			return r
+		default:
+			return '_'
+		}
+	}, s)
+}
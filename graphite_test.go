@@ -0,0 +1,79 @@
+// Copyright (C) 2020-2026, Lux Industries Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package metric
+
+import (
+	"io"
+	"math"
+	"net"
+	"strings"
+	"testing"
+)
+
+func TestPushGraphite(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	defer l.Close()
+	received := make(chan string, 1)
+	go func() {
+		conn, err := l.Accept()
+		if err != nil {
+			received <- ""
+			return
+		}
+		defer conn.Close()
+		data, _ := io.ReadAll(conn)
+		received <- string(data)
+	}()
+
+	g := staticGatherer{
+		{
+			Name:    "requests_total",
+			Type:    MetricTypeCounter,
+			Metrics: []Metric{{Labels: []LabelPair{{Name: "method", Value: "GET"}}, Value: MetricValue{Value: 3}}},
+		},
+		{
+			Name: "latency_seconds",
+			Type: MetricTypeHistogram,
+			Metrics: []Metric{{Value: MetricValue{
+				SampleCount: 2,
+				SampleSum:   0.75,
+				Buckets: []Bucket{
+					{UpperBound: 0.5, CumulativeCount: 1},
+					{UpperBound: math.Inf(1), CumulativeCount: 2},
+				},
+			}}},
+		},
+	}
+	err = PushGraphite(GraphiteOpts{
+		Address:           l.Addr().String(),
+		Gatherer:          g,
+		Prefix:            "prod",
+		UnderscoresToDots: true,
+	})
+	if err != nil {
+		t.Fatalf("PushGraphite: %v", err)
+	}
+
+	var paths []string
+	for _, line := range strings.Split(strings.TrimSpace(<-received), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) != 3 {
+			t.Fatalf("malformed line %q", line)
+		}
+		paths = append(paths, fields[0]+" "+fields[1])
+	}
+	want := []string{
+		"prod.requests.total.method.GET 3",
+		"prod.latency.seconds.count 2",
+		"prod.latency.seconds.sum 0.75",
+		"prod.latency.seconds.bucket.le.0_5 1",
+		"prod.latency.seconds.bucket.le.inf 2",
+	}
+	if strings.Join(paths, "\n") != strings.Join(want, "\n") {
+		t.Fatalf("lines:\ngot:\n%s\nwant:\n%s", strings.Join(paths, "\n"), strings.Join(want, "\n"))
+	}
+}
@@ -5,6 +5,7 @@ package metric
 
 import (
 	"context"
+	"crypto/subtle"
 	"fmt"
 	"io"
 	"net/http"
@@ -27,15 +28,48 @@ const (
 // HandlerOpts configures metrics handlers.
 type HandlerOpts struct {
 	// Timeout overrides the scrape timeout. If zero, the scrape header is used if present.
+	// The timeout also bounds writing the response.
 	Timeout time.Duration
 	// ErrorHandling controls how gather errors are handled.
 	ErrorHandling HandlerErrorHandling
-	// ErrorLog is used when ErrorHandling is Continue.
+	// ErrorLog receives gather and encode errors when ErrorLogCtx is nil.
 	ErrorLog interface{ Println(...any) }
+	// ErrorLogCtx receives gather and encode errors along with the scrape
+	// request, so failures can be correlated with the client (path, remote
+	// address). Takes precedence over ErrorLog.
+	ErrorLogCtx func(r *http.Request, err error)
 	// DisableCompression mirrors prometheus/client_golang/promhttp.HandlerOpts.
 	// The native handler serves uncompressed exposition, so this is always
 	// effectively honored; the field exists for source compatibility.
 	DisableCompression bool
+	// ExcludeFamilies lists family names dropped after gather and before
+	// encode, e.g. go_goroutines, to serve a clean endpoint from a registry
+	// that carries the Go/process collectors.
+	ExcludeFamilies []string
+	// EnableOpenMetrics serves the OpenMetrics format to clients that ask
+	// for it in their Accept header. See NegotiateFormat.
+	EnableOpenMetrics bool
+	// ErrorMetricName, if set, names a gauge appended to the exposition when
+	// ErrorHandling is HandlerErrorHandlingContinue and the gather failed. It
+	// has value 1 and the error text, sanitized and truncated, in an "error"
+	// label, so failures show up on the dashboard that is missing data.
+	ErrorMetricName string
+	// MaxRequestsInFlight caps concurrent scrapes; further requests get 503
+	// until a slot frees. Zero means no limit.
+	MaxRequestsInFlight int
+	// AuthToken, if set, makes the handler answer 401 unless the request
+	// carries "Authorization: Bearer <AuthToken>".
+	AuthToken string
+	// Authorizer, if set, decides which requests may scrape, in place of
+	// AuthToken; rejected requests get 401.
+	Authorizer func(*http.Request) bool
+	// StreamFlush flushes the response after each encoded family when the
+	// ResponseWriter supports http.Flusher, so a large scrape starts
+	// arriving before the whole exposition is encoded. A StreamingGatherer
+	// is then encoded family by family as it gathers, unless
+	// ExcludeFamilies, a timeout or ErrorMetricName needs the whole gather
+	// first.
+	StreamFlush bool
 }
 
 // HTTPHandlerOpts is an alias for HandlerOpts for compatibility.
@@ -48,7 +82,36 @@ func HandlerFor(gatherer Gatherer) http.Handler {
 
 // HandlerForWithOpts returns an HTTP handler for the provided gatherer and options.
 func HandlerForWithOpts(gatherer Gatherer, opts HandlerOpts) http.Handler {
+	excluded := make(map[string]struct{}, len(opts.ExcludeFamilies))
+	for _, name := range opts.ExcludeFamilies {
+		excluded[name] = struct{}{}
+	}
+	var inFlight chan struct{}
+	if opts.MaxRequestsInFlight > 0 {
+		inFlight = make(chan struct{}, opts.MaxRequestsInFlight)
+	}
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Scrapes must always see live values, even behind caching proxies.
+		// Set before any early return so a 401 or 503 isn't cached either.
+		w.Header().Set("Cache-Control", "no-store")
+		w.Header().Set("Expires", "0")
+
+		if !opts.authorized(r) {
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		if inFlight != nil {
+			select {
+			case inFlight <- struct{}{}:
+				defer func() { <-inFlight }()
+			default:
+				http.Error(w, "too many concurrent scrapes", http.StatusServiceUnavailable)
+				return
+			}
+		}
+
 		ctx := r.Context()
 		timeout := opts.Timeout
 		if timeout == 0 {
@@ -58,38 +121,237 @@ func HandlerForWithOpts(gatherer Gatherer, opts HandlerOpts) http.Handler {
 			var cancel context.CancelFunc
 			ctx, cancel = context.WithTimeout(ctx, timeout)
 			defer cancel()
+			// Bound the write too, so a client that stops reading can't hold
+			// an in-flight slot past the scrape timeout.
+			_ = http.NewResponseController(w).SetWriteDeadline(time.Now().Add(timeout))
+		}
+
+		format, ok := debugFormat(r)
+		if !ok {
+			format = NegotiateFormat(r, opts.EnableOpenMetrics)
+		}
+		if sg, ok := gatherer.(StreamingGatherer); ok && opts.streams(timeout, format) {
+			if f, ok := w.(http.Flusher); ok {
+				serveStream(ctx, w, f, r, format, sg, &opts)
+				return
+			}
 		}
 
-		families, err := gatherWithContext(ctx, gatherer)
+		start := time.Now()
+		families, partial, err := gatherWithContext(ctx, gatherer)
+		w.Header().Set("X-Metric-Gather-Duration-Seconds", formatSeconds(time.Since(start)))
+		if timeout > 0 {
+			w.Header().Set("X-Metric-Timeout-Seconds", formatSeconds(timeout))
+		}
 		if err != nil {
-			switch opts.ErrorHandling {
-			case HandlerErrorHandlingContinue:
-				if opts.ErrorLog != nil {
-					opts.ErrorLog.Println("metrics gather error:", err)
-				}
-			default:
+			opts.logError(r, "metrics gather error", err)
+			if opts.ErrorHandling != HandlerErrorHandlingContinue {
 				http.Error(w, "metrics gather error", http.StatusInternalServerError)
 				return
 			}
+			if opts.ErrorMetricName != "" {
+				families = append(families[:len(families):len(families)], errorFamily(opts.ErrorMetricName, err))
+			}
+		}
+		if partial {
+			families = append(families[:len(families):len(families)], &MetricFamily{
+				Name:    GatherIncompleteMetricName,
+				Help:    "1 if some gatherers did not finish before the scrape timeout.",
+				Type:    MetricTypeGauge,
+				Metrics: []Metric{{Value: MetricValue{Value: 1}}},
+			})
 		}
 
-		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
-		if err := EncodeText(w, families); err != nil {
-			if opts.ErrorHandling == HandlerErrorHandlingContinue && opts.ErrorLog != nil {
-				opts.ErrorLog.Println("metrics encode error:", err)
-				return
+		// An empty gather gets 204, which tooling handles better than an empty
+		// 200 body. If ExcludeFamilies filtered everything out, the endpoint
+		// is still populated, so that keeps its 200.
+		if len(families) == 0 && err == nil {
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+
+		if len(excluded) > 0 {
+			families = excludeFamilies(families, excluded)
+		}
+
+		w.Header().Set("Content-Type", string(format))
+		encode := encodeFormat
+		if f, ok := w.(http.Flusher); ok && opts.StreamFlush {
+			encode = func(w io.Writer, format Format, families []*MetricFamily) error {
+				return encodeFormatFlushing(w, f, format, families)
+			}
+		}
+		if err := encode(w, format, families); err != nil {
+			opts.logError(r, "metrics encode error", err)
+			if opts.ErrorHandling != HandlerErrorHandlingContinue {
+				http.Error(w, "metrics encode error", http.StatusInternalServerError)
 			}
-			http.Error(w, "metrics encode error", http.StatusInternalServerError)
 			return
 		}
 	})
 }
 
-func gatherWithContext(ctx context.Context, gatherer Gatherer) ([]*MetricFamily, error) {
+// streams reports whether a StreamFlush scrape can encode each family as the
+// gatherer hands it out: nothing may need the full slice first, so no
+// families are excluded and no timeout can leave the gather partial. The
+// error family is only ruled out when an error would add it.
+func (opts *HandlerOpts) streams(timeout time.Duration, format Format) bool {
+	return opts.StreamFlush &&
+		len(opts.ExcludeFamilies) == 0 &&
+		timeout <= 0 &&
+		format != FormatJSON &&
+		(opts.ErrorHandling != HandlerErrorHandlingContinue || opts.ErrorMetricName == "")
+}
+
+// serveStream writes the families sg hands out as they come, flushing f
+// after each one, so a large registry is never held as one slice. The gather
+// duration is only known at the end and is sent as a trailer. Once a family
+// has been written the status is fixed, so later errors are only logged.
+func serveStream(ctx context.Context, w http.ResponseWriter, f http.Flusher, r *http.Request, format Format, sg StreamingGatherer, opts *HandlerOpts) {
+	const durationHeader = "X-Metric-Gather-Duration-Seconds"
+	w.Header().Set("Content-Type", string(format))
+	w.Header().Set("Trailer", durationHeader)
+
+	start := time.Now()
+	var (
+		wrote     bool
+		encodeErr error
+	)
+	err := sg.GatherFunc(func(mf *MetricFamily) error {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if mf == nil {
+			return nil
+		}
+		if encodeErr = encodeFamily(w, format, mf); encodeErr != nil {
+			return encodeErr
+		}
+		wrote = true
+		f.Flush()
+		return nil
+	})
+	if err == nil && wrote && format == FormatOpenMetrics {
+		_, encodeErr = io.WriteString(w, "# EOF\n")
+		err = encodeErr
+	}
+	w.Header().Set(durationHeader, formatSeconds(time.Since(start)))
+
+	switch {
+	case encodeErr != nil:
+		opts.logError(r, "metrics encode error", encodeErr)
+	case err != nil:
+		opts.logError(r, "metrics gather error", err)
+		if !wrote && opts.ErrorHandling != HandlerErrorHandlingContinue {
+			w.Header().Del("Trailer")
+			http.Error(w, "metrics gather error", http.StatusInternalServerError)
+		}
+	case !wrote:
+		w.Header().Del("Content-Type")
+		w.Header().Del("Trailer")
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// maxErrorLabelLength bounds the error label of the handler's error metric,
+// to keep its cardinality and size in check.
+const maxErrorLabelLength = 256
+
+// errorFamily returns the gauge family reporting a gather error. The error
+// text is reduced to characters that need no escaping in any exposition
+// format, so a message with quotes or newlines can't break the output.
+func errorFamily(name string, err error) *MetricFamily {
+	msg := strings.Map(func(r rune) rune {
+		switch {
+		case r == '"':
+			return '\''
+		case r == '\\':
+			return '/'
+		case r < ' ' || r == 0x7f:
+			return ' '
+		default:
+			return r
+		}
+	}, err.Error())
+	return &MetricFamily{
+		Name: name,
+		Help: "1 if the last gather failed, with the error in the error label.",
+		Type: MetricTypeGauge,
+		Metrics: []Metric{{
+			Labels: []LabelPair{{Name: "error", Value: truncateUTF8(msg, maxErrorLabelLength)}},
+			Value:  MetricValue{Value: 1},
+		}},
+	}
+}
+
+// excludeFamilies returns families without those named in excluded.
+func excludeFamilies(families []*MetricFamily, excluded map[string]struct{}) []*MetricFamily {
+	kept := make([]*MetricFamily, 0, len(families))
+	for _, family := range families {
+		if _, ok := excluded[family.Name]; !ok {
+			kept = append(kept, family)
+		}
+	}
+	return kept
+}
+
+// authorized reports whether r may scrape: Authorizer decides if set,
+// otherwise a non-empty AuthToken must match the bearer token. The token is
+// compared in constant time.
+func (opts *HandlerOpts) authorized(r *http.Request) bool {
+	if opts.Authorizer != nil {
+		return opts.Authorizer(r)
+	}
+	if opts.AuthToken == "" {
+		return true
+	}
+	token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	return ok && subtle.ConstantTimeCompare([]byte(token), []byte(opts.AuthToken)) == 1
+}
+
+// logError reports err to ErrorLogCtx, falling back to ErrorLog.
+func (opts *HandlerOpts) logError(r *http.Request, msg string, err error) {
+	switch {
+	case opts.ErrorLogCtx != nil:
+		opts.ErrorLogCtx(r, fmt.Errorf("%s: %w", msg, err))
+	case opts.ErrorLog != nil:
+		opts.ErrorLog.Println(msg+":", err)
+	}
+}
+
+// gatherWithContext gathers from gatherer, returning ctx's error as soon as
+// ctx ends. Gather itself can't be interrupted, so it finishes in the
+// background and its result is discarded; the buffered channel lets it exit
+// without anyone receiving. A ContextGatherer instead returns what finished
+// in time, reported as partial.
+func gatherWithContext(ctx context.Context, gatherer Gatherer) ([]*MetricFamily, bool, error) {
 	if err := ctx.Err(); err != nil {
-		return nil, err
+		return nil, false, err
+	}
+	if ctx.Done() == nil {
+		families, err := gatherer.Gather()
+		return families, false, err
+	}
+	if cg, ok := gatherer.(ContextGatherer); ok {
+		res, err := cg.GatherContext(ctx)
+		return res.Families, res.Partial, err
+	}
+
+	type result struct {
+		families []*MetricFamily
+		err      error
+	}
+	done := make(chan result, 1)
+	go func() {
+		families, err := gatherer.Gather()
+		done <- result{families: families, err: err}
+	}()
+	select {
+	case r := <-done:
+		return r.families, false, r.err
+	case <-ctx.Done():
+		return nil, false, ctx.Err()
 	}
-	return gatherer.Gather()
 }
 
 // parseScrapeTimeout parses the scrape timeout header.
@@ -111,11 +373,21 @@ func parseScrapeTimeout(r *http.Request) time.Duration {
 	return time.Duration(seconds * float64(time.Second))
 }
 
+// formatSeconds renders d as fractional seconds for response headers.
+func formatSeconds(d time.Duration) string {
+	return strconv.FormatFloat(d.Seconds(), 'f', -1, 64)
+}
+
 // Handler is a convenience method for exposing the default registry.
 func Handler() http.Handler {
 	return HandlerFor(NewRegistry())
 }
 
+// HandlerForMetrics returns an HTTP handler serving the registry behind m.
+func HandlerForMetrics(m Metrics) http.Handler {
+	return HandlerFor(m.Registry())
+}
+
 // ValidateGatherer returns a non-nil error if the gatherer is nil.
 func ValidateGatherer(gatherer Gatherer) error {
 	if gatherer == nil {
@@ -124,44 +396,110 @@ func ValidateGatherer(gatherer Gatherer) error {
 	return nil
 }
 
-// EncodeText encodes metric families in the metrics text format.
-func EncodeText(w io.Writer, families []*MetricFamily) error {
+// EncodeText encodes metric families in the metrics text format. It returns
+// the first error reported by w.
+func EncodeText(out io.Writer, families []*MetricFamily) error {
 	for _, mf := range families {
-		if mf == nil {
-			continue
+		if err := EncodeTextFamily(out, mf); err != nil {
+			return err
 		}
+	}
+	return nil
+}
 
-		// Write HELP line
-		if mf.Help != "" {
-			fmt.Fprintf(w, "# HELP %s %s\n", mf.Name, escapeHelp(mf.Help))
+// EncodeTextFamily encodes a single metric family in the metrics text
+// format, so producers can stream families without building the whole
+// slice. A nil family writes nothing.
+func EncodeTextFamily(out io.Writer, mf *MetricFamily) error {
+	return encodeTextFamily(out, mf, EncodeTextOpts{})
+}
+
+// EncodeTextOpts trims the comment lines of the text format, for sinks that
+// only want samples and pay per byte.
+type EncodeTextOpts struct {
+	// OmitHelp drops the # HELP lines.
+	OmitHelp bool
+	// OmitType drops the # TYPE lines. Parsers then see every series as
+	// untyped, though histogram and summary lines keep their suffixes.
+	OmitType bool
+}
+
+// EncodeTextWithOpts is EncodeText with the comment lines adjusted by opts.
+func EncodeTextWithOpts(out io.Writer, families []*MetricFamily, opts EncodeTextOpts) error {
+	for _, mf := range families {
+		if err := encodeTextFamily(out, mf, opts); err != nil {
+			return err
 		}
+	}
+	return nil
+}
+
+func encodeTextFamily(out io.Writer, mf *MetricFamily, opts EncodeTextOpts) error {
+	if mf == nil {
+		return nil
+	}
+	w := &errWriter{w: out}
+
+	// Write HELP line
+	if mf.Help != "" && !opts.OmitHelp {
+		fmt.Fprintf(w, "# HELP %s %s\n", mf.Name, escapeHelp(mf.Help))
+	}
 
-		// Write TYPE line
+	// Write TYPE line
+	if !opts.OmitType {
 		fmt.Fprintf(w, "# TYPE %s %s\n", mf.Name, mf.Type.String())
+	}
 
-		// Write metrics
-		for _, m := range mf.Metrics {
-			switch mf.Type {
-			case MetricTypeCounter, MetricTypeGauge, MetricTypeUntyped:
-				writeMetricLine(w, mf.Name, m.Labels, m.Value.Value)
-			case MetricTypeHistogram:
-				writeHistogram(w, mf.Name, m)
-			case MetricTypeSummary:
-				writeSummary(w, mf.Name, m)
-			}
+	// Write metrics
+	for _, m := range mf.Metrics {
+		switch mf.Type {
+		case MetricTypeCounter, MetricTypeGauge, MetricTypeUntyped:
+			writeMetricLine(w, mf.Name, m.Labels, m.Value.Value, m.TimestampMs)
+		case MetricTypeHistogram:
+			writeHistogram(w, mf.Name, m)
+		case MetricTypeSummary:
+			writeSummary(w, mf.Name, m)
 		}
 	}
-	return nil
+	return w.err
+}
+
+// errWriter remembers the first write error so the encoder can keep using
+// fmt.Fprintf without checking every call.
+type errWriter struct {
+	w   io.Writer
+	err error
+}
+
+func (ew *errWriter) Write(p []byte) (int, error) {
+	if ew.err != nil {
+		return 0, ew.err
+	}
+	n, err := ew.w.Write(p)
+	ew.err = err
+	return n, err
 }
 
-func writeMetricLine(w io.Writer, name string, labels []LabelPair, value float64) {
+func writeMetricLine(w io.Writer, name string, labels []LabelPair, value float64, ts int64) {
 	if len(labels) == 0 {
-		fmt.Fprintf(w, "%s %v\n", name, value)
+		fmt.Fprintf(w, "%s %v%s\n", name, value, timestampSuffix(ts))
 	} else {
-		fmt.Fprintf(w, "%s{%s} %v\n", name, formatLabels(labels), value)
+		fmt.Fprintf(w, "%s{%s} %v%s\n", name, formatLabels(labels), value, timestampSuffix(ts))
+	}
+}
+
+// timestampSuffix formats an optional sample timestamp; zero means unset.
+func timestampSuffix(ts int64) string {
+	if ts == 0 {
+		return ""
 	}
+	return " " + strconv.FormatInt(ts, 10)
 }
 
+// writeHistogram writes a histogram series: each bucket with the series
+// labels plus le, then _sum and _count with the series labels. The label
+// slice is clipped before appending so concurrent encodes of a shared family
+// never write into its backing array.
 func writeHistogram(w io.Writer, name string, m Metric) {
 	// Sort buckets by upper bound
 	buckets := make([]Bucket, len(m.Value.Buckets))
@@ -171,20 +509,20 @@ func writeHistogram(w io.Writer, name string, m Metric) {
 	})
 
 	for _, b := range buckets {
-		labels := append(m.Labels, LabelPair{Name: "le", Value: formatFloat(b.UpperBound)})
-		fmt.Fprintf(w, "%s_bucket{%s} %d\n", name, formatLabels(labels), b.CumulativeCount)
+		labels := append(m.Labels[:len(m.Labels):len(m.Labels)], LabelPair{Name: "le", Value: formatFloat(b.UpperBound)})
+		fmt.Fprintf(w, "%s_bucket{%s} %d%s\n", name, formatLabels(labels), b.CumulativeCount, timestampSuffix(m.TimestampMs))
 	}
-	writeMetricLine(w, name+"_sum", m.Labels, m.Value.SampleSum)
-	fmt.Fprintf(w, "%s_count%s %d\n", name, formatLabelsWithBraces(m.Labels), m.Value.SampleCount)
+	writeMetricLine(w, name+"_sum", m.Labels, m.Value.SampleSum, m.TimestampMs)
+	fmt.Fprintf(w, "%s_count%s %d%s\n", name, formatLabelsWithBraces(m.Labels), m.Value.SampleCount, timestampSuffix(m.TimestampMs))
 }
 
 func writeSummary(w io.Writer, name string, m Metric) {
 	for _, q := range m.Value.Quantiles {
-		labels := append(m.Labels, LabelPair{Name: "quantile", Value: formatFloat(q.Quantile)})
-		fmt.Fprintf(w, "%s{%s} %v\n", name, formatLabels(labels), q.Value)
+		labels := append(m.Labels[:len(m.Labels):len(m.Labels)], LabelPair{Name: "quantile", Value: formatFloat(q.Quantile)})
+		fmt.Fprintf(w, "%s{%s} %v%s\n", name, formatLabels(labels), q.Value, timestampSuffix(m.TimestampMs))
 	}
-	writeMetricLine(w, name+"_sum", m.Labels, m.Value.SampleSum)
-	fmt.Fprintf(w, "%s_count%s %d\n", name, formatLabelsWithBraces(m.Labels), m.Value.SampleCount)
+	writeMetricLine(w, name+"_sum", m.Labels, m.Value.SampleSum, m.TimestampMs)
+	fmt.Fprintf(w, "%s_count%s %d%s\n", name, formatLabelsWithBraces(m.Labels), m.Value.SampleCount, timestampSuffix(m.TimestampMs))
 }
 
 func formatLabels(labels []LabelPair) string {
@@ -0,0 +1,524 @@
+// Copyright (C) 2020-2026, Lux Industries Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package metric
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"runtime"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// staticGatherer returns a fixed set of families, independent of build tags.
+type staticGatherer []*MetricFamily
+
+func (g staticGatherer) Gather() ([]*MetricFamily, error) { return g, nil }
+
+// failingWriter is a ResponseWriter whose body writes always fail.
+type failingWriter struct {
+	header http.Header
+}
+
+func (w *failingWriter) Header() http.Header       { return w.header }
+func (w *failingWriter) WriteHeader(int)           {}
+func (w *failingWriter) Write([]byte) (int, error) { return 0, errFailedWrite }
+
+var errFailedWrite = errors.New("write failed")
+
+func testFamilies() staticGatherer {
+	return staticGatherer{{
+		Name:    "requests_total",
+		Help:    "requests",
+		Type:    MetricTypeCounter,
+		Metrics: []Metric{{Value: MetricValue{Value: 1}}},
+	}}
+}
+
+func TestHandlerErrorLogCtxEncodeError(t *testing.T) {
+	var (
+		gotReq *http.Request
+		gotErr error
+	)
+	h := HandlerForWithOpts(testFamilies(), HandlerOpts{
+		ErrorHandling: HandlerErrorHandlingContinue,
+		ErrorLogCtx: func(r *http.Request, err error) {
+			gotReq, gotErr = r, err
+		},
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/metrics", nil)
+	req.RemoteAddr = "10.0.0.1:1234"
+	h.ServeHTTP(&failingWriter{header: http.Header{}}, req)
+
+	if gotReq != req {
+		t.Fatalf("ErrorLogCtx did not receive the scrape request")
+	}
+	if !errors.Is(gotErr, errFailedWrite) {
+		t.Fatalf("expected wrapped write error, got %v", gotErr)
+	}
+}
+
+func TestHandlerTimingHeaders(t *testing.T) {
+	h := HandlerFor(testFamilies())
+
+	req := httptest.NewRequest(http.MethodGet, "/metrics", nil)
+	req.Header.Set("X-Prometheus-Scrape-Timeout-Seconds", "2.5")
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+
+	if rec.Header().Get("X-Metric-Gather-Duration-Seconds") == "" {
+		t.Fatal("missing X-Metric-Gather-Duration-Seconds header")
+	}
+	if got := rec.Header().Get("X-Metric-Timeout-Seconds"); got != "2.5" {
+		t.Fatalf("X-Metric-Timeout-Seconds: got %q, want 2.5", got)
+	}
+
+	// Without a scrape budget only the duration is reported.
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	if rec.Header().Get("X-Metric-Gather-Duration-Seconds") == "" {
+		t.Fatal("missing X-Metric-Gather-Duration-Seconds header")
+	}
+	if got := rec.Header().Get("X-Metric-Timeout-Seconds"); got != "" {
+		t.Fatalf("unexpected X-Metric-Timeout-Seconds %q without a timeout", got)
+	}
+}
+
+func TestHandlerExcludeFamilies(t *testing.T) {
+	g := append(testFamilies(), &MetricFamily{
+		Name:    "go_goroutines",
+		Help:    "goroutines",
+		Type:    MetricTypeGauge,
+		Metrics: []Metric{{Value: MetricValue{Value: 8}}},
+	})
+	h := HandlerForWithOpts(g, HandlerOpts{ExcludeFamilies: []string{"go_goroutines"}})
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+
+	body := rec.Body.String()
+	if strings.Contains(body, "go_goroutines") {
+		t.Fatalf("excluded family still exposed:\n%s", body)
+	}
+	if !strings.Contains(body, "requests_total") {
+		t.Fatalf("expected requests_total to remain:\n%s", body)
+	}
+}
+
+func TestEncodeTextFamilyMatchesBatch(t *testing.T) {
+	families := append(testFamilies(), nil, &MetricFamily{
+		Name: "latency_seconds",
+		Help: "latency",
+		Type: MetricTypeHistogram,
+		Metrics: []Metric{{Value: MetricValue{
+			SampleCount: 2,
+			SampleSum:   1.5,
+			Buckets:     []Bucket{{UpperBound: 1, CumulativeCount: 1}},
+		}}},
+	})
+
+	var batch, incremental bytes.Buffer
+	if err := EncodeText(&batch, families); err != nil {
+		t.Fatalf("EncodeText: %v", err)
+	}
+	for _, mf := range families {
+		if err := EncodeTextFamily(&incremental, mf); err != nil {
+			t.Fatalf("EncodeTextFamily: %v", err)
+		}
+	}
+	if incremental.String() != batch.String() {
+		t.Fatalf("incremental output differs:\ngot:\n%s\nwant:\n%s", incremental.String(), batch.String())
+	}
+}
+
+func TestHandlerNoStore(t *testing.T) {
+	rec := httptest.NewRecorder()
+	HandlerFor(testFamilies()).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+
+	if got := rec.Header().Get("Cache-Control"); got != "no-store" {
+		t.Fatalf("Cache-Control: got %q, want no-store", got)
+	}
+	if got := rec.Header().Get("Expires"); got != "0" {
+		t.Fatalf("Expires: got %q, want 0", got)
+	}
+}
+
+// blockingGatherer blocks Gather until release is closed.
+type blockingGatherer struct {
+	started chan struct{}
+	release chan struct{}
+}
+
+func (g *blockingGatherer) Gather() ([]*MetricFamily, error) {
+	g.started <- struct{}{}
+	<-g.release
+	return testFamilies().Gather()
+}
+
+func TestHandlerMaxRequestsInFlight(t *testing.T) {
+	g := &blockingGatherer{started: make(chan struct{}, 1), release: make(chan struct{})}
+	h := HandlerForWithOpts(g, HandlerOpts{MaxRequestsInFlight: 1})
+
+	done := make(chan int)
+	go func() {
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+		done <- rec.Code
+	}()
+	<-g.started
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Fatalf("second scrape status = %d, want 503", rec.Code)
+	}
+	if got := rec.Header().Get("Cache-Control"); got != "no-store" {
+		t.Fatalf("503 Cache-Control: got %q, want no-store", got)
+	}
+
+	close(g.release)
+	if code := <-done; code != http.StatusOK {
+		t.Fatalf("first scrape status = %d, want 200", code)
+	}
+}
+
+func TestHandlerStalledClientFreesSlot(t *testing.T) {
+	// Enough output to overflow the socket buffers, so the handler is still
+	// writing when the client goes away.
+	metrics := make([]Metric, 200000)
+	for i := range metrics {
+		metrics[i] = Metric{Labels: []LabelPair{{Name: "id", Value: strconv.Itoa(i)}}, Value: MetricValue{Value: 1}}
+	}
+	big := staticGatherer{{Name: "big", Type: MetricTypeGauge, Metrics: metrics}}
+	srv := httptest.NewServer(HandlerForWithOpts(big, HandlerOpts{MaxRequestsInFlight: 1}))
+	defer srv.Close()
+
+	conn, err := net.Dial("tcp", srv.Listener.Addr().String())
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	if _, err := conn.Write([]byte("GET /metrics HTTP/1.1\r\nHost: test\r\n\r\n")); err != nil {
+		t.Fatalf("write request: %v", err)
+	}
+	if _, err := conn.Read(make([]byte, 1024)); err != nil {
+		t.Fatalf("read response: %v", err)
+	}
+	conn.Close()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		resp, err := http.Get(srv.URL)
+		if err != nil {
+			t.Fatalf("scrape: %v", err)
+		}
+		_, _ = io.Copy(io.Discard, resp.Body)
+		resp.Body.Close()
+		if resp.StatusCode == http.StatusOK {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("in-flight slot not released after client disconnect, status %d", resp.StatusCode)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestHandlerTimeoutAbandonsSlowGather(t *testing.T) {
+	g := &blockingGatherer{started: make(chan struct{}, 1), release: make(chan struct{})}
+	h := HandlerForWithOpts(g, HandlerOpts{Timeout: 20 * time.Millisecond})
+	before := runtime.NumGoroutine()
+
+	start := time.Now()
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Fatalf("handler took %v to give up on a slow gather", elapsed)
+	}
+	if rec.Code != http.StatusInternalServerError {
+		t.Fatalf("status = %d, want 500", rec.Code)
+	}
+
+	// The abandoned Gather must be able to finish and exit on its own.
+	<-g.started
+	close(g.release)
+	deadline := time.Now().Add(5 * time.Second)
+	for runtime.NumGoroutine() > before {
+		if time.Now().After(deadline) {
+			t.Fatalf("gather goroutine leaked: %d goroutines, started with %d", runtime.NumGoroutine(), before)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestHandlerEmptyRegistryNoContent(t *testing.T) {
+	rec := httptest.NewRecorder()
+	HandlerFor(staticGatherer{}).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	if rec.Code != http.StatusNoContent {
+		t.Fatalf("status = %d, want 204", rec.Code)
+	}
+	if rec.Body.Len() != 0 {
+		t.Fatalf("unexpected body %q", rec.Body.String())
+	}
+
+	// Filtering everything out is not an empty registry.
+	rec = httptest.NewRecorder()
+	h := HandlerForWithOpts(testFamilies(), HandlerOpts{ExcludeFamilies: []string{"requests_total"}})
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("filtered status = %d, want 200", rec.Code)
+	}
+}
+
+// partialGatherer returns its families along with err.
+type partialGatherer struct {
+	families []*MetricFamily
+	err      error
+}
+
+func (g partialGatherer) Gather() ([]*MetricFamily, error) { return g.families, g.err }
+
+func TestHandlerErrorMetric(t *testing.T) {
+	g := partialGatherer{
+		families: testFamilies(),
+		err:      errors.New("collector \"db\" failed:\nconnection refused\\" + strings.Repeat("x", 500)),
+	}
+	h := HandlerForWithOpts(g, HandlerOpts{
+		ErrorHandling:   HandlerErrorHandlingContinue,
+		ErrorMetricName: "scrape_error",
+	})
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200", rec.Code)
+	}
+
+	families, err := ParseText(rec.Body)
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	if _, ok := families["requests_total"]; !ok {
+		t.Fatal("healthy family missing from the exposition")
+	}
+	mf, ok := families["scrape_error"]
+	if !ok || len(mf.Metrics) != 1 || mf.Metrics[0].Value.Value != 1 {
+		t.Fatalf("error metric missing or malformed: %+v", mf)
+	}
+	label := mf.Metrics[0].Labels[0]
+	want := "collector 'db' failed: connection refused/"
+	if label.Name != "error" || !strings.HasPrefix(label.Value, want) {
+		t.Fatalf("error label = %+v, want prefix %q", label, want)
+	}
+	if len(label.Value) != 256 {
+		t.Fatalf("error label is %d bytes, want it truncated to 256", len(label.Value))
+	}
+}
+
+func TestHandlerPartialGather(t *testing.T) {
+	slow := &blockingGatherer{started: make(chan struct{}, 1), release: make(chan struct{})}
+	defer close(slow.release)
+	mg := NewMultiGatherer()
+	if err := mg.Register("fast", staticGatherer{{Name: "fast_total", Type: MetricTypeCounter, Metrics: []Metric{{Value: MetricValue{Value: 2}}}}}); err != nil {
+		t.Fatal(err)
+	}
+	if err := mg.Register("slow", slow); err != nil {
+		t.Fatal(err)
+	}
+
+	rec := httptest.NewRecorder()
+	HandlerForWithOpts(mg, HandlerOpts{Timeout: 50 * time.Millisecond}).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200", rec.Code)
+	}
+	body := rec.Body.String()
+	for _, want := range []string{"fast_total 2\n", GatherIncompleteMetricName + " 1\n"} {
+		if !strings.Contains(body, want) {
+			t.Fatalf("missing %q in body:\n%s", want, body)
+		}
+	}
+	if strings.Contains(body, "requests_total") {
+		t.Fatalf("slow gatherer's families should be absent:\n%s", body)
+	}
+}
+
+// flushCounter is a ResponseRecorder that counts Flush calls.
+type flushCounter struct {
+	*httptest.ResponseRecorder
+	flushes int
+}
+
+func (w *flushCounter) Flush() { w.flushes++ }
+
+func TestHandlerStreamFlush(t *testing.T) {
+	families := staticGatherer{
+		{Name: "a_total", Type: MetricTypeCounter, Metrics: []Metric{{Value: MetricValue{Value: 1}}}},
+		{Name: "b", Type: MetricTypeGauge, Metrics: []Metric{{Value: MetricValue{Value: 2}}}},
+		{Name: "c", Type: MetricTypeGauge, Metrics: []Metric{{Value: MetricValue{Value: 3}}}},
+	}
+
+	for _, accept := range []string{"", "application/openmetrics-text"} {
+		w := &flushCounter{ResponseRecorder: httptest.NewRecorder()}
+		req := httptest.NewRequest(http.MethodGet, "/metrics", nil)
+		req.Header.Set("Accept", accept)
+		HandlerForWithOpts(families, HandlerOpts{StreamFlush: true, EnableOpenMetrics: true}).ServeHTTP(w, req)
+		if w.flushes != len(families) {
+			t.Fatalf("Accept %q: expected %d flushes, got %d", accept, len(families), w.flushes)
+		}
+		body := w.Body.String()
+		if !strings.Contains(body, "a_total 1") || !strings.Contains(body, "c 3") {
+			t.Fatalf("Accept %q: unexpected body %q", accept, body)
+		}
+		if n := strings.Count(body, "# EOF"); accept != "" && n != 1 {
+			t.Fatalf("expected a single # EOF, got %d in %q", n, body)
+		}
+	}
+
+	w := &flushCounter{ResponseRecorder: httptest.NewRecorder()}
+	HandlerForWithOpts(families, HandlerOpts{}).ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	if w.flushes != 0 {
+		t.Fatalf("expected no flushes without StreamFlush, got %d", w.flushes)
+	}
+}
+
+// streamingGatherer hands out families one at a time, recording how many
+// flushes had happened before each one.
+type streamingGatherer struct {
+	staticGatherer
+	w       *flushCounter
+	flushed []int
+}
+
+func (g *streamingGatherer) GatherFunc(fn func(*MetricFamily) error) error {
+	for _, mf := range g.staticGatherer {
+		g.flushed = append(g.flushed, g.w.flushes)
+		if err := fn(mf); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func TestHandlerStreamFlushStreamingGatherer(t *testing.T) {
+	families := staticGatherer{
+		{Name: "a_total", Type: MetricTypeCounter, Metrics: []Metric{{Value: MetricValue{Value: 1}}}},
+		{Name: "b", Type: MetricTypeGauge, Metrics: []Metric{{Value: MetricValue{Value: 2}}}},
+	}
+
+	for _, accept := range []string{"", "application/openmetrics-text"} {
+		w := &flushCounter{ResponseRecorder: httptest.NewRecorder()}
+		g := &streamingGatherer{staticGatherer: families, w: w}
+		req := httptest.NewRequest(http.MethodGet, "/metrics", nil)
+		req.Header.Set("Accept", accept)
+		HandlerForWithOpts(g, HandlerOpts{StreamFlush: true, EnableOpenMetrics: true}).ServeHTTP(w, req)
+		if want := []int{0, 1}; len(g.flushed) != 2 || g.flushed[0] != want[0] || g.flushed[1] != want[1] {
+			t.Fatalf("Accept %q: flushes before each family = %v, want %v", accept, g.flushed, want)
+		}
+		body := w.Body.String()
+		if !strings.Contains(body, "a_total 1") || !strings.Contains(body, "b 2") {
+			t.Fatalf("Accept %q: unexpected body %q", accept, body)
+		}
+		if n := strings.Count(body, "# EOF"); accept != "" && n != 1 {
+			t.Fatalf("expected a single # EOF, got %d in %q", n, body)
+		}
+		if w.Result().Trailer.Get("X-Metric-Gather-Duration-Seconds") == "" {
+			t.Fatalf("Accept %q: missing gather duration trailer", accept)
+		}
+	}
+
+	w := &flushCounter{ResponseRecorder: httptest.NewRecorder()}
+	g := &streamingGatherer{w: w}
+	HandlerForWithOpts(g, HandlerOpts{StreamFlush: true}).ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	if w.Code != http.StatusNoContent {
+		t.Fatalf("empty stream status = %d, want 204", w.Code)
+	}
+}
+
+func TestHandlerAuthToken(t *testing.T) {
+	h := HandlerForWithOpts(testFamilies(), HandlerOpts{AuthToken: "s3cret"})
+	for header, want := range map[string]int{
+		"":              http.StatusUnauthorized,
+		"Bearer wrong":  http.StatusUnauthorized,
+		"Basic s3cret":  http.StatusUnauthorized,
+		"Bearer s3cret": http.StatusOK,
+	} {
+		req := httptest.NewRequest(http.MethodGet, "/metrics", nil)
+		if header != "" {
+			req.Header.Set("Authorization", header)
+		}
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		if rec.Code != want {
+			t.Fatalf("Authorization %q: status %d, want %d", header, rec.Code, want)
+		}
+		if want == http.StatusUnauthorized && strings.Contains(rec.Body.String(), "requests_total") {
+			t.Fatalf("Authorization %q: metrics leaked in a 401 body", header)
+		}
+		if got := rec.Header().Get("Cache-Control"); got != "no-store" {
+			t.Fatalf("Authorization %q: Cache-Control %q, want no-store", header, got)
+		}
+	}
+}
+
+func TestHandlerAuthorizer(t *testing.T) {
+	h := HandlerForWithOpts(testFamilies(), HandlerOpts{
+		AuthToken:  "ignored",
+		Authorizer: func(r *http.Request) bool { return r.Header.Get("X-Scraper") == "prometheus" },
+	})
+	req := httptest.NewRequest(http.MethodGet, "/metrics", nil)
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	if rec.Code != http.StatusUnauthorized {
+		t.Fatalf("status %d without the header, want 401", rec.Code)
+	}
+
+	req.Header.Set("X-Scraper", "prometheus")
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status %d with the header, want 200", rec.Code)
+	}
+}
+
+func TestEncodeTextWithOpts(t *testing.T) {
+	families := staticGatherer{
+		{Name: "requests_total", Help: "requests", Type: MetricTypeCounter, Metrics: []Metric{{Value: MetricValue{Value: 3}}}},
+		{Name: "latency_seconds", Help: "latency", Type: MetricTypeHistogram, Metrics: []Metric{{
+			Labels: []LabelPair{{Name: "op", Value: "read"}},
+			Value:  MetricValue{SampleCount: 1, SampleSum: 0.5, Buckets: []Bucket{{UpperBound: 1, CumulativeCount: 1}}},
+		}}},
+	}
+
+	var buf bytes.Buffer
+	if err := EncodeTextWithOpts(&buf, families, EncodeTextOpts{OmitHelp: true, OmitType: true}); err != nil {
+		t.Fatalf("EncodeTextWithOpts: %v", err)
+	}
+	text := buf.String()
+	if strings.Contains(text, "#") {
+		t.Fatalf("expected no comment lines, got %q", text)
+	}
+	parsed, err := ParseText(strings.NewReader(text))
+	if err != nil {
+		t.Fatalf("ParseText: %v", err)
+	}
+	if f := parsed["requests"]; f == nil || len(f.Metrics) != 1 || f.Metrics[0].Value.Value != 3 {
+		t.Fatalf("requests_total not parsed back from %q: %+v", text, f)
+	}
+	if f := parsed["latency_seconds"]; f == nil || len(f.Metrics) != 3 {
+		t.Fatalf("expected bucket, sum and count lines for latency_seconds in %q", text)
+	}
+
+	buf.Reset()
+	if err := EncodeTextWithOpts(&buf, families, EncodeTextOpts{OmitHelp: true}); err != nil {
+		t.Fatalf("EncodeTextWithOpts: %v", err)
+	}
+	if text := buf.String(); strings.Contains(text, "# HELP") || !strings.Contains(text, "# TYPE requests_total counter\n") {
+		t.Fatalf("expected TYPE lines without HELP lines, got %q", text)
+	}
+}
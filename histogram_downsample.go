@@ -0,0 +1,52 @@
+// Copyright (C) 2020-2026, Lux Industries Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package metric
+
+import (
+	"math"
+	"sync/atomic"
+	"unsafe"
+)
+
+// Downsample returns a copy of vh with at most maxBuckets finite buckets, for
+// backends that limit bucket counts. Adjacent buckets are merged by summing
+// their counts under the higher bound, so cumulative counts at the kept
+// bounds are unchanged and the highest finite bound and +Inf overflow
+// survive. The copy is detached: it is not registered and later
+// observations of vh do not reach it. A maxBuckets below 1, or at least the
+// current number of buckets, copies vh unchanged.
+func (vh *metricHistogram) Downsample(maxBuckets int) *metricHistogram {
+	vh.mu.RLock()
+	defer vh.mu.RUnlock()
+
+	n := len(vh.buckets)
+	if maxBuckets < 1 || maxBuckets > n {
+		maxBuckets = n
+	}
+	out := &metricHistogram{
+		name:         vh.name,
+		help:         vh.help,
+		buckets:      make([]float64, 0, maxBuckets),
+		bucketCounts: make([]uint64, 0, maxBuckets+1),
+		count:        atomic.LoadUint64(&vh.count),
+		sum:          math.Float64frombits(atomic.LoadUint64((*uint64)(unsafe.Pointer(&vh.sum)))),
+		nanCount:     atomic.LoadUint64(&vh.nanCount),
+		timestampMs:  atomic.LoadInt64(&vh.timestampMs),
+		sampleRate:   vh.sampleRate,
+	}
+	start := 0
+	for k := 0; k < maxBuckets; k++ {
+		// Spread the merges evenly; the last group ends at the top bound.
+		end := (k + 1) * n / maxBuckets
+		var merged uint64
+		for i := start; i < end; i++ {
+			merged += atomic.LoadUint64(&vh.bucketCounts[i])
+		}
+		out.buckets = append(out.buckets, vh.buckets[end-1])
+		out.bucketCounts = append(out.bucketCounts, merged)
+		start = end
+	}
+	out.bucketCounts = append(out.bucketCounts, atomic.LoadUint64(&vh.bucketCounts[n]))
+	return out
+}
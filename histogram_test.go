@@ -2,7 +2,14 @@
 
 package metric
 
-import "testing"
+import (
+	"fmt"
+	"math"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
 
 func TestHistogramCounts(t *testing.T) {
 	reg := NewRegistry()
@@ -39,3 +46,549 @@ func TestHistogramCounts(t *testing.T) {
 		t.Fatalf("bucket +Inf count mismatch")
 	}
 }
+
+func TestHistogramNonFinite(t *testing.T) {
+	reg := NewRegistry()
+	h := reg.NewHistogram("nonfinite_seconds", "latency", []float64{1, 5})
+	h.Observe(2)
+	h.Observe(math.NaN())
+	h.Observe(math.Inf(1))
+	h.Observe(math.Inf(-1))
+
+	families := gatherFamilies(t, reg)
+	m := findFamily(t, families, "nonfinite_seconds").Metrics[0]
+	if m.Value.SampleCount != 3 {
+		t.Fatalf("expected NaN to be dropped from count, got %d", m.Value.SampleCount)
+	}
+	if m.Value.SampleSum != 2 {
+		t.Fatalf("expected sum to ignore non-finite values, got %v", m.Value.SampleSum)
+	}
+	if got := m.Value.Buckets[0].CumulativeCount; got != 1 {
+		t.Fatalf("expected -Inf in the first bucket, got cumulative %d", got)
+	}
+	if got := m.Value.Buckets[2].CumulativeCount; got != 3 {
+		t.Fatalf("expected +Inf bucket to hold every finite and infinite value, got %d", got)
+	}
+
+	nan := findFamily(t, families, "nonfinite_seconds_nan_total")
+	if nan.Type != MetricTypeCounter || nan.Metrics[0].Value.Value != 1 {
+		t.Fatalf("expected nan_total counter of 1, got %+v", nan)
+	}
+}
+
+func TestHistogramNaNFamilyNameTaken(t *testing.T) {
+	reg := NewRegistry()
+	reg.NewHistogram("taken_seconds", "latency", []float64{1}).Observe(math.NaN())
+	reg.NewGauge("taken_seconds_nan_total", "user gauge").Set(7)
+
+	var matches []*MetricFamily
+	for _, f := range gatherFamilies(t, reg) {
+		if f.Name == "taken_seconds_nan_total" {
+			matches = append(matches, f)
+		}
+	}
+	if len(matches) != 1 || matches[0].Type != MetricTypeGauge || matches[0].Metrics[0].Value.Value != 7 {
+		t.Fatalf("expected only the registered gauge named taken_seconds_nan_total, got %+v", matches)
+	}
+}
+
+// TestHistogramGetSumConcurrent reads the sum while observers are running;
+// run with -race to catch torn or unsynchronized float64 access.
+func TestHistogramGetSumConcurrent(t *testing.T) {
+	h := newHistogram("concurrent_seconds", "latency", []float64{1})
+	s := newSummary("concurrent_summary", "latency", nil)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 1000; j++ {
+				h.Observe(0.5)
+				s.Observe(0.5)
+			}
+		}()
+	}
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 1000; i++ {
+			_ = h.GetSum()
+			_ = s.GetSum()
+		}
+	}()
+	wg.Wait()
+	<-done
+
+	if got := h.GetSum(); got != 4000 {
+		t.Fatalf("histogram sum: got %v, want 4000", got)
+	}
+	if got := s.GetSum(); got != 4000 {
+		t.Fatalf("summary sum: got %v, want 4000", got)
+	}
+}
+
+func TestHistogramNilBucketsDefault(t *testing.T) {
+	reg := NewRegistry()
+	reg.NewHistogram("default_buckets_seconds", "latency", nil).Observe(0.2)
+
+	m := findFamily(t, gatherFamilies(t, reg), "default_buckets_seconds").Metrics[0]
+	if got, want := len(m.Value.Buckets), len(DefBuckets)+1; got != want {
+		t.Fatalf("expected %d buckets (DefBuckets + +Inf), got %d", want, got)
+	}
+	for i, upper := range DefBuckets {
+		if m.Value.Buckets[i].UpperBound != upper {
+			t.Fatalf("bucket %d: got %v, want %v", i, m.Value.Buckets[i].UpperBound, upper)
+		}
+	}
+}
+
+func TestDefaultBucketsIsolated(t *testing.T) {
+	b := DefaultBuckets()
+	b[0] = 1000
+	saved := DefBuckets[0]
+	DefBuckets[0] = 2000
+	defer func() { DefBuckets[0] = saved }()
+
+	if DefaultBuckets()[0] != .005 {
+		t.Fatal("mutating a DefaultBuckets result leaked into later calls")
+	}
+	h := newHistogram("isolated_seconds", "latency", nil)
+	if h.buckets[0] != .005 {
+		t.Fatalf("fallback buckets were corrupted: first bound %v", h.buckets[0])
+	}
+}
+
+func TestHistogramVecGetMetricWithLabelValues(t *testing.T) {
+	reg := NewRegistry()
+	hv := reg.NewHistogramVec("checked_seconds", "checked", []string{"method", "code"}, nil)
+
+	if _, err := hv.GetMetricWithLabelValues("GET"); err == nil {
+		t.Fatal("expected an error for too few label values")
+	}
+	if _, err := hv.GetMetricWithLabelValues("GET", "200", "extra"); err == nil {
+		t.Fatal("expected an error for too many label values")
+	}
+	if _, err := hv.GetMetricWith(Labels{"method": "GET"}); err == nil {
+		t.Fatal("expected an error for a missing label name")
+	}
+	h, err := hv.GetMetricWithLabelValues("GET", "200")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if h != hv.WithLabelValues("GET", "200") {
+		t.Fatal("expected GetMetricWithLabelValues to return the existing child")
+	}
+
+	// WithLabelValues stays lenient.
+	hv.WithLabelValues("GET").Observe(1)
+}
+
+func TestHistogramObserveAt(t *testing.T) {
+	reg := NewRegistry()
+	h := reg.NewHistogram("backfill_seconds", "backfill", []float64{1})
+	at := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	h.(TimestampedHistogram).ObserveAt(0.5, at)
+
+	m := findFamily(t, gatherFamilies(t, reg), "backfill_seconds").Metrics[0]
+	if m.TimestampMs != at.UnixMilli() {
+		t.Fatalf("timestamp: got %d, want %d", m.TimestampMs, at.UnixMilli())
+	}
+	want := fmt.Sprintf("backfill_seconds_count 1 %d\n", at.UnixMilli())
+	if text := encodeFamilies(t, gatherFamilies(t, reg)); !strings.Contains(text, want) {
+		t.Fatalf("expected %q in exposition:\n%s", want, text)
+	}
+
+	// An older backfilled value doesn't move the timestamp back.
+	h.(TimestampedHistogram).ObserveAt(0.5, at.Add(-time.Hour))
+	if m := findFamily(t, gatherFamilies(t, reg), "backfill_seconds").Metrics[0]; m.TimestampMs != at.UnixMilli() {
+		t.Fatalf("timestamp after older ObserveAt: got %d, want %d", m.TimestampMs, at.UnixMilli())
+	}
+
+	// A live observation clears it.
+	h.Observe(0.5)
+	if m := findFamily(t, gatherFamilies(t, reg), "backfill_seconds").Metrics[0]; m.TimestampMs != 0 {
+		t.Fatalf("timestamp after Observe: got %d, want 0", m.TimestampMs)
+	}
+}
+
+func TestHistogramGetCumulativeCounts(t *testing.T) {
+	h := newHistogram("cumulative_seconds", "cumulative", []float64{1, 2, 5})
+	for _, v := range []float64{0.5, 1.5, 1.5, 3, 10, 10} {
+		h.Observe(v)
+	}
+
+	perBucket := h.GetBucketCounts()
+	got := h.GetCumulativeCounts()
+	if len(got) != len(perBucket) {
+		t.Fatalf("buckets: got %d, want %d", len(got), len(perBucket))
+	}
+	var sum uint64
+	for i, b := range got {
+		sum += perBucket[i]
+		if b.CumulativeCount != sum {
+			t.Fatalf("bucket %d (le=%v): got %d, want %d", i, b.UpperBound, b.CumulativeCount, sum)
+		}
+	}
+	if last := got[len(got)-1]; !math.IsInf(last.UpperBound, 1) || last.CumulativeCount != h.GetCount() {
+		t.Fatalf("+Inf bucket: got %+v, want count %d", last, h.GetCount())
+	}
+}
+
+func TestHistogramReset(t *testing.T) {
+	reg := NewRegistry()
+	h, ok := reg.NewHistogram("reset_seconds", "reset", []float64{1, 5}).(ResettableHistogram)
+	if !ok {
+		t.Fatal("native histogram does not implement ResettableHistogram")
+	}
+	for _, v := range []float64{0.5, 2, 10, math.NaN()} {
+		h.Observe(v)
+	}
+	h.Reset()
+
+	family := findFamily(t, gatherFamilies(t, reg), "reset_seconds")
+	m := family.Metrics[0]
+	if m.Value.SampleCount != 0 || m.Value.SampleSum != 0 {
+		t.Fatalf("after reset: count %d, sum %v", m.Value.SampleCount, m.Value.SampleSum)
+	}
+	for _, b := range m.Value.Buckets {
+		if b.CumulativeCount != 0 {
+			t.Fatalf("bucket le=%v = %d after reset", b.UpperBound, b.CumulativeCount)
+		}
+	}
+
+	h.Observe(2)
+	if got := h.(*metricHistogram).GetCount(); got != 1 {
+		t.Fatalf("count after reset and observe = %d, want 1", got)
+	}
+}
+
+func TestSampledHistogram(t *testing.T) {
+	const n = 100000
+	sh := NewHistogramWithSampleRate("hot_seconds", "hot path", []float64{1}, 0.1)
+	for i := 0; i < n; i++ {
+		sh.Observe(0.5)
+	}
+
+	if again := NewHistogramWithSampleRate("hot_seconds", "hot path", []float64{1}, 0.1); again != sh {
+		t.Fatal("creating the same name twice should return the existing histogram")
+	}
+
+	m := findFamily(t, gatherFamilies(t, DefaultRegistry), "hot_seconds").Metrics[0]
+	if got := float64(m.Value.SampleCount); math.Abs(got-n)/n > 0.05 {
+		t.Fatalf("scaled count %v not within 5%% of %d", got, n)
+	}
+	if got := m.Value.SampleSum; math.Abs(got-n*0.5)/(n*0.5) > 0.05 {
+		t.Fatalf("scaled sum %v not within 5%% of %v", got, n*0.5)
+	}
+	if inf := m.Value.Buckets[len(m.Value.Buckets)-1]; inf.CumulativeCount != m.Value.SampleCount {
+		t.Fatalf("+Inf bucket %d, want count %d", inf.CumulativeCount, m.Value.SampleCount)
+	}
+	if raw := sh.h.GetCount(); raw >= n/2 {
+		t.Fatalf("recorded %d of %d observations, want about 10%%", raw, n)
+	}
+
+	all := NewHistogramWithSampleRate("all_seconds", "", nil, 0)
+	all.Observe(1)
+	if got := all.GetCount(); got != 1 {
+		t.Fatalf("rate 0 should record everything, got count %d", got)
+	}
+}
+
+func TestClampedHistogram(t *testing.T) {
+	tests := []struct {
+		name        string
+		metric      string
+		val         float64
+		wantSum     float64
+		wantClamped float64
+	}{
+		{name: "below min", metric: "clamped_below_seconds", val: -3, wantSum: 0, wantClamped: 1},
+		{name: "above max", metric: "clamped_above_seconds", val: 120, wantSum: 60, wantClamped: 1},
+		{name: "in range", metric: "clamped_in_range_seconds", val: 2.5, wantSum: 2.5, wantClamped: 0},
+		{name: "at bound", metric: "clamped_at_bound_seconds", val: 60, wantSum: 60, wantClamped: 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ch := NewHistogramClamped(tt.metric, "clamped", []float64{1, 10}, 0, 60)
+			ch.Observe(tt.val)
+			if got := ch.GetSum(); got != tt.wantSum {
+				t.Fatalf("sum = %v, want %v", got, tt.wantSum)
+			}
+			if got := ch.GetCount(); got != 1 {
+				t.Fatalf("count = %d, want 1", got)
+			}
+			if got := ch.Clamped().Get(); got != tt.wantClamped {
+				t.Fatalf("clamped = %v, want %v", got, tt.wantClamped)
+			}
+		})
+	}
+
+	ch := NewHistogramClamped("clamped_seconds", "clamped", []float64{1, 10}, 0, 60)
+	ch.Observe(-1)
+	if first := ch.GetCumulativeCounts()[0]; first.UpperBound != 1 || first.CumulativeCount != 1 {
+		t.Fatalf("below-min value should land in the lowest bucket, got %+v", first)
+	}
+	if again := NewHistogramClamped("clamped_seconds", "clamped", []float64{1, 10}, 0, 60); again != ch {
+		t.Fatal("creating the same name twice should return the existing histogram")
+	}
+
+	families := gatherFamilies(t, DefaultRegistry)
+	if got := findFamily(t, families, "clamped_seconds").Metrics[0].Value.SampleCount; got != 1 {
+		t.Fatalf("gathered count = %d, want 1", got)
+	}
+	if got := findFamily(t, families, "clamped_seconds_clamped_total").Metrics[0].Value.Value; got != 1 {
+		t.Fatalf("gathered clamp count = %v, want 1", got)
+	}
+}
+
+func TestNativeHistogramGathered(t *testing.T) {
+	nh := NewNativeHistogram("native_gathered_seconds", "native", 0, 0)
+	nh.Observe(2)
+	nh.Observe(math.NaN())
+	if again := NewNativeHistogram("native_gathered_seconds", "native", 0, 0); again != nh {
+		t.Fatal("creating the same name twice should return the existing histogram")
+	}
+
+	families := gatherFamilies(t, DefaultRegistry)
+	family := findFamily(t, families, "native_gathered_seconds")
+	if family.Type != MetricTypeHistogram || !family.Metrics[0].Value.IsNativeHistogram() {
+		t.Fatalf("gathered family = %+v, want a native histogram", family)
+	}
+	if got := family.Metrics[0].Value.SampleCount; got != 1 {
+		t.Fatalf("gathered count = %d, want 1", got)
+	}
+	if got := findFamily(t, families, "native_gathered_seconds_nan_total").Metrics[0].Value.Value; got != 1 {
+		t.Fatalf("gathered NaN count = %v, want 1", got)
+	}
+
+	reg := NewRegistry()
+	if err := reg.Register(NewNativeHistogram("native_gathered_seconds", "native", 0, 0)); err != nil {
+		t.Fatalf("Register: %v", err)
+	}
+	if got := findFamily(t, gatherFamilies(t, reg), "native_gathered_seconds").Metrics[0].Value.SampleCount; got != 1 {
+		t.Fatalf("count on second registry = %d, want 1", got)
+	}
+}
+
+func TestHistogramObserveWeighted(t *testing.T) {
+	weighted := newHistogram("weighted_seconds", "weighted", []float64{1, 5})
+	plain := newHistogram("plain_seconds", "plain", []float64{1, 5})
+	batches := []struct {
+		val    float64
+		weight uint64
+	}{{0.5, 100}, {2, 3}, {10, 7}, {math.Inf(1), 2}, {math.NaN(), 4}, {3, 0}}
+	for _, b := range batches {
+		weighted.ObserveWeighted(b.val, b.weight)
+		for i := uint64(0); i < b.weight; i++ {
+			plain.Observe(b.val)
+		}
+	}
+
+	if got, want := weighted.GetCount(), plain.GetCount(); got != want {
+		t.Fatalf("count = %d, want %d", got, want)
+	}
+	if got, want := weighted.GetSum(), plain.GetSum(); math.Abs(got-want) > 1e-9 {
+		t.Fatalf("sum = %v, want %v", got, want)
+	}
+	if got, want := weighted.GetNaNCount(), plain.GetNaNCount(); got != want {
+		t.Fatalf("nan count = %d, want %d", got, want)
+	}
+	want := plain.GetBucketCounts()
+	for i, got := range weighted.GetBucketCounts() {
+		if got != want[i] {
+			t.Fatalf("bucket %d = %d, want %d", i, got, want[i])
+		}
+	}
+
+	if _, ok := NewRegistry().NewHistogram("iface_seconds", "", nil).(WeightedHistogram); !ok {
+		t.Fatal("native histogram does not implement WeightedHistogram")
+	}
+}
+
+func TestHistogramBucketFor(t *testing.T) {
+	h := newHistogram("lookup_seconds", "lookup", []float64{0.1, 1, 5})
+	tests := []struct {
+		val       float64
+		wantBound float64
+		wantIndex int
+	}{
+		{val: math.Inf(-1), wantBound: 0.1, wantIndex: 0},
+		{val: -2, wantBound: 0.1, wantIndex: 0},
+		{val: 0.1, wantBound: 0.1, wantIndex: 0},
+		{val: 0.10001, wantBound: 1, wantIndex: 1},
+		{val: 1, wantBound: 1, wantIndex: 1},
+		{val: 4.9, wantBound: 5, wantIndex: 2},
+		{val: 5, wantBound: 5, wantIndex: 2},
+		{val: 5.1, wantBound: math.Inf(1), wantIndex: 3},
+		{val: math.Inf(1), wantBound: math.Inf(1), wantIndex: 3},
+	}
+	for _, tt := range tests {
+		bound, index := h.BucketFor(tt.val)
+		if bound != tt.wantBound || index != tt.wantIndex {
+			t.Fatalf("BucketFor(%v) = (%v, %d), want (%v, %d)", tt.val, bound, index, tt.wantBound, tt.wantIndex)
+		}
+		h.Observe(tt.val)
+		if got := h.GetBucketCounts()[index]; got == 0 {
+			t.Fatalf("Observe(%v) did not land in bucket %d", tt.val, index)
+		}
+	}
+}
+
+// TestHistogramGatherConsistentUnderLoad gathers while observers are running
+// and checks that every gathered series agrees with itself; run with -race.
+func TestHistogramGatherConsistentUnderLoad(t *testing.T) {
+	reg := NewRegistry()
+	h := reg.NewHistogram("loaded_seconds", "loaded", []float64{1, 2, 4})
+	// One value per bucket, each exactly representable, so the sum implied
+	// by the buckets must equal the reported sum exactly.
+	values := []float64{0.5, 1.5, 3, 5}
+
+	stop := make(chan struct{})
+	var wg sync.WaitGroup
+	for _, v := range values {
+		wg.Add(1)
+		go func(v float64) {
+			defer wg.Done()
+			for {
+				select {
+				case <-stop:
+					return
+				default:
+					h.Observe(v)
+				}
+			}
+		}(v)
+	}
+	defer func() {
+		close(stop)
+		wg.Wait()
+	}()
+
+	for observed := 0; observed < 1000; {
+		m := findFamily(t, gatherFamilies(t, reg), "loaded_seconds").Metrics[0]
+		if m.Value.SampleCount == 0 {
+			continue
+		}
+		observed++
+		var count, prev uint64
+		var sum float64
+		for i, b := range m.Value.Buckets {
+			count += b.CumulativeCount - prev
+			sum += float64(b.CumulativeCount-prev) * values[i]
+			prev = b.CumulativeCount
+		}
+		if count != m.Value.SampleCount || sum != m.Value.SampleSum {
+			t.Fatalf("buckets imply count %d sum %v, series reports count %d sum %v", count, sum, m.Value.SampleCount, m.Value.SampleSum)
+		}
+	}
+}
+
+func TestHistogramDownsample(t *testing.T) {
+	bounds := make([]float64, 20)
+	for i := range bounds {
+		bounds[i] = float64(i + 1)
+	}
+	h := newHistogram("wide_seconds", "help", bounds)
+	for v := 0.5; v < 25; v += 0.5 {
+		h.Observe(v)
+	}
+
+	small := h.Downsample(5)
+	got := small.GetCumulativeCounts()
+	if len(got) != 6 {
+		t.Fatalf("expected 5 finite buckets plus +Inf, got %v", got)
+	}
+	full := h.GetCumulativeCounts()
+	for i := 1; i < len(got); i++ {
+		if got[i].CumulativeCount < got[i-1].CumulativeCount {
+			t.Fatalf("cumulative counts not monotonic: %v", got)
+		}
+	}
+	// Each kept bound reports the same cumulative count as before.
+	for _, b := range got {
+		for _, f := range full {
+			if f.UpperBound == b.UpperBound && f.CumulativeCount != b.CumulativeCount {
+				t.Fatalf("bound %v: expected cumulative %d, got %d", b.UpperBound, f.CumulativeCount, b.CumulativeCount)
+			}
+		}
+	}
+	if got[4].UpperBound != 20 || !math.IsInf(got[5].UpperBound, 1) {
+		t.Fatalf("expected the top finite bound and +Inf to survive, got %v", got)
+	}
+	if small.GetCount() != h.GetCount() || got[5].CumulativeCount != h.GetCount() || small.GetSum() != h.GetSum() {
+		t.Fatalf("count/sum changed: %d/%v, want %d/%v", small.GetCount(), small.GetSum(), h.GetCount(), h.GetSum())
+	}
+
+	if same := h.Downsample(0).GetCumulativeCounts(); len(same) != len(full) {
+		t.Fatalf("expected Downsample(0) to keep %d buckets, got %d", len(full), len(same))
+	}
+
+	// A sampled histogram's copy keeps scaling its counts up.
+	sampled := newHistogram("wide_sampled_seconds", "help", bounds)
+	sampled.sampleRate = 0.5
+	sampled.Observe(3)
+	if got := sampled.Downsample(5).ToMetric(nil).Value.SampleCount; got != 2 {
+		t.Fatalf("downsampled sampled count = %d, want 2", got)
+	}
+}
+
+func TestEncodeTextLabeledHistogramVec(t *testing.T) {
+	reg := NewRegistry()
+	hv := reg.NewHistogramVec("labeled_seconds", "help", []string{"method"}, []float64{0.1, 1})
+	hv.WithLabelValues("get").Observe(0.05)
+	hv.WithLabelValues("post").Observe(2)
+
+	text := encodeFamilies(t, gatherFamilies(t, reg))
+	parsed, err := ParseText(strings.NewReader(text))
+	if err != nil {
+		t.Fatalf("ParseText: %v", err)
+	}
+	f, ok := parsed["labeled_seconds"]
+	if !ok {
+		t.Fatalf("labeled_seconds missing from %q", text)
+	}
+	// Two series of three buckets (0.1, 1, +Inf), _sum and _count each.
+	if len(f.Metrics) != 10 {
+		t.Fatalf("expected 10 sample lines, got %d in %q", len(f.Metrics), text)
+	}
+	for _, m := range f.Metrics {
+		var method string
+		for _, lp := range m.Labels {
+			if lp.Name == "method" {
+				method = lp.Value
+			}
+		}
+		if method != "get" && method != "post" {
+			t.Fatalf("sample without its method label: %+v in %q", m, text)
+		}
+	}
+	for _, line := range []string{
+		`labeled_seconds_bucket{method="get",le="0.1"} 1`,
+		`labeled_seconds_bucket{method="post",le="+Inf"} 1`,
+		`labeled_seconds_sum{method="post"} 2`,
+		`labeled_seconds_count{method="get"} 1`,
+	} {
+		if !strings.Contains(text, line+"\n") {
+			t.Fatalf("expected line %q in %q", line, text)
+		}
+	}
+}
+
+func TestObserveTimeCappedGathered(t *testing.T) {
+	reg := NewRegistry()
+	timer := NewTimingMetricWithDropped(
+		reg.NewHistogram("job_seconds", "job", nil),
+		reg.NewCounter("job_seconds_dropped_total", "job durations dropped as implausible"),
+	)
+	timer.ObserveTimeCapped(2*time.Hour, time.Minute)
+	timer.ObserveTimeCapped(time.Second, time.Minute)
+
+	families := gatherFamilies(t, reg)
+	if got := findFamily(t, families, "job_seconds_dropped_total").Metrics[0].Value.Value; got != 1 {
+		t.Fatalf("dropped_total = %v, want 1", got)
+	}
+	if got := findFamily(t, families, "job_seconds").Metrics[0].Value.SampleCount; got != 1 {
+		t.Fatalf("job_seconds count = %d, want 1", got)
+	}
+}
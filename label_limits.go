@@ -0,0 +1,115 @@
+// Copyright (C) 2020-2026, Lux Industries Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package metric
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"unicode/utf8"
+)
+
+// ErrLabelLimitExceeded is returned (wrapped) from GetMetricWith* when a
+// series' labels exceed the registry's LabelLimits under LabelLimitReject.
+var ErrLabelLimitExceeded = errors.New("label limit exceeded")
+
+// LabelLimitPolicy decides what happens to a series whose labels exceed
+// LabelLimits.
+type LabelLimitPolicy int
+
+const (
+	// LabelLimitReject keeps the series out of the registry. With and
+	// WithLabelValues return a detached metric that is never exposed;
+	// GetMetricWith* return ErrLabelLimitExceeded.
+	LabelLimitReject LabelLimitPolicy = iota
+	// LabelLimitTruncate shortens over-long names and values and drops
+	// labels beyond MaxLabelsPerMetric (keeping the first by name).
+	LabelLimitTruncate
+)
+
+// LabelLimits bounds the labels of every series in a registry, so one
+// pathological label value can't bloat every scrape. Zero fields are
+// unlimited.
+type LabelLimits struct {
+	MaxLabelNameLength  int
+	MaxLabelValueLength int
+	MaxLabelsPerMetric  int
+	Policy              LabelLimitPolicy
+}
+
+// apply returns labels adjusted to l, or an error under LabelLimitReject.
+// labels is never modified.
+func (l LabelLimits) apply(labels Labels) (Labels, error) {
+	if l == (LabelLimits{Policy: l.Policy}) || len(labels) == 0 {
+		return labels, nil
+	}
+
+	names := make([]string, 0, len(labels))
+	for name := range labels {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	if l.MaxLabelsPerMetric > 0 && len(names) > l.MaxLabelsPerMetric {
+		if l.Policy == LabelLimitReject {
+			return nil, fmt.Errorf("%w: %d labels, limit %d", ErrLabelLimitExceeded, len(names), l.MaxLabelsPerMetric)
+		}
+		names = names[:l.MaxLabelsPerMetric]
+	}
+
+	result := make(Labels, len(names))
+	for _, name := range names {
+		value := labels[name]
+		if l.MaxLabelNameLength > 0 && len(name) > l.MaxLabelNameLength {
+			if l.Policy == LabelLimitReject {
+				return nil, fmt.Errorf("%w: label name %q is %d bytes, limit %d", ErrLabelLimitExceeded, name, len(name), l.MaxLabelNameLength)
+			}
+			name = truncateUTF8(name, l.MaxLabelNameLength)
+		}
+		if l.MaxLabelValueLength > 0 && len(value) > l.MaxLabelValueLength {
+			if l.Policy == LabelLimitReject {
+				return nil, fmt.Errorf("%w: label %q value is %d bytes, limit %d", ErrLabelLimitExceeded, name, len(value), l.MaxLabelValueLength)
+			}
+			value = truncateUTF8(value, l.MaxLabelValueLength)
+		}
+		result[name] = value
+	}
+	return result, nil
+}
+
+// LabelNormalizer canonicalizes one label of every series in a registry, e.g.
+// lowercasing or trimming values, so inputs that differ only in spelling
+// land in one series. It returns the name and value to store.
+type LabelNormalizer func(name, value string) (string, string)
+
+// apply returns labels with n applied to each pair, or labels itself if n is
+// nil. Pairs that normalize to the same name keep the value of the last name
+// in sorted order. labels is never modified.
+func (n LabelNormalizer) apply(labels Labels) Labels {
+	if n == nil || len(labels) == 0 {
+		return labels
+	}
+	names := make([]string, 0, len(labels))
+	for name := range labels {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	result := make(Labels, len(labels))
+	for _, name := range names {
+		k, v := n(name, labels[name])
+		result[k] = v
+	}
+	return result
+}
+
+// truncateUTF8 shortens s to at most n bytes without splitting a rune.
+func truncateUTF8(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	for n > 0 && !utf8.RuneStart(s[n]) {
+		n--
+	}
+	return s[:n]
+}
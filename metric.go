@@ -5,6 +5,7 @@ package metric
 
 import (
 	"context"
+	"sync/atomic"
 	"time"
 )
 
@@ -15,6 +16,8 @@ type CounterOpts struct {
 	Name        string
 	Help        string
 	ConstLabels Labels
+	// Unit is the OpenMetrics unit, e.g. "bytes"; Name should end in it.
+	Unit string
 }
 
 // GaugeOpts configures a gauge metric.
@@ -34,6 +37,8 @@ type HistogramOpts struct {
 	Help        string
 	ConstLabels Labels
 	Buckets     []float64
+	// Unit is the OpenMetrics unit, e.g. "seconds"; Name should end in it.
+	Unit string
 }
 
 // SummaryOpts configures a summary metric.
@@ -44,6 +49,9 @@ type SummaryOpts struct {
 	Help        string
 	ConstLabels Labels
 	Objectives  map[float64]float64
+	// Estimator, if set, creates the quantile estimator for each series in
+	// place of the default sample window, e.g. NewTDigestEstimator.
+	Estimator func() QuantileEstimator
 }
 
 // Counter is a metric that can only increase.
@@ -64,16 +72,67 @@ type Gauge interface {
 	Get() float64
 }
 
+// MinMaxGauge is a Gauge that can be moved only upwards or downwards
+// atomically, for high- and low-water marks without a read-modify-write race
+// in the caller. Gauges created by the native registry implement it.
+type MinMaxGauge interface {
+	Gauge
+	// SetMax sets the gauge to the value if it exceeds the current value.
+	SetMax(float64)
+	// SetMin sets the gauge to the value if it is below the current value.
+	SetMin(float64)
+}
+
 // Histogram samples observations and counts them in configurable buckets.
 type Histogram interface {
 	Observe(float64)
 }
 
+// TimestampedHistogram is a Histogram that accepts an explicit observation
+// time, for backfilling historical data. Histograms created by the native
+// registry implement it.
+type TimestampedHistogram interface {
+	Histogram
+	ObserveAt(float64, time.Time)
+}
+
+// ResettableHistogram is a Histogram that can be zeroed in place, for tests
+// that share a registered histogram. Histograms created by the native
+// registry implement it.
+type ResettableHistogram interface {
+	Histogram
+	Reset()
+}
+
+// WeightedHistogram is a Histogram that records a value standing for many
+// observations in one call. Histograms created by the native registry
+// implement it.
+type WeightedHistogram interface {
+	Histogram
+	ObserveWeighted(val float64, weight uint64)
+}
+
 // Summary captures individual observations and provides quantiles.
 type Summary interface {
 	Observe(float64)
 }
 
+// SortedSummary is a Summary that records an ascending batch at once and
+// keeps its sample window sorted incrementally. Summaries created by the
+// native registry implement it.
+type SortedSummary interface {
+	Summary
+	ObserveSorted([]float64)
+}
+
+// QuantileSummary is a Summary whose quantile estimates can be read back,
+// e.g. to log a p99 without scraping. GetQuantile reports false before any
+// observation. Summaries created by the native registry implement it.
+type QuantileSummary interface {
+	Summary
+	GetQuantile(q float64) (float64, bool)
+}
+
 // Timer measures durations.
 type Timer interface {
 	Start() func()
@@ -106,7 +165,21 @@ type Factory interface {
 type CounterVec interface {
 	With(Labels) Counter
 	WithLabelValues(...string) Counter
+	// GetMetricWith is like With but returns an error instead of creating
+	// a child when labels don't match the vec's label names.
+	GetMetricWith(Labels) (Counter, error)
+	// GetMetricWithLabelValues is like WithLabelValues but returns an error
+	// when the number of values doesn't match the vec's label names.
+	GetMetricWithLabelValues(...string) (Counter, error)
 	MustCurryWith(Labels) CounterVec
+	// Prewarm creates the child for each set of label values up front, so
+	// a label space known at startup is exposed at zero without first-use
+	// latency. It stops at the first set that doesn't match the label names.
+	Prewarm(valueSets [][]string) error
+	// DeletePartialMatch removes every child whose labels include all of
+	// the given pairs, returning how many were removed. An empty match
+	// removes nothing; use Reset to drop all children.
+	DeletePartialMatch(Labels) int
 	Reset()
 }
 
@@ -114,7 +187,19 @@ type CounterVec interface {
 type GaugeVec interface {
 	With(Labels) Gauge
 	WithLabelValues(...string) Gauge
+	// GetMetricWith is like With but returns an error instead of creating
+	// a child when labels don't match the vec's label names.
+	GetMetricWith(Labels) (Gauge, error)
+	// GetMetricWithLabelValues is like WithLabelValues but returns an error
+	// when the number of values doesn't match the vec's label names.
+	GetMetricWithLabelValues(...string) (Gauge, error)
 	MustCurryWith(Labels) GaugeVec
+	// Prewarm is CounterVec.Prewarm.
+	Prewarm(valueSets [][]string) error
+	// DeletePartialMatch removes every child whose labels include all of
+	// the given pairs, returning how many were removed. An empty match
+	// removes nothing; use Reset to drop all children.
+	DeletePartialMatch(Labels) int
 	Reset()
 }
 
@@ -122,7 +207,15 @@ type GaugeVec interface {
 type HistogramVec interface {
 	With(Labels) Histogram
 	WithLabelValues(...string) Histogram
+	// GetMetricWith is like With but returns an error instead of creating
+	// a child when labels don't match the vec's label names.
+	GetMetricWith(Labels) (Histogram, error)
+	// GetMetricWithLabelValues is like WithLabelValues but returns an error
+	// when the number of values doesn't match the vec's label names.
+	GetMetricWithLabelValues(...string) (Histogram, error)
 	MustCurryWith(Labels) HistogramVec
+	// Prewarm is CounterVec.Prewarm.
+	Prewarm(valueSets [][]string) error
 	Reset()
 }
 
@@ -130,7 +223,15 @@ type HistogramVec interface {
 type SummaryVec interface {
 	With(Labels) Summary
 	WithLabelValues(...string) Summary
+	// GetMetricWith is like With but returns an error instead of creating
+	// a child when labels don't match the vec's label names.
+	GetMetricWith(Labels) (Summary, error)
+	// GetMetricWithLabelValues is like WithLabelValues but returns an error
+	// when the number of values doesn't match the vec's label names.
+	GetMetricWithLabelValues(...string) (Summary, error)
 	MustCurryWith(Labels) SummaryVec
+	// Prewarm is CounterVec.Prewarm.
+	Prewarm(valueSets [][]string) error
 	Reset()
 }
 
@@ -168,6 +269,27 @@ var DefaultGatherer Gatherer = DefaultRegistry
 // Global factory instance.
 var defaultFactory Factory = NewFactoryWithRegistry(DefaultRegistry)
 
+// nameSeparator joins namespace, subsystem and name; see SetNameSeparator.
+// Metrics can be created from any goroutine, so it is read atomically.
+var nameSeparator atomic.Pointer[string]
+
+// SetNameSeparator sets the separator placed between namespace, subsystem
+// and metric name by Metrics instances, the Opts constructors and
+// AppendNamespace. The default is "_"; an empty separator concatenates.
+// Metrics already created keep their names, so call it during
+// initialization.
+func SetNameSeparator(sep string) {
+	nameSeparator.Store(&sep)
+}
+
+// separator returns the separator set by SetNameSeparator.
+func separator() string {
+	if sep := nameSeparator.Load(); sep != nil {
+		return *sep
+	}
+	return "_"
+}
+
 // SetFactory sets the global metrics factory.
 func SetFactory(factory Factory) {
 	defaultFactory = factory
@@ -186,7 +308,9 @@ func NewWithRegistry(namespace string, registry Registry) Metrics {
 // NewCounter creates a new counter with the given options.
 func NewCounter(opts CounterOpts) Counter {
 	prefix := AppendNamespace(opts.Namespace, opts.Subsystem)
-	return DefaultRegistry.NewCounter(prefixedName(prefix, opts.Name), opts.Help)
+	name := prefixedName(prefix, opts.Name)
+	setUnit(DefaultRegistry, name, opts.Unit)
+	return DefaultRegistry.NewCounter(name, opts.Help)
 }
 
 // NewGauge creates a new gauge with the given options.
@@ -198,19 +322,25 @@ func NewGauge(opts GaugeOpts) Gauge {
 // NewHistogram creates a new histogram with the given options.
 func NewHistogram(opts HistogramOpts) Histogram {
 	prefix := AppendNamespace(opts.Namespace, opts.Subsystem)
-	return DefaultRegistry.NewHistogram(prefixedName(prefix, opts.Name), opts.Help, opts.Buckets)
+	name := prefixedName(prefix, opts.Name)
+	setUnit(DefaultRegistry, name, opts.Unit)
+	return DefaultRegistry.NewHistogram(name, opts.Help, opts.Buckets)
 }
 
 // NewSummary creates a new summary with the given options.
 func NewSummary(opts SummaryOpts) Summary {
 	prefix := AppendNamespace(opts.Namespace, opts.Subsystem)
-	return DefaultRegistry.NewSummary(prefixedName(prefix, opts.Name), opts.Help, opts.Objectives)
+	name := prefixedName(prefix, opts.Name)
+	setEstimator(DefaultRegistry, name, opts.Estimator)
+	return DefaultRegistry.NewSummary(name, opts.Help, opts.Objectives)
 }
 
 // NewCounterVec creates a new counter vector with the given options.
 func NewCounterVec(opts CounterOpts, labelNames []string) CounterVec {
 	prefix := AppendNamespace(opts.Namespace, opts.Subsystem)
-	return DefaultRegistry.NewCounterVec(prefixedName(prefix, opts.Name), opts.Help, labelNames)
+	name := prefixedName(prefix, opts.Name)
+	setUnit(DefaultRegistry, name, opts.Unit)
+	return DefaultRegistry.NewCounterVec(name, opts.Help, labelNames)
 }
 
 // NewGaugeVec creates a new gauge vector with the given options.
@@ -222,13 +352,35 @@ func NewGaugeVec(opts GaugeOpts, labelNames []string) GaugeVec {
 // NewHistogramVec creates a new histogram vector with the given options.
 func NewHistogramVec(opts HistogramOpts, labelNames []string) HistogramVec {
 	prefix := AppendNamespace(opts.Namespace, opts.Subsystem)
-	return DefaultRegistry.NewHistogramVec(prefixedName(prefix, opts.Name), opts.Help, labelNames, opts.Buckets)
+	name := prefixedName(prefix, opts.Name)
+	setUnit(DefaultRegistry, name, opts.Unit)
+	return DefaultRegistry.NewHistogramVec(name, opts.Help, labelNames, opts.Buckets)
 }
 
 // NewSummaryVec creates a new summary vector with the given options.
 func NewSummaryVec(opts SummaryOpts, labelNames []string) SummaryVec {
 	prefix := AppendNamespace(opts.Namespace, opts.Subsystem)
-	return DefaultRegistry.NewSummaryVec(prefixedName(prefix, opts.Name), opts.Help, labelNames, opts.Objectives)
+	name := prefixedName(prefix, opts.Name)
+	setEstimator(DefaultRegistry, name, opts.Estimator)
+	return DefaultRegistry.NewSummaryVec(name, opts.Help, labelNames, opts.Objectives)
+}
+
+// setUnit records the OpenMetrics unit of family name on registries that
+// track units; others ignore it.
+func setUnit(reg Registry, name, unit string) {
+	if u, ok := reg.(interface{ setUnit(name, unit string) }); ok && unit != "" {
+		u.setUnit(name, unit)
+	}
+}
+
+// setEstimator records the quantile estimator of summary family name on
+// registries that support them; others ignore it.
+func setEstimator(reg Registry, name string, newEstimator func() QuantileEstimator) {
+	if e, ok := reg.(interface {
+		setEstimator(string, func() QuantileEstimator)
+	}); ok && newEstimator != nil {
+		e.setEstimator(name, newEstimator)
+	}
 }
 
 // ExponentialBuckets returns count buckets whose upper bounds are
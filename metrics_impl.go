@@ -66,6 +66,9 @@ type metricGauge struct {
 	value int64 // Use int64 to handle negative values
 	name  string
 	help  string
+	// read, if set, computes the value Get reports, for gauges derived
+	// lazily from other state. It is fixed before the gauge is registered.
+	read func() float64
 }
 
 // newGauge creates a gauge.
@@ -89,13 +92,21 @@ func (vg *metricGauge) SetToCurrentTime() {
 
 // Get returns the gauge value
 func (vg *metricGauge) Get() float64 {
+	if vg.read != nil {
+		return vg.read()
+	}
+	return vg.load()
+}
+
+// load returns the stored value, for the CAS loops below.
+func (vg *metricGauge) load() float64 {
 	return math.Float64frombits(uint64(atomic.LoadInt64(&vg.value)))
 }
 
 // Inc increments the gauge by 1
 func (vg *metricGauge) Inc() {
 	for {
-		oldVal := vg.Get()
+		oldVal := vg.load()
 		newVal := oldVal + 1
 		oldBits := math.Float64bits(oldVal)
 		newBits := math.Float64bits(newVal)
@@ -108,7 +119,7 @@ func (vg *metricGauge) Inc() {
 // Dec decrements the gauge by 1
 func (vg *metricGauge) Dec() {
 	for {
-		oldVal := vg.Get()
+		oldVal := vg.load()
 		newVal := oldVal - 1
 		oldBits := math.Float64bits(oldVal)
 		newBits := math.Float64bits(newVal)
@@ -121,7 +132,7 @@ func (vg *metricGauge) Dec() {
 // Add adds a value to the gauge
 func (vg *metricGauge) Add(val float64) {
 	for {
-		oldVal := vg.Get()
+		oldVal := vg.load()
 		newVal := oldVal + val
 		oldBits := math.Float64bits(oldVal)
 		newBits := math.Float64bits(newVal)
@@ -134,7 +145,7 @@ func (vg *metricGauge) Add(val float64) {
 // Sub subtracts a value from the gauge
 func (vg *metricGauge) Sub(val float64) {
 	for {
-		oldVal := vg.Get()
+		oldVal := vg.load()
 		newVal := oldVal - val
 		oldBits := math.Float64bits(oldVal)
 		newBits := math.Float64bits(newVal)
@@ -144,6 +155,34 @@ func (vg *metricGauge) Sub(val float64) {
 	}
 }
 
+// SetMax sets the gauge to val if val exceeds the current value, e.g. to
+// track a high-water mark. NaN is ignored.
+func (vg *metricGauge) SetMax(val float64) {
+	for {
+		oldBits := atomic.LoadInt64(&vg.value)
+		if !(val > math.Float64frombits(uint64(oldBits))) {
+			return
+		}
+		if atomic.CompareAndSwapInt64(&vg.value, oldBits, int64(math.Float64bits(val))) {
+			return
+		}
+	}
+}
+
+// SetMin sets the gauge to val if val is below the current value. NaN is
+// ignored.
+func (vg *metricGauge) SetMin(val float64) {
+	for {
+		oldBits := atomic.LoadInt64(&vg.value)
+		if !(val < math.Float64frombits(uint64(oldBits))) {
+			return
+		}
+		if atomic.CompareAndSwapInt64(&vg.value, oldBits, int64(math.Float64bits(val))) {
+			return
+		}
+	}
+}
+
 // String returns the gauge in the metrics text format.
 func (vg *metricGauge) String() string {
 	return fmt.Sprintf("# HELP %s %s\n# TYPE %s gauge\n%s %f", vg.name, vg.help, vg.name, vg.name, vg.Get())
@@ -154,6 +193,12 @@ func (vg *metricGauge) Value() float64 {
 	return vg.Get()
 }
 
+// metricUntyped is a gauge-like value exposed with an untyped TYPE line,
+// for passthrough values whose semantics are unknown.
+type metricUntyped struct {
+	*metricGauge
+}
+
 // metricHistogram provides a histogram.
 type metricHistogram struct {
 	name         string
@@ -162,19 +207,18 @@ type metricHistogram struct {
 	bucketCounts []uint64 // Count of values in each bucket
 	count        uint64   // Total count of observations
 	sum          float64  // Sum of all observations
-	mu           sync.RWMutex
+	nanCount     uint64   // NaN observations dropped
+	timestampMs  int64    // latest ObserveAt time since the last Observe, 0 if unset
+	// sampleRate, if set, is the fraction of observations recorded, and
+	// ToMetric scales counts and the sum by its inverse. It is fixed before
+	// the histogram is registered.
+	sampleRate float64
+	mu         sync.RWMutex
 }
 
 // newHistogram creates a histogram.
 func newHistogram(name, help string, buckets []float64) *metricHistogram {
-	if len(buckets) == 0 {
-		buckets = DefBuckets
-	}
-	// Sort buckets to ensure they're in ascending order
-	sortedBuckets := make([]float64, len(buckets))
-	copy(sortedBuckets, buckets)
-	sort.Float64s(sortedBuckets)
-
+	sortedBuckets := bucketLayout(buckets)
 	return &metricHistogram{
 		name:         name,
 		help:         help,
@@ -183,36 +227,117 @@ func newHistogram(name, help string, buckets []float64) *metricHistogram {
 	}
 }
 
-// Observe records a value in the histogram
+// bucketLayout returns the upper bounds a histogram created with buckets
+// uses: the default buckets if none are given, in ascending order.
+func bucketLayout(buckets []float64) []float64 {
+	if len(buckets) == 0 {
+		buckets = defaultBuckets[:]
+	}
+	sorted := make([]float64, len(buckets))
+	copy(sorted, buckets)
+	sort.Float64s(sorted)
+	return sorted
+}
+
+// Observe records a value in the histogram. NaN is dropped and counted
+// separately; ±Inf lands in the outermost bucket and is counted but not
+// added to the sum, so a single bad value can't poison it.
 func (vh *metricHistogram) Observe(val float64) {
+	vh.ObserveWeighted(val, 1)
+}
+
+// ObserveWeighted records weight observations of val at once, for values
+// that stand for an aggregated batch: the bucket and count grow by weight
+// and the sum by val*weight. It follows Observe's NaN and ±Inf rules.
+func (vh *metricHistogram) ObserveWeighted(val float64, weight uint64) {
+	vh.observe(val, weight, 0)
+}
+
+// observe records weight observations of val and updates the series
+// timestamp: a non-zero timestampMs raises it, so out-of-order backfill keeps
+// the latest time, and zero, a live observation, clears it. Buckets, count,
+// sum and timestamp change under one write lock, and ToMetric reads them
+// under the read lock, so a gathered series is always internally consistent.
+func (vh *metricHistogram) observe(val float64, weight uint64, timestampMs int64) {
+	if weight == 0 {
+		return
+	}
+	if math.IsNaN(val) {
+		atomic.AddUint64(&vh.nanCount, weight)
+		return
+	}
+
 	vh.mu.Lock()
 	defer vh.mu.Unlock()
 
-	// Find the appropriate bucket
-	bucketIdx := len(vh.buckets) // Default to +Inf bucket
-	for i, bucket := range vh.buckets {
-		if val <= bucket {
-			bucketIdx = i
-			break
-		}
+	switch {
+	case timestampMs == 0:
+		atomic.StoreInt64(&vh.timestampMs, 0)
+	case timestampMs > atomic.LoadInt64(&vh.timestampMs):
+		atomic.StoreInt64(&vh.timestampMs, timestampMs)
 	}
 
+	bucketIdx := vh.bucketIndex(val)
+
 	// Increment the appropriate bucket count
-	atomic.AddUint64(&vh.bucketCounts[bucketIdx], 1)
+	atomic.AddUint64(&vh.bucketCounts[bucketIdx], weight)
 
 	// Increment total count
-	atomic.AddUint64(&vh.count, 1)
+	atomic.AddUint64(&vh.count, weight)
+
+	if math.IsInf(val, 0) {
+		return
+	}
 
 	// Add to sum
+	delta := val * float64(weight)
 	for {
 		oldSum := vh.sum
-		newSum := oldSum + val
+		newSum := oldSum + delta
 		if atomic.CompareAndSwapUint64((*uint64)(unsafe.Pointer(&vh.sum)), math.Float64bits(oldSum), math.Float64bits(newSum)) {
 			break
 		}
 	}
 }
 
+// bucketIndex returns the index of the first bucket whose upper bound is at
+// least val, or len(vh.buckets) for the +Inf bucket. Bounds are fixed at
+// construction, so no lock is needed.
+func (vh *metricHistogram) bucketIndex(val float64) int {
+	return sort.SearchFloat64s(vh.buckets, val)
+}
+
+// BucketFor reports which bucket val would land in, without observing it:
+// the bucket's upper bound, +Inf for the overflow bucket, and its index.
+func (vh *metricHistogram) BucketFor(val float64) (upperBound float64, index int) {
+	index = vh.bucketIndex(val)
+	if index == len(vh.buckets) {
+		return math.Inf(1), index
+	}
+	return vh.buckets[index], index
+}
+
+// ObserveAt records a value observed at t, and exposes the latest such t as
+// the series timestamp so backfilled data keeps its original time. A later
+// Observe drops the timestamp, since the series is then live again.
+func (vh *metricHistogram) ObserveAt(val float64, t time.Time) {
+	vh.observe(val, 1, t.UnixMilli())
+}
+
+// Reset zeroes every bucket, the count, the sum and the NaN count, so tests
+// can reuse a registered histogram without re-registering it.
+func (vh *metricHistogram) Reset() {
+	vh.mu.Lock()
+	defer vh.mu.Unlock()
+	for i := range vh.bucketCounts {
+		atomic.StoreUint64(&vh.bucketCounts[i], 0)
+	}
+	atomic.StoreUint64(&vh.count, 0)
+	atomic.StoreUint64((*uint64)(unsafe.Pointer(&vh.sum)), 0)
+	atomic.StoreUint64(&vh.nanCount, 0)
+	atomic.StoreInt64(&vh.timestampMs, 0)
+}
+
 // GetBucketCounts returns the current bucket counts
 func (vh *metricHistogram) GetBucketCounts() []uint64 {
 	vh.mu.RLock()
@@ -225,6 +350,27 @@ func (vh *metricHistogram) GetBucketCounts() []uint64 {
 	return result
 }
 
+// GetCumulativeCounts returns each upper bound, ending with +Inf, paired
+// with the number of observations at or below it, as exposed by ToMetric.
+func (vh *metricHistogram) GetCumulativeCounts() []Bucket {
+	vh.mu.RLock()
+	defer vh.mu.RUnlock()
+	return vh.cumulativeCountsLocked()
+}
+
+// cumulativeCountsLocked is GetCumulativeCounts for callers holding vh.mu.
+func (vh *metricHistogram) cumulativeCountsLocked() []Bucket {
+	buckets := make([]Bucket, 0, len(vh.bucketCounts))
+	var cumulative uint64
+	for i, upper := range vh.buckets {
+		cumulative += atomic.LoadUint64(&vh.bucketCounts[i])
+		buckets = append(buckets, Bucket{UpperBound: upper, CumulativeCount: cumulative})
+	}
+	// +Inf bucket
+	cumulative += atomic.LoadUint64(&vh.bucketCounts[len(vh.bucketCounts)-1])
+	return append(buckets, Bucket{UpperBound: math.Inf(1), CumulativeCount: cumulative})
+}
+
 // GetCount returns the total count
 func (vh *metricHistogram) GetCount() uint64 {
 	return atomic.LoadUint64(&vh.count)
@@ -235,32 +381,38 @@ func (vh *metricHistogram) GetSum() float64 {
 	return math.Float64frombits(atomic.LoadUint64((*uint64)(unsafe.Pointer(&vh.sum))))
 }
 
+// GetNaNCount returns the number of NaN observations that were dropped.
+func (vh *metricHistogram) GetNaNCount() uint64 {
+	return atomic.LoadUint64(&vh.nanCount)
+}
+
 // ToMetric returns a Metric representation for exposition.
 func (vh *metricHistogram) ToMetric(labels []LabelPair) Metric {
 	vh.mu.RLock()
 	defer vh.mu.RUnlock()
 
-	var buckets []Bucket
-	var cumulative uint64
-	for i, upper := range vh.buckets {
-		cumulative += atomic.LoadUint64(&vh.bucketCounts[i])
-		buckets = append(buckets, Bucket{UpperBound: upper, CumulativeCount: cumulative})
-	}
-	// +Inf bucket
-	cumulative += atomic.LoadUint64(&vh.bucketCounts[len(vh.bucketCounts)-1])
-	buckets = append(buckets, Bucket{UpperBound: math.Inf(1), CumulativeCount: cumulative})
-
-	return Metric{
+	m := Metric{
 		Labels: labels,
 		Value: MetricValue{
 			SampleCount: atomic.LoadUint64(&vh.count),
 			SampleSum:   math.Float64frombits(atomic.LoadUint64((*uint64)(unsafe.Pointer(&vh.sum)))),
-			Buckets:     buckets,
+			Buckets:     vh.cumulativeCountsLocked(),
 		},
+		TimestampMs: atomic.LoadInt64(&vh.timestampMs),
+	}
+	if vh.sampleRate > 0 {
+		m.Value.SampleCount = scaleSampled(m.Value.SampleCount, vh.sampleRate)
+		m.Value.SampleSum /= vh.sampleRate
+		for i := range m.Value.Buckets {
+			m.Value.Buckets[i].CumulativeCount = scaleSampled(m.Value.Buckets[i].CumulativeCount, vh.sampleRate)
+		}
 	}
+	return m
 }
 
-// String returns the histogram in the metrics text format.
+// String returns the histogram in the metrics text format as an unlabeled
+// series. Labeled series are exposed through the registry, whose EncodeText
+// writes each series' labels on its bucket, _sum and _count lines.
 func (vh *metricHistogram) String() string {
 	vh.mu.RLock()
 	defer vh.mu.RUnlock()
@@ -298,36 +450,47 @@ type metricSummary struct {
 	samples    []float64
 	sampleIdx  int
 	maxSamples int
-	mu         sync.RWMutex
+	nanCount   uint64 // NaN observations dropped
+	// sortedSamples holds samples in ascending order while sortedValid is
+	// set. ObserveSorted maintains it; Observe invalidates it.
+	sortedSamples []float64
+	sortedValid   bool
+	// estimator replaces the sample window when set. It is fixed before the
+	// summary is published.
+	estimator QuantileEstimator
+	mu        sync.RWMutex
 }
 
 // newSummary creates a summary.
 func newSummary(name, help string, objectives map[float64]float64) *metricSummary {
-	objList := make([]float64, 0, len(objectives))
-	for q := range objectives {
-		objList = append(objList, q)
-	}
-	if len(objList) == 0 {
-		objList = []float64{0.5, 0.9, 0.99}
+	if len(objectives) == 0 {
+		objectives = DefaultObjectives()
 	}
-	sort.Float64s(objList)
+	mustValidObjectives(name, objectives)
 	return &metricSummary{
 		name:       name,
 		help:       help,
-		objectives: objList,
+		objectives: sortedObjectives(objectives),
 		maxSamples: 1024,
 	}
 }
 
-// Observe records a value in the summary
+// Observe records a value in the summary. NaN is dropped and counted
+// separately; ±Inf is counted and sampled but not added to the sum, and not
+// passed to an estimator.
 func (vs *metricSummary) Observe(val float64) {
+	if math.IsNaN(val) {
+		atomic.AddUint64(&vs.nanCount, 1)
+		return
+	}
+
 	vs.mu.Lock()
 	defer vs.mu.Unlock()
 
 	atomic.AddUint64(&vs.count, 1)
 
 	// Add to sum atomically
-	for {
+	for !math.IsInf(val, 0) {
 		oldSum := vs.sum
 		newSum := oldSum + val
 		if atomic.CompareAndSwapUint64((*uint64)(unsafe.Pointer(&vs.sum)), math.Float64bits(oldSum), math.Float64bits(newSum)) {
@@ -335,15 +498,137 @@ func (vs *metricSummary) Observe(val float64) {
 		}
 	}
 
+	if vs.estimator != nil {
+		if !math.IsInf(val, 0) {
+			vs.estimator.Insert(val)
+		}
+		return
+	}
 	if vs.maxSamples <= 0 {
 		return
 	}
+	vs.sortedValid = false
+	vs.sampleLocked(val)
+}
+
+// sampleLocked adds val to the sample window and returns the value it
+// evicted, if any. Callers must hold vs.mu.
+func (vs *metricSummary) sampleLocked(val float64) (evicted float64, ok bool) {
 	if len(vs.samples) < vs.maxSamples {
 		vs.samples = append(vs.samples, val)
-		return
+		return 0, false
 	}
+	evicted = vs.samples[vs.sampleIdx]
 	vs.samples[vs.sampleIdx] = val
 	vs.sampleIdx = (vs.sampleIdx + 1) % vs.maxSamples
+	return evicted, true
+}
+
+// ObserveSorted records a batch of values that is already in ascending
+// order, e.g. a flushed batch of latencies. The batch is merged into a sorted
+// copy of the sample window, so gathers can skip re-sorting it. A batch that
+// is not sorted is detected and sorted first. NaN and ±Inf are handled as in
+// Observe.
+func (vs *metricSummary) ObserveSorted(vals []float64) {
+	batch := make([]float64, 0, len(vals))
+	var nans uint64
+	for _, v := range vals {
+		if math.IsNaN(v) {
+			nans++
+			continue
+		}
+		batch = append(batch, v)
+	}
+	if nans > 0 {
+		atomic.AddUint64(&vs.nanCount, nans)
+	}
+	if len(batch) == 0 {
+		return
+	}
+	if !sort.Float64sAreSorted(batch) {
+		sort.Float64s(batch)
+	}
+	var delta float64
+	for _, v := range batch {
+		if !math.IsInf(v, 0) {
+			delta += v
+		}
+	}
+
+	vs.mu.Lock()
+	defer vs.mu.Unlock()
+
+	atomic.AddUint64(&vs.count, uint64(len(batch)))
+	for {
+		oldSum := vs.sum
+		newSum := oldSum + delta
+		if atomic.CompareAndSwapUint64((*uint64)(unsafe.Pointer(&vs.sum)), math.Float64bits(oldSum), math.Float64bits(newSum)) {
+			break
+		}
+	}
+
+	if vs.estimator != nil {
+		for _, v := range batch {
+			if !math.IsInf(v, 0) {
+				vs.estimator.Insert(v)
+			}
+		}
+		return
+	}
+	if vs.maxSamples <= 0 {
+		return
+	}
+	var evicted []float64
+	for _, v := range batch {
+		if old, ok := vs.sampleLocked(v); ok {
+			evicted = append(evicted, old)
+		}
+	}
+	if !vs.sortedValid || len(batch) > vs.maxSamples {
+		// The window no longer matches the sorted copy, or the batch evicted
+		// some of itself; rebuild once.
+		vs.sortedSamples = append(vs.sortedSamples[:0], vs.samples...)
+		sort.Float64s(vs.sortedSamples)
+		vs.sortedValid = true
+		return
+	}
+	sort.Float64s(evicted)
+	vs.sortedSamples = mergeSorted(removeSorted(vs.sortedSamples, evicted), batch)
+}
+
+// removeSorted removes one occurrence of each value of drop from data. Both
+// must be sorted and drop must be a sub-multiset of data. data is reused.
+func removeSorted(data, drop []float64) []float64 {
+	if len(drop) == 0 {
+		return data
+	}
+	out := data[:0]
+	j := 0
+	for _, v := range data {
+		if j < len(drop) && v == drop[j] {
+			j++
+			continue
+		}
+		out = append(out, v)
+	}
+	return out
+}
+
+// mergeSorted merges two sorted slices into a new sorted slice.
+func mergeSorted(a, b []float64) []float64 {
+	out := make([]float64, 0, len(a)+len(b))
+	i, j := 0, 0
+	for i < len(a) && j < len(b) {
+		if a[i] <= b[j] {
+			out = append(out, a[i])
+			i++
+		} else {
+			out = append(out, b[j])
+			j++
+		}
+	}
+	out = append(out, a[i:]...)
+	return append(out, b[j:]...)
 }
 
 // GetCount returns the total count
@@ -356,52 +641,165 @@ func (vs *metricSummary) GetSum() float64 {
 	return math.Float64frombits(atomic.LoadUint64((*uint64)(unsafe.Pointer(&vs.sum))))
 }
 
+// GetNaNCount returns the number of NaN observations that were dropped.
+func (vs *metricSummary) GetNaNCount() uint64 {
+	return atomic.LoadUint64(&vs.nanCount)
+}
+
 // ToMetric returns a Metric representation for exposition.
 func (vs *metricSummary) ToMetric(labels []LabelPair) Metric {
-	vs.mu.RLock()
-	defer vs.mu.RUnlock()
+	defer vs.readLock()()
 
 	return Metric{
 		Labels: labels,
 		Value: MetricValue{
 			SampleCount: atomic.LoadUint64(&vs.count),
 			SampleSum:   math.Float64frombits(atomic.LoadUint64((*uint64)(unsafe.Pointer(&vs.sum)))),
-			Quantiles:   quantilesFromSamples(vs.samples, vs.objectives),
+			Quantiles:   vs.quantilesLocked(),
 		},
 	}
 }
 
+// readLock takes the lock for reading quantiles: the read lock, or the write
+// lock when an estimator is set, since estimators may reorganize on Query.
+// It returns the matching unlock.
+func (vs *metricSummary) readLock() (unlock func()) {
+	if vs.estimator != nil {
+		vs.mu.Lock()
+		return vs.mu.Unlock
+	}
+	vs.mu.RLock()
+	return vs.mu.RUnlock
+}
+
+// quantilesLocked returns the objectives' current values. Callers must hold
+// vs.readLock.
+func (vs *metricSummary) quantilesLocked() []Quantile {
+	if vs.estimator == nil {
+		if vs.sortedValid {
+			return quantilesFromSorted(vs.sortedSamples, vs.objectives)
+		}
+		return quantilesFromSamples(vs.samples, vs.objectives)
+	}
+	var quantiles []Quantile
+	for _, q := range vs.objectives {
+		v, ok := vs.estimator.Query(q)
+		if !ok {
+			return nil
+		}
+		quantiles = append(quantiles, Quantile{Quantile: q, Value: v})
+	}
+	return quantiles
+}
+
+// SetObjectives replaces the reported quantiles. The sample window is kept,
+// so the new quantiles are available from the next gather. An empty map is
+// rejected.
+func (vs *metricSummary) SetObjectives(objectives map[float64]float64) error {
+	if len(objectives) == 0 {
+		return fmt.Errorf("summary %q: objectives must not be empty", vs.name)
+	}
+	if err := validateObjectives(vs.name, objectives); err != nil {
+		return err
+	}
+	objList := sortedObjectives(objectives)
+	vs.mu.Lock()
+	defer vs.mu.Unlock()
+	vs.objectives = objList
+	return nil
+}
+
+// validateObjectives reports an objective quantile outside [0, 1], which
+// would otherwise silently index past the sample window.
+func validateObjectives(name string, objectives map[float64]float64) error {
+	for q := range objectives {
+		if !(q >= 0 && q <= 1) {
+			return fmt.Errorf("summary %q: objective quantile %v outside [0, 1]", name, q)
+		}
+	}
+	return nil
+}
+
+// mustValidObjectives is validateObjectives for constructors, which have no
+// error return.
+func mustValidObjectives(name string, objectives map[float64]float64) {
+	if err := validateObjectives(name, objectives); err != nil {
+		panic(err)
+	}
+}
+
+// sortedObjectives returns the quantiles of objectives in ascending order.
+func sortedObjectives(objectives map[float64]float64) []float64 {
+	objList := make([]float64, 0, len(objectives))
+	for q := range objectives {
+		objList = append(objList, q)
+	}
+	sort.Float64s(objList)
+	return objList
+}
+
+// GetQuantile returns the estimated value at quantile q from the current
+// sample window. It reports false when nothing has been observed yet.
+func (vs *metricSummary) GetQuantile(q float64) (float64, bool) {
+	defer vs.readLock()()
+
+	if vs.estimator != nil {
+		return vs.estimator.Query(q)
+	}
+	if len(vs.samples) == 0 {
+		return 0, false
+	}
+	if vs.sortedValid {
+		return quantileFromSorted(vs.sortedSamples, q), true
+	}
+	data := append([]float64(nil), vs.samples...)
+	sort.Float64s(data)
+	return quantileFromSorted(data, q), true
+}
+
 func quantilesFromSamples(samples []float64, objectives []float64) []Quantile {
 	if len(samples) == 0 || len(objectives) == 0 {
 		return nil
 	}
 	data := append([]float64(nil), samples...)
 	sort.Float64s(data)
+	return quantilesFromSorted(data, objectives)
+}
+
+// quantilesFromSorted is quantilesFromSamples for data already in ascending
+// order.
+func quantilesFromSorted(data []float64, objectives []float64) []Quantile {
+	if len(data) == 0 || len(objectives) == 0 {
+		return nil
+	}
 	quantiles := make([]Quantile, 0, len(objectives))
 	for _, q := range objectives {
-		if q <= 0 {
-			quantiles = append(quantiles, Quantile{Quantile: q, Value: data[0]})
-			continue
-		}
-		if q >= 1 {
-			quantiles = append(quantiles, Quantile{Quantile: q, Value: data[len(data)-1]})
-			continue
-		}
-		idx := int(math.Ceil(q*float64(len(data)))) - 1
-		if idx < 0 {
-			idx = 0
-		} else if idx >= len(data) {
-			idx = len(data) - 1
-		}
-		quantiles = append(quantiles, Quantile{Quantile: q, Value: data[idx]})
+		quantiles = append(quantiles, Quantile{Quantile: q, Value: quantileFromSorted(data, q)})
 	}
 	return quantiles
 }
 
+// quantileFromSorted returns the nearest-rank value for q from sorted,
+// non-empty data.
+func quantileFromSorted(data []float64, q float64) float64 {
+	if q <= 0 {
+		return data[0]
+	}
+	if q >= 1 {
+		return data[len(data)-1]
+	}
+	idx := int(math.Ceil(q*float64(len(data)))) - 1
+	if idx < 0 {
+		idx = 0
+	} else if idx >= len(data) {
+		idx = len(data) - 1
+	}
+	return data[idx]
+}
+
 // String returns the summary in the metrics text format.
 func (vs *metricSummary) String() string {
-	vs.mu.RLock()
-	defer vs.mu.RUnlock()
+	defer vs.readLock()()
 
 	var sb strings.Builder
 
@@ -413,31 +811,36 @@ func (vs *metricSummary) String() string {
 	sb.WriteString(fmt.Sprintf("%s_sum %g\n", vs.name, math.Float64frombits(atomic.LoadUint64((*uint64)(unsafe.Pointer(&vs.sum))))))
 
 	// Write quantiles
-	for _, q := range quantilesFromSamples(vs.samples, vs.objectives) {
+	for _, q := range vs.quantilesLocked() {
 		sb.WriteString(fmt.Sprintf("%s{quantile=\"%g\"} %g\n", vs.name, q.Quantile, q.Value))
 	}
 
 	return sb.String()
 }
 
-// timingMetric provides timing functionality for histogram-backed timing.
+// observer is the common recording method of Histogram and Summary.
+type observer interface {
+	Observe(float64)
+}
+
+// timingMetric provides timing functionality backed by a histogram or summary.
 type timingMetric struct {
-	histogram Histogram
-	start     time.Time
+	observer observer
+	start    time.Time
 }
 
 // newTimingMetric creates a new timing metric.
-func newTimingMetric(histogram Histogram) *timingMetric {
+func newTimingMetric(o observer) *timingMetric {
 	return &timingMetric{
-		histogram: histogram,
-		start:     time.Now(),
+		observer: o,
+		start:    time.Now(),
 	}
 }
 
 // Stop stops the timing and records the duration
 func (vtm *timingMetric) Stop() {
 	duration := time.Since(vtm.start).Seconds()
-	vtm.histogram.Observe(duration)
+	vtm.observer.Observe(duration)
 }
 
 // Reset resets the timing
@@ -458,7 +861,7 @@ func (vtm *timingMetric) Start() func() {
 
 // ObserveTime observes the given duration
 func (vtm *timingMetric) ObserveTime(d time.Duration) {
-	vtm.histogram.Observe(d.Seconds())
+	vtm.observer.Observe(d.Seconds())
 }
 
 // factory creates metrics.
@@ -563,7 +966,7 @@ func prefixedName(namespace, name string) string {
 	if namespace == "" {
 		return name
 	}
-	return namespace + "_" + name
+	return namespace + separator() + name
 }
 
 // registry collects metrics and exposes them via Gather.
@@ -573,7 +976,28 @@ type registry struct {
 	gauges     map[string]map[string]*labeledGauge
 	histograms map[string]map[string]*labeledHistogram
 	summaries  map[string]map[string]*labeledSummary
-	registered map[string]MetricType
+	untyped    map[string]*metricUntyped
+	natives    map[string]*NativeHistogram
+	registered map[string]MetricType // names passed to Register
+	types      map[string]MetricType // every known family name, by type
+	vecs       map[string]vecSchema  // help and label names of vec families
+	help       map[string]string     // family help, fixed by the first registration
+	units      map[string]string     // OpenMetrics unit by family name
+	estimators map[string]func() QuantileEstimator
+	aliases    map[string]string // old family name → the family it mirrors
+	derived    map[string]derivedMetric
+
+	labelLimits     LabelLimits
+	labelNormalizer LabelNormalizer
+}
+
+// vecSchema is what Describe reports for a vec family before it has series,
+// and the layout Restore creates missing children with.
+type vecSchema struct {
+	help       string
+	labelNames []string
+	buckets    []float64           // histogram vecs
+	objectives map[float64]float64 // summary vecs
 }
 
 type labeledCounter struct {
@@ -603,7 +1027,16 @@ func newRegistry() *registry {
 		gauges:     make(map[string]map[string]*labeledGauge),
 		histograms: make(map[string]map[string]*labeledHistogram),
 		summaries:  make(map[string]map[string]*labeledSummary),
+		untyped:    make(map[string]*metricUntyped),
+		natives:    make(map[string]*NativeHistogram),
 		registered: make(map[string]MetricType),
+		types:      make(map[string]MetricType),
+		vecs:       make(map[string]vecSchema),
+		help:       make(map[string]string),
+		units:      make(map[string]string),
+		estimators: make(map[string]func() QuantileEstimator),
+		aliases:    make(map[string]string),
+		derived:    make(map[string]derivedMetric),
 	}
 }
 
@@ -627,8 +1060,13 @@ func (hpr *registry) RegisterSummary(name string, summary *metricSummary) {
 	hpr.RegisterLabeledSummary(name, nil, summary)
 }
 
-// RegisterLabeledCounter registers a counter with labels.
+// RegisterLabeledCounter registers a counter with labels. Label sets rejected by
+// the registry's LabelLimits are not registered.
 func (hpr *registry) RegisterLabeledCounter(name string, labels Labels, counter *metricCounter) {
+	labels, err := hpr.seriesLabels(labels)
+	if err != nil {
+		return
+	}
 	hpr.mu.Lock()
 	defer hpr.mu.Unlock()
 	key := labelsKeyFromLabels(labels)
@@ -638,8 +1076,13 @@ func (hpr *registry) RegisterLabeledCounter(name string, labels Labels, counter
 	hpr.counters[name][key] = &labeledCounter{labels: cloneLabels(labels), counter: counter}
 }
 
-// RegisterLabeledGauge registers a gauge with labels.
+// RegisterLabeledGauge registers a gauge with labels. Label sets rejected by
+// the registry's LabelLimits are not registered.
 func (hpr *registry) RegisterLabeledGauge(name string, labels Labels, gauge *metricGauge) {
+	labels, err := hpr.seriesLabels(labels)
+	if err != nil {
+		return
+	}
 	hpr.mu.Lock()
 	defer hpr.mu.Unlock()
 	key := labelsKeyFromLabels(labels)
@@ -649,8 +1092,13 @@ func (hpr *registry) RegisterLabeledGauge(name string, labels Labels, gauge *met
 	hpr.gauges[name][key] = &labeledGauge{labels: cloneLabels(labels), gauge: gauge}
 }
 
-// RegisterLabeledHistogram registers a histogram with labels.
+// RegisterLabeledHistogram registers a histogram with labels. Label sets rejected by
+// the registry's LabelLimits are not registered.
 func (hpr *registry) RegisterLabeledHistogram(name string, labels Labels, histogram *metricHistogram) {
+	labels, err := hpr.seriesLabels(labels)
+	if err != nil {
+		return
+	}
 	hpr.mu.Lock()
 	defer hpr.mu.Unlock()
 	key := labelsKeyFromLabels(labels)
@@ -660,8 +1108,13 @@ func (hpr *registry) RegisterLabeledHistogram(name string, labels Labels, histog
 	hpr.histograms[name][key] = &labeledHistogram{labels: cloneLabels(labels), histogram: histogram}
 }
 
-// RegisterLabeledSummary registers a summary with labels.
+// RegisterLabeledSummary registers a summary with labels. Label sets rejected by
+// the registry's LabelLimits are not registered.
 func (hpr *registry) RegisterLabeledSummary(name string, labels Labels, summary *metricSummary) {
+	labels, err := hpr.seriesLabels(labels)
+	if err != nil {
+		return
+	}
 	hpr.mu.Lock()
 	defer hpr.mu.Unlock()
 	key := labelsKeyFromLabels(labels)
@@ -671,67 +1124,293 @@ func (hpr *registry) RegisterLabeledSummary(name string, labels Labels, summary
 	hpr.summaries[name][key] = &labeledSummary{labels: cloneLabels(labels), summary: summary}
 }
 
-// deregisterLabeled drops all label-permutation children for the named
-// metric across counter/gauge/histogram/summary registries. Called by
-// {Counter,Gauge,Histogram,Summary}Vec.Reset() to mirror prometheus
-// vec semantics.
-func (hpr *registry) deregisterLabeled(name string) {
-	hpr.mu.Lock()
-	defer hpr.mu.Unlock()
-	delete(hpr.counters, name)
-	delete(hpr.gauges, name)
-	delete(hpr.histograms, name)
-	delete(hpr.summaries, name)
+// seriesLabels returns labels as the registry stores them: normalized by the
+// registry's LabelNormalizer, then adjusted to its LabelLimits.
+func (hpr *registry) seriesLabels(labels Labels) (Labels, error) {
+	return hpr.labelLimits.apply(hpr.labelNormalizer.apply(labels))
 }
 
-// NewCounter creates and registers a counter.
-func (hpr *registry) NewCounter(name, help string) Counter {
+// counterFor returns the counter registered under name and labels, creating
+// it if absent. Vec children go through here so they adopt series that
+// already exist in the registry (e.g. ones re-created by Restore). Labels
+// are subject to the registry's LabelLimits; a rejected series is returned
+// detached from the registry along with the error.
+func (hpr *registry) counterFor(name, help string, labels Labels) (*metricCounter, error) {
+	labels, err := hpr.seriesLabels(labels)
+	if err != nil {
+		return newCounter(name, help), err
+	}
+	key := labelsKeyFromLabels(labels)
+	hpr.mu.Lock()
+	defer hpr.mu.Unlock()
+	if entry, ok := hpr.counters[name][key]; ok {
+		return entry.counter, nil
+	}
 	counter := newCounter(name, help)
-	hpr.RegisterCounter(name, counter)
-	return counter
-}
-
-// NewCounterVec creates and registers a counter vec.
-func (hpr *registry) NewCounterVec(name, help string, labelNames []string) CounterVec {
-	return newCounterVec(hpr, name, help, labelNames)
+	if hpr.counters[name] == nil {
+		hpr.counters[name] = make(map[string]*labeledCounter)
+	}
+	hpr.counters[name][key] = &labeledCounter{labels: cloneLabels(labels), counter: counter}
+	return counter, nil
 }
 
-// NewGauge creates and registers a gauge.
-func (hpr *registry) NewGauge(name, help string) Gauge {
+// gaugeFor is counterFor for gauges.
+func (hpr *registry) gaugeFor(name, help string, labels Labels) (*metricGauge, error) {
+	labels, err := hpr.seriesLabels(labels)
+	if err != nil {
+		return newGauge(name, help), err
+	}
+	key := labelsKeyFromLabels(labels)
+	hpr.mu.Lock()
+	defer hpr.mu.Unlock()
+	if entry, ok := hpr.gauges[name][key]; ok {
+		return entry.gauge, nil
+	}
 	gauge := newGauge(name, help)
-	hpr.RegisterGauge(name, gauge)
-	return gauge
-}
-
-// NewGaugeVec creates and registers a gauge vec.
-func (hpr *registry) NewGaugeVec(name, help string, labelNames []string) GaugeVec {
-	return newGaugeVec(hpr, name, help, labelNames)
+	if hpr.gauges[name] == nil {
+		hpr.gauges[name] = make(map[string]*labeledGauge)
+	}
+	hpr.gauges[name][key] = &labeledGauge{labels: cloneLabels(labels), gauge: gauge}
+	return gauge, nil
 }
 
-// NewHistogram creates and registers a histogram.
-func (hpr *registry) NewHistogram(name, help string, buckets []float64) Histogram {
-	histogram := newHistogram(name, help, buckets)
-	hpr.RegisterHistogram(name, histogram)
-	return histogram
+// histogramFor is counterFor for histograms.
+func (hpr *registry) histogramFor(name, help string, labels Labels, buckets []float64) (*metricHistogram, error) {
+	labels, err := hpr.seriesLabels(labels)
+	if err != nil {
+		return newHistogram(name, help, buckets), err
+	}
+	key := labelsKeyFromLabels(labels)
+	hpr.mu.Lock()
+	defer hpr.mu.Unlock()
+	if entry, ok := hpr.histograms[name][key]; ok {
+		return entry.histogram, nil
+	}
+	histogram := newHistogram(name, help, buckets)
+	if hpr.histograms[name] == nil {
+		hpr.histograms[name] = make(map[string]*labeledHistogram)
+	}
+	hpr.histograms[name][key] = &labeledHistogram{labels: cloneLabels(labels), histogram: histogram}
+	return histogram, nil
+}
+
+// summaryFor is counterFor for summaries.
+func (hpr *registry) summaryFor(name, help string, labels Labels, objectives map[float64]float64) (*metricSummary, error) {
+	labels, err := hpr.seriesLabels(labels)
+	if err != nil {
+		return newSummary(name, help, objectives), err
+	}
+	key := labelsKeyFromLabels(labels)
+	hpr.mu.Lock()
+	defer hpr.mu.Unlock()
+	if entry, ok := hpr.summaries[name][key]; ok {
+		return entry.summary, nil
+	}
+	summary := hpr.newSummaryLocked(name, help, objectives)
+	if hpr.summaries[name] == nil {
+		hpr.summaries[name] = make(map[string]*labeledSummary)
+	}
+	hpr.summaries[name][key] = &labeledSummary{labels: cloneLabels(labels), summary: summary}
+	return summary, nil
+}
+
+// deregisterLabeled drops all label-permutation children for the named
+// metric across counter/gauge/histogram/summary registries. Called by
+// {Counter,Gauge,Histogram,Summary}Vec.Reset() to mirror prometheus
+// vec semantics.
+func (hpr *registry) deregisterLabeled(name string) {
+	hpr.mu.Lock()
+	defer hpr.mu.Unlock()
+	delete(hpr.counters, name)
+	delete(hpr.gauges, name)
+	delete(hpr.histograms, name)
+	delete(hpr.summaries, name)
+}
+
+// deleteCounterSeries removes the counter series of name whose labels
+// include every pair in match.
+func (hpr *registry) deleteCounterSeries(name string, match Labels) int {
+	hpr.mu.Lock()
+	defer hpr.mu.Unlock()
+	return deletePartialMatch(hpr.counters, name, match, func(e *labeledCounter) Labels { return e.labels })
+}
+
+// deleteGaugeSeries is deleteCounterSeries for gauges.
+func (hpr *registry) deleteGaugeSeries(name string, match Labels) int {
+	hpr.mu.Lock()
+	defer hpr.mu.Unlock()
+	return deletePartialMatch(hpr.gauges, name, match, func(e *labeledGauge) Labels { return e.labels })
+}
+
+// deletePartialMatch removes the entries of families[name] whose labels
+// include every pair in match, and drops the family once it is empty so
+// Gather never sees an empty series map.
+func deletePartialMatch[E any](families map[string]map[string]E, name string, match Labels, labelsOf func(E) Labels) int {
+	if len(match) == 0 {
+		return 0
+	}
+	removed := 0
+	for key, entry := range families[name] {
+		if labelsContain(labelsOf(entry), match) {
+			delete(families[name], key)
+			removed++
+		}
+	}
+	if removed > 0 && len(families[name]) == 0 {
+		delete(families, name)
+	}
+	return removed
+}
+
+// labelsContain reports whether labels has every pair in match.
+func labelsContain(labels, match Labels) bool {
+	for name, value := range match {
+		if v, ok := labels[name]; !ok || v != value {
+			return false
+		}
+	}
+	return true
+}
+
+// NewCounter creates and registers a counter. Creating the same name twice
+// returns the existing counter; panics if the name is taken by another type.
+func (hpr *registry) NewCounter(name, help string) Counter {
+	hpr.mu.Lock()
+	defer hpr.mu.Unlock()
+	hpr.mustClaimName(name, MetricTypeCounter, help)
+	return hpr.counterLocked(name, help)
+}
+
+// counterLocked returns the unlabeled counter name, creating it if absent. Callers
+// must hold hpr.mu and have claimed name.
+func (hpr *registry) counterLocked(name, help string) *metricCounter {
+	if existing, ok := hpr.counters[name][""]; ok {
+		return existing.counter
+	}
+	counter := newCounter(name, help)
+	if hpr.counters[name] == nil {
+		hpr.counters[name] = make(map[string]*labeledCounter)
+	}
+	hpr.counters[name][""] = &labeledCounter{counter: counter}
+	return counter
+}
+
+// NewCounterVec creates and registers a counter vec.
+func (hpr *registry) NewCounterVec(name, help string, labelNames []string) CounterVec {
+	hpr.claimVec(name, MetricTypeCounter, vecSchema{help: help, labelNames: labelNames})
+	return newCounterVec(hpr, name, help, labelNames)
+}
+
+// NewGauge creates and registers a gauge. Creating the same name twice
+// returns the existing gauge; panics if the name is taken by another type.
+func (hpr *registry) NewGauge(name, help string) Gauge {
+	hpr.mu.Lock()
+	defer hpr.mu.Unlock()
+	hpr.mustClaimName(name, MetricTypeGauge, help)
+	return hpr.gaugeLocked(name, help)
+}
+
+// gaugeLocked is counterLocked for gauges.
+func (hpr *registry) gaugeLocked(name, help string) *metricGauge {
+	if existing, ok := hpr.gauges[name][""]; ok {
+		return existing.gauge
+	}
+	gauge := newGauge(name, help)
+	if hpr.gauges[name] == nil {
+		hpr.gauges[name] = make(map[string]*labeledGauge)
+	}
+	hpr.gauges[name][""] = &labeledGauge{gauge: gauge}
+	return gauge
+}
+
+// NewGaugeVec creates and registers a gauge vec.
+func (hpr *registry) NewGaugeVec(name, help string, labelNames []string) GaugeVec {
+	hpr.claimVec(name, MetricTypeGauge, vecSchema{help: help, labelNames: labelNames})
+	return newGaugeVec(hpr, name, help, labelNames)
+}
+
+// NewHistogram creates and registers a histogram. Creating the same name
+// twice returns the existing histogram; panics if the name is taken by
+// another type.
+func (hpr *registry) NewHistogram(name, help string, buckets []float64) Histogram {
+	hpr.mu.Lock()
+	defer hpr.mu.Unlock()
+	hpr.mustClaimName(name, MetricTypeHistogram, help)
+	return hpr.histogramLocked(name, help, buckets)
+}
+
+// histogramLocked is counterLocked for histograms.
+func (hpr *registry) histogramLocked(name, help string, buckets []float64) *metricHistogram {
+	if existing, ok := hpr.histograms[name][""]; ok {
+		return existing.histogram
+	}
+	histogram := newHistogram(name, help, buckets)
+	if hpr.histograms[name] == nil {
+		hpr.histograms[name] = make(map[string]*labeledHistogram)
+	}
+	hpr.histograms[name][""] = &labeledHistogram{histogram: histogram}
+	return histogram
 }
 
 // NewHistogramVec creates and registers a histogram vec.
 func (hpr *registry) NewHistogramVec(name, help string, labelNames []string, buckets []float64) HistogramVec {
+	hpr.claimVec(name, MetricTypeHistogram, vecSchema{help: help, labelNames: labelNames, buckets: buckets})
 	return newHistogramVec(hpr, name, help, labelNames, buckets)
 }
 
-// NewSummary creates and registers a summary.
+// NewSummary creates and registers a summary. Creating the same name twice
+// returns the existing summary; panics if the name is taken by another type.
 func (hpr *registry) NewSummary(name, help string, objectives map[float64]float64) Summary {
+	hpr.mu.Lock()
+	defer hpr.mu.Unlock()
+	hpr.mustClaimName(name, MetricTypeSummary, help)
+	return hpr.summaryLocked(name, help, objectives)
+}
+
+// summaryLocked is counterLocked for summaries.
+func (hpr *registry) summaryLocked(name, help string, objectives map[float64]float64) *metricSummary {
+	if existing, ok := hpr.summaries[name][""]; ok {
+		return existing.summary
+	}
+	summary := hpr.newSummaryLocked(name, help, objectives)
+	if hpr.summaries[name] == nil {
+		hpr.summaries[name] = make(map[string]*labeledSummary)
+	}
+	hpr.summaries[name][""] = &labeledSummary{summary: summary}
+	return summary
+}
+
+// newSummaryLocked creates a summary using the estimator set for name, if
+// any. Callers must hold hpr.mu.
+func (hpr *registry) newSummaryLocked(name, help string, objectives map[float64]float64) *metricSummary {
 	summary := newSummary(name, help, objectives)
-	hpr.RegisterSummary(name, summary)
+	if newEstimator := hpr.estimators[name]; newEstimator != nil {
+		summary.estimator = newEstimator()
+	}
 	return summary
 }
 
 // NewSummaryVec creates and registers a summary vec.
 func (hpr *registry) NewSummaryVec(name, help string, labelNames []string, objectives map[float64]float64) SummaryVec {
+	hpr.claimVec(name, MetricTypeSummary, vecSchema{help: help, labelNames: labelNames, objectives: objectives})
 	return newSummaryVec(hpr, name, help, labelNames, objectives)
 }
 
+// NewUntyped creates and registers an untyped value. Creating the same name
+// twice returns the existing value; panics if the name is taken by another
+// type.
+func (hpr *registry) NewUntyped(name, help string) Gauge {
+	hpr.mu.Lock()
+	defer hpr.mu.Unlock()
+	hpr.mustClaimName(name, MetricTypeUntyped, help)
+	if existing, ok := hpr.untyped[name]; ok {
+		return existing
+	}
+	untyped := &metricUntyped{newGauge(name, help)}
+	hpr.untyped[name] = untyped
+	return untyped
+}
+
 // Registry returns the registry itself.
 func (hpr *registry) Registry() Registry {
 	return hpr
@@ -739,11 +1418,11 @@ func (hpr *registry) Registry() Registry {
 
 // Register is a compatibility no-op. Metrics are registered on creation.
 func (hpr *registry) Register(c Collector) error {
-	name, typ, ok := collectorIdentity(c)
+	name, typ, help, ok := collectorIdentity(c)
 	if !ok {
 		return fmt.Errorf("unsupported collector type %T", c)
 	}
-	if err := hpr.registerName(name, typ); err != nil {
+	if err := hpr.registerName(name, typ, help); err != nil {
 		return err
 	}
 	switch v := c.(type) {
@@ -755,14 +1434,30 @@ func (hpr *registry) Register(c Collector) error {
 		hpr.RegisterHistogram(name, v)
 	case *metricSummary:
 		hpr.RegisterSummary(name, v)
+	case *metricUntyped:
+		hpr.mu.Lock()
+		hpr.untyped[name] = v
+		hpr.mu.Unlock()
+	case *NativeHistogram:
+		hpr.mu.Lock()
+		hpr.natives[name] = v
+		hpr.mu.Unlock()
 	case *counterVec:
 		v.registry = hpr
+		hpr.recordVec(name, vecSchema{help: v.help, labelNames: v.labelNames})
 	case *gaugeVec:
 		v.registry = hpr
+		hpr.recordVec(name, vecSchema{help: v.help, labelNames: v.labelNames})
 	case *histogramVec:
 		v.registry = hpr
+		hpr.recordVec(name, vecSchema{help: v.help, labelNames: v.labelNames, buckets: v.buckets})
 	case *summaryVec:
 		v.registry = hpr
+		hpr.recordVec(name, vecSchema{help: v.help, labelNames: v.labelNames, objectives: v.objectives})
+	case *buildInfoCollector:
+		gauge := newGauge(name, buildInfoHelp)
+		gauge.Set(1)
+		hpr.RegisterLabeledGauge(name, v.labels, gauge)
 	}
 	return nil
 }
@@ -780,82 +1475,382 @@ func (hpr *registry) MustRegister(cs ...Collector) {
 // frees its name for re-registration. Returns true if the name was registered.
 // Mirrors prometheus.Registerer.Unregister.
 func (hpr *registry) Unregister(c Collector) bool {
-	name, _, ok := collectorIdentity(c)
+	name, _, _, ok := collectorIdentity(c)
 	if !ok {
 		return false
 	}
 	hpr.mu.Lock()
 	defer hpr.mu.Unlock()
 	_, had := hpr.registered[name]
+	hpr.removeNameLocked(name)
+	return had
+}
+
+// UnregisterByName drops every series of the named family, for callers that
+// no longer hold the original collector. Returns the number of series
+// removed.
+func (hpr *registry) UnregisterByName(name string) int {
+	hpr.mu.Lock()
+	defer hpr.mu.Unlock()
+	return hpr.removeNameLocked(name)
+}
+
+// Reset forgets every registered metric, name claim, help string and unit,
+// giving tests that share a registry a clean slate. Handles created before
+// the reset are detached: they keep working but are no longer gathered.
+func (hpr *registry) Reset() {
+	fresh := newRegistry()
+	hpr.mu.Lock()
+	defer hpr.mu.Unlock()
+	hpr.counters = fresh.counters
+	hpr.gauges = fresh.gauges
+	hpr.histograms = fresh.histograms
+	hpr.summaries = fresh.summaries
+	hpr.untyped = fresh.untyped
+	hpr.natives = fresh.natives
+	hpr.registered = fresh.registered
+	hpr.types = fresh.types
+	hpr.vecs = fresh.vecs
+	hpr.help = fresh.help
+	hpr.units = fresh.units
+	hpr.estimators = fresh.estimators
+	hpr.aliases = fresh.aliases
+	hpr.derived = fresh.derived
+}
+
+// Stats counts the registry's families by type and its series.
+func (hpr *registry) Stats() RegistryStats {
+	hpr.mu.RLock()
+	defer hpr.mu.RUnlock()
+	var stats RegistryStats
+	for _, typ := range hpr.types {
+		switch typ {
+		case MetricTypeCounter:
+			stats.Counters++
+		case MetricTypeGauge:
+			stats.Gauges++
+		case MetricTypeHistogram:
+			stats.Histograms++
+		case MetricTypeSummary:
+			stats.Summaries++
+		default:
+			stats.Untyped++
+		}
+	}
+	for _, series := range hpr.counters {
+		stats.Series += len(series)
+	}
+	for _, series := range hpr.gauges {
+		stats.Series += len(series)
+	}
+	for _, series := range hpr.histograms {
+		stats.Series += len(series)
+	}
+	for _, series := range hpr.summaries {
+		stats.Series += len(series)
+	}
+	stats.Series += len(hpr.untyped) + len(hpr.natives)
+	return stats
+}
+
+// hasFamilyLocked reports whether name is claimed or has series, including
+// series registered directly without claiming it. Callers must hold hpr.mu.
+func (hpr *registry) hasFamilyLocked(name string) bool {
+	if _, ok := hpr.types[name]; ok {
+		return true
+	}
+	if _, ok := hpr.untyped[name]; ok {
+		return true
+	}
+	if _, ok := hpr.natives[name]; ok {
+		return true
+	}
+	return len(hpr.counters[name])+len(hpr.gauges[name])+len(hpr.histograms[name])+len(hpr.summaries[name]) > 0
+}
+
+// removeNameLocked forgets name entirely and returns how many series it
+// had. Callers must hold hpr.mu.
+func (hpr *registry) removeNameLocked(name string) int {
+	n := len(hpr.counters[name]) + len(hpr.gauges[name]) + len(hpr.histograms[name]) + len(hpr.summaries[name])
+	if _, ok := hpr.untyped[name]; ok {
+		n++
+	}
+	if _, ok := hpr.natives[name]; ok {
+		n++
+	}
 	delete(hpr.registered, name)
+	delete(hpr.types, name)
+	delete(hpr.vecs, name)
+	delete(hpr.help, name)
+	delete(hpr.units, name)
+	delete(hpr.estimators, name)
+	delete(hpr.derived, name)
 	delete(hpr.counters, name)
 	delete(hpr.gauges, name)
 	delete(hpr.histograms, name)
 	delete(hpr.summaries, name)
-	return had
+	delete(hpr.untyped, name)
+	delete(hpr.natives, name)
+	return n
 }
 
-// Gather returns metric families for all registered metrics.
+// Gather returns metric families for all registered metrics. Families are
+// grouped by type (counters, gauges, histograms, summaries) and sorted by
+// name within each group, with series sorted by label set, so repeated
+// gathers of an unchanged registry produce identical output.
 func (hpr *registry) Gather() ([]*MetricFamily, error) {
+	var families []*MetricFamily
+	err := hpr.GatherFunc(func(family *MetricFamily) error {
+		families = append(families, family)
+		return nil
+	})
+	return families, err
+}
+
+// GatherFunc calls fn with each family in Gather order, building families one
+// at a time so callers can encode and release them without materializing the
+// whole registry. The registry lock is only held while collecting series
+// references, not while fn runs. An error from fn stops iteration and is
+// returned.
+func (hpr *registry) GatherFunc(fn func(*MetricFamily) error) error {
+	for _, build := range hpr.familyBuilders() {
+		for _, family := range build() {
+			if err := fn(family); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// setUnit records the OpenMetrics unit exposed for family name.
+func (hpr *registry) setUnit(name, unit string) {
+	hpr.mu.Lock()
+	defer hpr.mu.Unlock()
+	hpr.units[name] = unit
+}
+
+// setEstimator makes summaries of family name created from now on use
+// estimators from newEstimator.
+func (hpr *registry) setEstimator(name string, newEstimator func() QuantileEstimator) {
+	hpr.mu.Lock()
+	defer hpr.mu.Unlock()
+	hpr.estimators[name] = newEstimator
+}
+
+// familyHelp returns the help fixed for name at registration, or fallback for
+// series registered directly without claiming the name. Callers must hold
+// hpr.mu.
+func (hpr *registry) familyHelp(name, fallback string) string {
+	if help, ok := hpr.help[name]; ok {
+		return help
+	}
+	return fallback
+}
+
+// familyBuilders snapshots the series of every family, in Gather order, as
+// closures that build the exposed families on demand. Histograms and
+// summaries also build their _nan_total family when NaNs were dropped,
+// unless a registered family already has that name.
+func (hpr *registry) familyBuilders() []func() []*MetricFamily {
 	hpr.mu.RLock()
 	defer hpr.mu.RUnlock()
 
-	var families []*MetricFamily
-	for name, entries := range hpr.counters {
-		help := ""
-		for _, entry := range entries {
-			help = entry.counter.help
-			break
-		}
-		family := &MetricFamily{Name: name, Help: help, Type: MetricTypeCounter}
-		for _, entry := range entries {
-			family.Metrics = append(family.Metrics, Metric{
-				Labels: labelsToLabelPairs(entry.labels),
-				Value:  MetricValue{Value: entry.counter.Get()},
+	builders := make([]func() []*MetricFamily, 0, len(hpr.types))
+	for _, name := range sortedKeys(hpr.counters) {
+		entries := sortedValues(hpr.counters[name])
+		help, unit := hpr.familyHelp(name, entries[0].counter.help), hpr.units[name]
+		builders = append(builders, func() []*MetricFamily {
+			family := &MetricFamily{Name: name, Help: help, Type: MetricTypeCounter, Unit: unit}
+			for _, entry := range entries {
+				family.Metrics = append(family.Metrics, Metric{
+					Labels: labelsToLabelPairs(entry.labels),
+					Value:  MetricValue{Value: entry.counter.Get()},
+				})
+			}
+			return []*MetricFamily{family}
+		})
+	}
+	for _, name := range sortedKeys(hpr.gauges) {
+		entries := sortedValues(hpr.gauges[name])
+		help, unit := hpr.familyHelp(name, entries[0].gauge.help), hpr.units[name]
+		builders = append(builders, func() []*MetricFamily {
+			family := &MetricFamily{Name: name, Help: help, Type: MetricTypeGauge, Unit: unit}
+			for _, entry := range entries {
+				family.Metrics = append(family.Metrics, Metric{
+					Labels: labelsToLabelPairs(entry.labels),
+					Value:  MetricValue{Value: entry.gauge.Get()},
+				})
+			}
+			return []*MetricFamily{family}
+		})
+	}
+	histogramNames := sortedKeys(hpr.histograms)
+	for name := range hpr.natives {
+		histogramNames = append(histogramNames, name)
+	}
+	sort.Strings(histogramNames)
+	for _, name := range histogramNames {
+		reportNaN := !hpr.hasFamilyLocked(name + "_nan_total")
+		if nh, ok := hpr.natives[name]; ok {
+			help, unit := hpr.familyHelp(name, nh.help), hpr.units[name]
+			builders = append(builders, func() []*MetricFamily {
+				family := &MetricFamily{Name: name, Help: help, Type: MetricTypeHistogram, Unit: unit, Metrics: []Metric{nh.ToMetric(nil)}}
+				if n := nh.GetNaNCount(); n > 0 && reportNaN {
+					return []*MetricFamily{family, {
+						Name:    name + "_nan_total",
+						Help:    "NaN observations dropped by " + name,
+						Type:    MetricTypeCounter,
+						Metrics: []Metric{{Value: MetricValue{Value: float64(n)}}},
+					}}
+				}
+				return []*MetricFamily{family}
 			})
+			continue
 		}
-		families = append(families, family)
+		entries := sortedValues(hpr.histograms[name])
+		help, unit := hpr.familyHelp(name, entries[0].histogram.help), hpr.units[name]
+		builders = append(builders, func() []*MetricFamily {
+			family := &MetricFamily{Name: name, Help: help, Type: MetricTypeHistogram, Unit: unit}
+			nan := &MetricFamily{Name: name + "_nan_total", Help: "NaN observations dropped by " + name, Type: MetricTypeCounter}
+			for _, entry := range entries {
+				labels := labelsToLabelPairs(entry.labels)
+				family.Metrics = append(family.Metrics, entry.histogram.ToMetric(labels))
+				if n := entry.histogram.GetNaNCount(); n > 0 {
+					nan.Metrics = append(nan.Metrics, Metric{Labels: labels, Value: MetricValue{Value: float64(n)}})
+				}
+			}
+			if len(nan.Metrics) > 0 && reportNaN {
+				return []*MetricFamily{family, nan}
+			}
+			return []*MetricFamily{family}
+		})
+	}
+	for _, name := range sortedKeys(hpr.summaries) {
+		entries := sortedValues(hpr.summaries[name])
+		help, unit := hpr.familyHelp(name, entries[0].summary.help), hpr.units[name]
+		reportNaN := !hpr.hasFamilyLocked(name + "_nan_total")
+		builders = append(builders, func() []*MetricFamily {
+			family := &MetricFamily{Name: name, Help: help, Type: MetricTypeSummary, Unit: unit}
+			nan := &MetricFamily{Name: name + "_nan_total", Help: "NaN observations dropped by " + name, Type: MetricTypeCounter}
+			for _, entry := range entries {
+				labels := labelsToLabelPairs(entry.labels)
+				family.Metrics = append(family.Metrics, entry.summary.ToMetric(labels))
+				if n := entry.summary.GetNaNCount(); n > 0 {
+					nan.Metrics = append(nan.Metrics, Metric{Labels: labels, Value: MetricValue{Value: float64(n)}})
+				}
+			}
+			if len(nan.Metrics) > 0 && reportNaN {
+				return []*MetricFamily{family, nan}
+			}
+			return []*MetricFamily{family}
+		})
+	}
+	for _, name := range sortedKeys(hpr.untyped) {
+		untyped := hpr.untyped[name]
+		builders = append(builders, func() []*MetricFamily {
+			return []*MetricFamily{{
+				Name:    name,
+				Help:    untyped.help,
+				Type:    MetricTypeUntyped,
+				Metrics: []Metric{{Value: MetricValue{Value: untyped.Get()}}},
+			}}
+		})
+	}
+
+	aliases := hpr.aliasesLocked()
+	if len(aliases) == 0 {
+		return builders
+	}
+	for i, build := range builders {
+		builders[i] = func() []*MetricFamily { return withAliases(build(), aliases) }
+	}
+	return builders
+}
+
+// Alias makes Gather also expose family newName under oldName, sharing its
+// series and values, so dashboards keep working through a rename. oldName
+// must not be a registered family; aliasing it again retargets it. If a
+// family named oldName is registered later, it takes precedence over the
+// alias.
+func (hpr *registry) Alias(oldName, newName string) error {
+	if err := ValidateMetricName(oldName); err != nil {
+		return err
 	}
-	for name, entries := range hpr.gauges {
-		help := ""
-		for _, entry := range entries {
-			help = entry.gauge.help
-			break
-		}
-		family := &MetricFamily{Name: name, Help: help, Type: MetricTypeGauge}
-		for _, entry := range entries {
-			family.Metrics = append(family.Metrics, Metric{
-				Labels: labelsToLabelPairs(entry.labels),
-				Value:  MetricValue{Value: entry.gauge.Get()},
-			})
-		}
-		families = append(families, family)
+	if oldName == newName {
+		return fmt.Errorf("metric %q cannot alias itself", oldName)
 	}
-	for name, entries := range hpr.histograms {
-		help := ""
-		for _, entry := range entries {
-			help = entry.histogram.help
-			break
-		}
-		family := &MetricFamily{Name: name, Help: help, Type: MetricTypeHistogram}
-		for _, entry := range entries {
-			family.Metrics = append(family.Metrics, entry.histogram.ToMetric(labelsToLabelPairs(entry.labels)))
-		}
-		families = append(families, family)
+	hpr.mu.Lock()
+	defer hpr.mu.Unlock()
+	if typ, ok := hpr.types[oldName]; ok {
+		return fmt.Errorf("metric %q already registered as %s", oldName, typ.String())
 	}
-	for name, entries := range hpr.summaries {
-		help := ""
-		for _, entry := range entries {
-			help = entry.summary.help
-			break
+	hpr.aliases[oldName] = newName
+	return nil
+}
+
+// RemoveAlias stops exposing oldName, reporting whether it was an alias.
+func (hpr *registry) RemoveAlias(oldName string) bool {
+	hpr.mu.Lock()
+	defer hpr.mu.Unlock()
+	_, ok := hpr.aliases[oldName]
+	delete(hpr.aliases, oldName)
+	return ok
+}
+
+// aliasesLocked returns the old names to expose for each target family,
+// sorted, leaving out old names now taken by a real family. Callers must
+// hold hpr.mu.
+func (hpr *registry) aliasesLocked() map[string][]string {
+	byTarget := make(map[string][]string, len(hpr.aliases))
+	for _, oldName := range sortedKeys(hpr.aliases) {
+		if _, taken := hpr.types[oldName]; taken {
+			continue
 		}
-		family := &MetricFamily{Name: name, Help: help, Type: MetricTypeSummary}
-		for _, entry := range entries {
-			family.Metrics = append(family.Metrics, entry.summary.ToMetric(labelsToLabelPairs(entry.labels)))
+		target := hpr.aliases[oldName]
+		byTarget[target] = append(byTarget[target], oldName)
+	}
+	return byTarget
+}
+
+// withAliases returns families with a copy of each aliased family, renamed,
+// right after it. A unit the old name does not end in is dropped from the
+// copy so it still encodes as OpenMetrics.
+func withAliases(families []*MetricFamily, aliases map[string][]string) []*MetricFamily {
+	result := make([]*MetricFamily, 0, len(families))
+	for _, mf := range families {
+		result = append(result, mf)
+		for _, oldName := range aliases[mf.Name] {
+			alias := *mf
+			alias.Name = oldName
+			alias.Metrics = append([]Metric(nil), mf.Metrics...)
+			if alias.validateUnit() != nil {
+				alias.Unit = ""
+			}
+			result = append(result, &alias)
 		}
-		families = append(families, family)
 	}
-	return families, nil
+	return result
+}
+
+// sortedKeys returns the keys of m in ascending order.
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// sortedValues returns the values of m ordered by key.
+func sortedValues[V any](m map[string]V) []V {
+	keys := sortedKeys(m)
+	values := make([]V, len(keys))
+	for i, k := range keys {
+		values[i] = m[k]
+	}
+	return values
 }
 
 // counterVec is a labeled counter collection.
@@ -864,40 +1859,79 @@ type counterVec struct {
 	name       string
 	help       string
 	labelNames []string
+	labelOrder []int // indices of labelNames in sorted name order
 	mu         sync.Mutex
 	counters   map[string]Counter
 }
 
 func newCounterVec(registry *registry, name, help string, labelNames []string) *counterVec {
+	mustValidLabelNames(name, labelNames)
 	return &counterVec{
 		registry:   registry,
 		name:       name,
 		help:       help,
 		labelNames: append([]string(nil), labelNames...),
+		labelOrder: sortedLabelOrder(labelNames),
 		counters:   make(map[string]Counter),
 	}
 }
 
 func (v *counterVec) With(labels Labels) Counter {
-	return v.getOrCreate(labels)
+	series, _ := v.getOrCreate(labels)
+	return series
 }
 
 func (v *counterVec) WithLabelValues(values ...string) Counter {
-	labels := labelsFromValues(v.labelNames, values)
+	// Hot path: look the child up by a key built straight from the ordered
+	// values, and only build a Labels map on first use.
+	key := labelsKeyFromValues(v.labelNames, v.labelOrder, values)
+	v.mu.Lock()
+	series, ok := v.counters[key]
+	v.mu.Unlock()
+	if ok {
+		return series
+	}
+	series, _ = v.getOrCreate(labelsFromValues(v.labelNames, values))
+	return series
+}
+
+func (v *counterVec) GetMetricWith(labels Labels) (Counter, error) {
+	if err := checkLabels(v.labelNames, labels); err != nil {
+		return nil, err
+	}
 	return v.getOrCreate(labels)
 }
 
-func (v *counterVec) getOrCreate(labels Labels) Counter {
+func (v *counterVec) GetMetricWithLabelValues(values ...string) (Counter, error) {
+	labels, err := labelsFromValuesChecked(v.labelNames, values)
+	if err != nil {
+		return nil, err
+	}
+	return v.getOrCreate(labels)
+}
+
+// getOrCreate returns the child for labels, adopting the registry's series if
+// one exists. The vec lock is held across counterFor so a concurrent Reset
+// can't leave a detached child cached; this is safe because locks are always
+// taken vec first, then registry, and the registry never calls into a vec.
+func (v *counterVec) getOrCreate(labels Labels) (Counter, error) {
 	key := labelsKeyFromLabels(labels)
 	v.mu.Lock()
 	defer v.mu.Unlock()
 	if c, ok := v.counters[key]; ok {
-		return c
+		return c, nil
+	}
+	counter, err := v.registry.counterFor(v.name, v.help, labels)
+	if err != nil {
+		return counter, err
 	}
-	counter := newCounter(v.name, v.help)
-	v.registry.RegisterLabeledCounter(v.name, labels, counter)
 	v.counters[key] = counter
-	return counter
+	return counter, nil
+}
+
+// Prewarm creates a child for each set of label values.
+func (v *counterVec) Prewarm(valueSets [][]string) error {
+	return prewarm(valueSets, v.GetMetricWithLabelValues)
 }
 
 // Reset drops every label-permutation child from this vec. Mirrors
@@ -909,46 +1943,92 @@ func (v *counterVec) Reset() {
 	v.counters = make(map[string]Counter)
 }
 
+// DeletePartialMatch removes every child whose labels include all of match.
+func (v *counterVec) DeletePartialMatch(match Labels) int {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	removed := v.registry.deleteCounterSeries(v.name, match)
+	if removed > 0 {
+		// Surviving children are re-adopted from the registry on next use.
+		v.counters = make(map[string]Counter)
+	}
+	return removed
+}
+
 // gaugeVec is a labeled gauge collection.
 type gaugeVec struct {
 	registry   *registry
 	name       string
 	help       string
 	labelNames []string
+	labelOrder []int // indices of labelNames in sorted name order
 	mu         sync.Mutex
 	gauges     map[string]Gauge
 }
 
 func newGaugeVec(registry *registry, name, help string, labelNames []string) *gaugeVec {
+	mustValidLabelNames(name, labelNames)
 	return &gaugeVec{
 		registry:   registry,
 		name:       name,
 		help:       help,
 		labelNames: append([]string(nil), labelNames...),
+		labelOrder: sortedLabelOrder(labelNames),
 		gauges:     make(map[string]Gauge),
 	}
 }
 
 func (v *gaugeVec) With(labels Labels) Gauge {
-	return v.getOrCreate(labels)
+	series, _ := v.getOrCreate(labels)
+	return series
 }
 
 func (v *gaugeVec) WithLabelValues(values ...string) Gauge {
-	labels := labelsFromValues(v.labelNames, values)
+	// Hot path: look the child up by a key built straight from the ordered
+	// values, and only build a Labels map on first use.
+	key := labelsKeyFromValues(v.labelNames, v.labelOrder, values)
+	v.mu.Lock()
+	series, ok := v.gauges[key]
+	v.mu.Unlock()
+	if ok {
+		return series
+	}
+	series, _ = v.getOrCreate(labelsFromValues(v.labelNames, values))
+	return series
+}
+
+func (v *gaugeVec) GetMetricWith(labels Labels) (Gauge, error) {
+	if err := checkLabels(v.labelNames, labels); err != nil {
+		return nil, err
+	}
 	return v.getOrCreate(labels)
 }
 
-func (v *gaugeVec) getOrCreate(labels Labels) Gauge {
+func (v *gaugeVec) GetMetricWithLabelValues(values ...string) (Gauge, error) {
+	labels, err := labelsFromValuesChecked(v.labelNames, values)
+	if err != nil {
+		return nil, err
+	}
+	return v.getOrCreate(labels)
+}
+
+func (v *gaugeVec) getOrCreate(labels Labels) (Gauge, error) {
 	key := labelsKeyFromLabels(labels)
 	v.mu.Lock()
 	defer v.mu.Unlock()
 	if g, ok := v.gauges[key]; ok {
-		return g
+		return g, nil
+	}
+	gauge, err := v.registry.gaugeFor(v.name, v.help, labels)
+	if err != nil {
+		return gauge, err
 	}
-	gauge := newGauge(v.name, v.help)
-	v.registry.RegisterLabeledGauge(v.name, labels, gauge)
 	v.gauges[key] = gauge
-	return gauge
+	return gauge, nil
+}
+
+func (v *gaugeVec) Prewarm(valueSets [][]string) error {
+	return prewarm(valueSets, v.GetMetricWithLabelValues)
 }
 
 // Reset drops every label-permutation child from this vec.
@@ -959,48 +2039,94 @@ func (v *gaugeVec) Reset() {
 	v.gauges = make(map[string]Gauge)
 }
 
+// DeletePartialMatch removes every child whose labels include all of match.
+func (v *gaugeVec) DeletePartialMatch(match Labels) int {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	removed := v.registry.deleteGaugeSeries(v.name, match)
+	if removed > 0 {
+		// Surviving children are re-adopted from the registry on next use.
+		v.gauges = make(map[string]Gauge)
+	}
+	return removed
+}
+
 // histogramVec is a labeled histogram collection.
 type histogramVec struct {
 	registry   *registry
 	name       string
 	help       string
 	labelNames []string
+	labelOrder []int // indices of labelNames in sorted name order
 	buckets    []float64
 	mu         sync.Mutex
 	histograms map[string]Histogram
 }
 
 func newHistogramVec(registry *registry, name, help string, labelNames []string, buckets []float64) *histogramVec {
+	mustValidLabelNames(name, labelNames)
 	return &histogramVec{
 		registry:   registry,
 		name:       name,
 		help:       help,
 		labelNames: append([]string(nil), labelNames...),
+		labelOrder: sortedLabelOrder(labelNames),
 		buckets:    append([]float64(nil), buckets...),
 		histograms: make(map[string]Histogram),
 	}
 }
 
 func (v *histogramVec) With(labels Labels) Histogram {
-	return v.getOrCreate(labels)
+	series, _ := v.getOrCreate(labels)
+	return series
 }
 
 func (v *histogramVec) WithLabelValues(values ...string) Histogram {
-	labels := labelsFromValues(v.labelNames, values)
+	// Hot path: look the child up by a key built straight from the ordered
+	// values, and only build a Labels map on first use.
+	key := labelsKeyFromValues(v.labelNames, v.labelOrder, values)
+	v.mu.Lock()
+	series, ok := v.histograms[key]
+	v.mu.Unlock()
+	if ok {
+		return series
+	}
+	series, _ = v.getOrCreate(labelsFromValues(v.labelNames, values))
+	return series
+}
+
+func (v *histogramVec) GetMetricWith(labels Labels) (Histogram, error) {
+	if err := checkLabels(v.labelNames, labels); err != nil {
+		return nil, err
+	}
+	return v.getOrCreate(labels)
+}
+
+func (v *histogramVec) GetMetricWithLabelValues(values ...string) (Histogram, error) {
+	labels, err := labelsFromValuesChecked(v.labelNames, values)
+	if err != nil {
+		return nil, err
+	}
 	return v.getOrCreate(labels)
 }
 
-func (v *histogramVec) getOrCreate(labels Labels) Histogram {
+func (v *histogramVec) getOrCreate(labels Labels) (Histogram, error) {
 	key := labelsKeyFromLabels(labels)
 	v.mu.Lock()
 	defer v.mu.Unlock()
 	if h, ok := v.histograms[key]; ok {
-		return h
+		return h, nil
+	}
+	histogram, err := v.registry.histogramFor(v.name, v.help, labels, v.buckets)
+	if err != nil {
+		return histogram, err
 	}
-	histogram := newHistogram(v.name, v.help, v.buckets)
-	v.registry.RegisterLabeledHistogram(v.name, labels, histogram)
 	v.histograms[key] = histogram
-	return histogram
+	return histogram, nil
+}
+
+func (v *histogramVec) Prewarm(valueSets [][]string) error {
+	return prewarm(valueSets, v.GetMetricWithLabelValues)
 }
 
 // Reset drops every label-permutation child from this vec.
@@ -1017,12 +2143,15 @@ type summaryVec struct {
 	name       string
 	help       string
 	labelNames []string
+	labelOrder []int // indices of labelNames in sorted name order
 	objectives map[float64]float64
 	mu         sync.Mutex
 	summaries  map[string]Summary
 }
 
 func newSummaryVec(registry *registry, name, help string, labelNames []string, objectives map[float64]float64) *summaryVec {
+	mustValidLabelNames(name, labelNames)
+	mustValidObjectives(name, objectives)
 	objCopy := make(map[float64]float64, len(objectives))
 	for k, v := range objectives {
 		objCopy[k] = v
@@ -1032,31 +2161,63 @@ func newSummaryVec(registry *registry, name, help string, labelNames []string, o
 		name:       name,
 		help:       help,
 		labelNames: append([]string(nil), labelNames...),
+		labelOrder: sortedLabelOrder(labelNames),
 		objectives: objCopy,
 		summaries:  make(map[string]Summary),
 	}
 }
 
 func (v *summaryVec) With(labels Labels) Summary {
-	return v.getOrCreate(labels)
+	series, _ := v.getOrCreate(labels)
+	return series
 }
 
 func (v *summaryVec) WithLabelValues(values ...string) Summary {
-	labels := labelsFromValues(v.labelNames, values)
+	// Hot path: look the child up by a key built straight from the ordered
+	// values, and only build a Labels map on first use.
+	key := labelsKeyFromValues(v.labelNames, v.labelOrder, values)
+	v.mu.Lock()
+	series, ok := v.summaries[key]
+	v.mu.Unlock()
+	if ok {
+		return series
+	}
+	series, _ = v.getOrCreate(labelsFromValues(v.labelNames, values))
+	return series
+}
+
+func (v *summaryVec) GetMetricWith(labels Labels) (Summary, error) {
+	if err := checkLabels(v.labelNames, labels); err != nil {
+		return nil, err
+	}
 	return v.getOrCreate(labels)
 }
 
-func (v *summaryVec) getOrCreate(labels Labels) Summary {
+func (v *summaryVec) GetMetricWithLabelValues(values ...string) (Summary, error) {
+	labels, err := labelsFromValuesChecked(v.labelNames, values)
+	if err != nil {
+		return nil, err
+	}
+	return v.getOrCreate(labels)
+}
+
+func (v *summaryVec) getOrCreate(labels Labels) (Summary, error) {
 	key := labelsKeyFromLabels(labels)
 	v.mu.Lock()
 	defer v.mu.Unlock()
 	if s, ok := v.summaries[key]; ok {
-		return s
+		return s, nil
+	}
+	summary, err := v.registry.summaryFor(v.name, v.help, labels, v.objectives)
+	if err != nil {
+		return summary, err
 	}
-	summary := newSummary(v.name, v.help, v.objectives)
-	v.registry.RegisterLabeledSummary(v.name, labels, summary)
 	v.summaries[key] = summary
-	return summary
+	return summary, nil
+}
+
+func (v *summaryVec) Prewarm(valueSets [][]string) error {
+	return prewarm(valueSets, v.GetMetricWithLabelValues)
 }
 
 // Reset drops every label-permutation child from this vec.
@@ -1077,6 +2238,40 @@ func labelsFromValues(labelNames []string, values []string) Labels {
 	return labels
 }
 
+// prewarm calls get with each set of label values, stopping at the first
+// error.
+func prewarm[T any](valueSets [][]string, get func(...string) (T, error)) error {
+	for i, values := range valueSets {
+		if _, err := get(values...); err != nil {
+			return fmt.Errorf("prewarming label values %d %q: %w", i, values, err)
+		}
+	}
+	return nil
+}
+
+// labelsFromValuesChecked is labelsFromValues for callers that want a
+// length mismatch reported rather than silently padded or truncated.
+func labelsFromValuesChecked(labelNames []string, values []string) (Labels, error) {
+	if len(values) != len(labelNames) {
+		return nil, fmt.Errorf("inconsistent label cardinality: expected %d label values but got %d", len(labelNames), len(values))
+	}
+	return labelsFromValues(labelNames, values), nil
+}
+
+// checkLabels reports an error unless labels has exactly the names in
+// labelNames.
+func checkLabels(labelNames []string, labels Labels) error {
+	if len(labels) != len(labelNames) {
+		return fmt.Errorf("inconsistent label cardinality: expected %d labels but got %d", len(labelNames), len(labels))
+	}
+	for _, name := range labelNames {
+		if _, ok := labels[name]; !ok {
+			return fmt.Errorf("label name %q missing in label map", name)
+		}
+	}
+	return nil
+}
+
 func labelsKeyFromLabels(labels Labels) string {
 	if len(labels) == 0 {
 		return ""
@@ -1101,6 +2296,51 @@ func labelsKeyFromLabels(labels Labels) string {
 	return sb.String()
 }
 
+// sortedLabelOrder returns the indices of labelNames in sorted name order.
+func sortedLabelOrder(labelNames []string) []int {
+	order := make([]int, len(labelNames))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(a, b int) bool {
+		return labelNames[order[a]] < labelNames[order[b]]
+	})
+	return order
+}
+
+// labelsKeyFromValues returns labelsKeyFromLabels(labelsFromValues(labelNames,
+// values)) without building the map. order is sortedLabelOrder(labelNames).
+func labelsKeyFromValues(labelNames []string, order []int, values []string) string {
+	size := 2
+	for i, value := range values {
+		if i < len(labelNames) {
+			size += len(labelNames[i]) + len(value) + 4
+		}
+	}
+	var sb strings.Builder
+	sb.Grow(size)
+	sb.WriteString("{")
+	n := 0
+	for _, i := range order {
+		if i >= len(values) {
+			continue
+		}
+		if n > 0 {
+			sb.WriteString(",")
+		}
+		sb.WriteString(labelNames[i])
+		sb.WriteString("=\"")
+		sb.WriteString(values[i])
+		sb.WriteString("\"")
+		n++
+	}
+	if n == 0 {
+		return ""
+	}
+	sb.WriteString("}")
+	return sb.String()
+}
+
 func cloneLabels(labels Labels) Labels {
 	if len(labels) == 0 {
 		return nil
@@ -1128,35 +2368,116 @@ func labelsToLabelPairs(labels Labels) []LabelPair {
 	return res
 }
 
-func (hpr *registry) registerName(name string, typ MetricType) error {
+func (hpr *registry) registerName(name string, typ MetricType, help string) error {
 	hpr.mu.Lock()
 	defer hpr.mu.Unlock()
 	if existing, ok := hpr.registered[name]; ok {
 		return fmt.Errorf("metric %q already registered as %s", name, existing.String())
 	}
+	if err := hpr.helpConflictLocked(name, help); err != nil {
+		return err
+	}
+	if err := hpr.claimName(name, typ, help); err != nil {
+		return err
+	}
 	hpr.registered[name] = typ
 	return nil
 }
 
-func collectorIdentity(c Collector) (string, MetricType, bool) {
+// helpConflictLocked reports an error if family name already has help text
+// other than help. Constructors aren't checked, since they have no error to
+// return; they keep the first help. Callers must hold hpr.mu.
+func (hpr *registry) helpConflictLocked(name, help string) error {
+	if existing, ok := hpr.help[name]; ok && existing != help {
+		return fmt.Errorf("metric %q already registered with help %q, not %q", name, existing, help)
+	}
+	return nil
+}
+
+// claimName records that name is a family of type typ, failing if it is
+// already known under a different type. The first claim fixes the family's
+// help, so the exposed HELP line never depends on which series sorts first.
+// Callers must hold hpr.mu.
+func (hpr *registry) claimName(name string, typ MetricType, help string) error {
+	if existing, ok := hpr.types[name]; ok && existing != typ {
+		return fmt.Errorf("metric %q already registered as %s, not %s", name, existing.String(), typ.String())
+	}
+	hpr.types[name] = typ
+	if _, ok := hpr.help[name]; !ok {
+		hpr.help[name] = help
+	}
+	return nil
+}
+
+// mustClaimName is claimName for constructors, which have no error return.
+// Callers must hold hpr.mu.
+func (hpr *registry) mustClaimName(name string, typ MetricType, help string) {
+	if err := hpr.claimName(name, typ, help); err != nil {
+		panic(err)
+	}
+}
+
+// claim is mustClaimName for callers that do not hold hpr.mu.
+func (hpr *registry) claim(name string, typ MetricType, help string) {
+	hpr.mu.Lock()
+	defer hpr.mu.Unlock()
+	hpr.mustClaimName(name, typ, help)
+}
+
+// claimVec is claim for vecs, also recording their schema.
+func (hpr *registry) claimVec(name string, typ MetricType, schema vecSchema) {
+	hpr.mu.Lock()
+	defer hpr.mu.Unlock()
+	hpr.mustClaimName(name, typ, schema.help)
+	hpr.recordVecLocked(name, schema)
+}
+
+// recordVec records the schema of vec family name.
+func (hpr *registry) recordVec(name string, schema vecSchema) {
+	hpr.mu.Lock()
+	defer hpr.mu.Unlock()
+	hpr.recordVecLocked(name, schema)
+}
+
+// recordVecLocked is recordVec for callers holding hpr.mu.
+func (hpr *registry) recordVecLocked(name string, schema vecSchema) {
+	schema.labelNames = append([]string(nil), schema.labelNames...)
+	schema.buckets = append([]float64(nil), schema.buckets...)
+	if schema.objectives != nil {
+		objectives := make(map[float64]float64, len(schema.objectives))
+		for q, e := range schema.objectives {
+			objectives[q] = e
+		}
+		schema.objectives = objectives
+	}
+	hpr.vecs[name] = schema
+}
+
+func collectorIdentity(c Collector) (string, MetricType, string, bool) {
 	switch v := c.(type) {
 	case *metricCounter:
-		return v.name, MetricTypeCounter, true
+		return v.name, MetricTypeCounter, v.help, true
 	case *metricGauge:
-		return v.name, MetricTypeGauge, true
+		return v.name, MetricTypeGauge, v.help, true
 	case *metricHistogram:
-		return v.name, MetricTypeHistogram, true
+		return v.name, MetricTypeHistogram, v.help, true
 	case *metricSummary:
-		return v.name, MetricTypeSummary, true
+		return v.name, MetricTypeSummary, v.help, true
+	case *metricUntyped:
+		return v.name, MetricTypeUntyped, v.help, true
+	case *NativeHistogram:
+		return v.name, MetricTypeHistogram, v.help, true
 	case *counterVec:
-		return v.name, MetricTypeCounter, true
+		return v.name, MetricTypeCounter, v.help, true
 	case *gaugeVec:
-		return v.name, MetricTypeGauge, true
+		return v.name, MetricTypeGauge, v.help, true
 	case *histogramVec:
-		return v.name, MetricTypeHistogram, true
+		return v.name, MetricTypeHistogram, v.help, true
 	case *summaryVec:
-		return v.name, MetricTypeSummary, true
+		return v.name, MetricTypeSummary, v.help, true
+	case *buildInfoCollector:
+		return v.name, MetricTypeGauge, buildInfoHelp, true
 	default:
-		return "", MetricTypeUntyped, false
+		return "", MetricTypeUntyped, "", false
 	}
 }
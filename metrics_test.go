@@ -125,3 +125,22 @@ func TestGlobalFactory(t *testing.T) {
 	// Reset to noop for other tests
 	SetFactory(NewNoOpFactory())
 }
+
+func TestNewFactoryForBackend(t *testing.T) {
+	for _, kind := range []BackendKind{BackendNative, BackendNoop} {
+		t.Run(kind.String(), func(t *testing.T) {
+			counter := NewFactoryForBackend(kind).New("test").NewCounter("backend_total", "backend")
+			counter.Add(2)
+			if counter.Get() != 2 {
+				t.Errorf("expected counter value 2, got %f", counter.Get())
+			}
+		})
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Error("expected panic for an unknown backend")
+		}
+	}()
+	NewFactoryForBackend(BackendKind(99))
+}
@@ -0,0 +1,123 @@
+// Copyright (C) 2020-2026, Lux Industries Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+// Package metrictest provides test assertions for metrics exposed through a
+// metric.Gatherer.
+package metrictest
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/luxfi/metric"
+)
+
+// AssertCounterValue fails t unless g exposes a counter family name with a
+// series whose labels are exactly labels and whose value is want. It reports
+// whether the assertion passed.
+func AssertCounterValue(t testing.TB, g metric.Gatherer, name string, labels metric.Labels, want float64) bool {
+	t.Helper()
+	m, ok := find(t, g, name, metric.MetricTypeCounter, labels)
+	if !ok {
+		return false
+	}
+	if got := m.Value.Value; got != want {
+		t.Errorf("counter %s%s = %v, want %v", name, formatLabels(labels), got, want)
+		return false
+	}
+	return true
+}
+
+// AssertGaugeValue is AssertCounterValue for gauges.
+func AssertGaugeValue(t testing.TB, g metric.Gatherer, name string, labels metric.Labels, want float64) bool {
+	t.Helper()
+	m, ok := find(t, g, name, metric.MetricTypeGauge, labels)
+	if !ok {
+		return false
+	}
+	if got := m.Value.Value; got != want {
+		t.Errorf("gauge %s%s = %v, want %v", name, formatLabels(labels), got, want)
+		return false
+	}
+	return true
+}
+
+// AssertHistogramCount fails t unless g exposes a histogram family name with
+// a series whose labels are exactly labels and whose sample count is want.
+func AssertHistogramCount(t testing.TB, g metric.Gatherer, name string, labels metric.Labels, want uint64) bool {
+	t.Helper()
+	m, ok := find(t, g, name, metric.MetricTypeHistogram, labels)
+	if !ok {
+		return false
+	}
+	if got := m.Value.SampleCount; got != want {
+		t.Errorf("histogram %s%s count = %d, want %d", name, formatLabels(labels), got, want)
+		return false
+	}
+	return true
+}
+
+// find gathers g and returns the series of family name with exactly labels,
+// reporting a test error if it is missing or of the wrong type.
+func find(t testing.TB, g metric.Gatherer, name string, typ metric.MetricType, labels metric.Labels) (metric.Metric, bool) {
+	t.Helper()
+	families, err := g.Gather()
+	if err != nil {
+		t.Errorf("gather failed: %v", err)
+		return metric.Metric{}, false
+	}
+	for _, mf := range families {
+		if mf == nil || mf.Name != name {
+			continue
+		}
+		if mf.Type != typ {
+			t.Errorf("%s is a %s, want %s", name, mf.Type, typ)
+			return metric.Metric{}, false
+		}
+		for _, m := range mf.Metrics {
+			if labelsEqual(m.Labels, labels) {
+				return m, true
+			}
+		}
+		t.Errorf("%s has no series with labels %s", name, formatLabels(labels))
+		return metric.Metric{}, false
+	}
+	t.Errorf("missing metric family %q", name)
+	return metric.Metric{}, false
+}
+
+func labelsEqual(pairs []metric.LabelPair, want metric.Labels) bool {
+	if len(pairs) != len(want) {
+		return false
+	}
+	for _, p := range pairs {
+		if v, ok := want[p.Name]; !ok || v != p.Value {
+			return false
+		}
+	}
+	return true
+}
+
+// formatLabels renders labels in exposition format, sorted by name.
+func formatLabels(labels metric.Labels) string {
+	if len(labels) == 0 {
+		return "{}"
+	}
+	pairs := make([]metric.LabelPair, 0, len(labels))
+	for name, value := range labels {
+		pairs = append(pairs, metric.LabelPair{Name: name, Value: value})
+	}
+	sort.Slice(pairs, func(i, j int) bool { return pairs[i].Name < pairs[j].Name })
+	var sb strings.Builder
+	sb.WriteByte('{')
+	for i, p := range pairs {
+		if i > 0 {
+			sb.WriteByte(',')
+		}
+		fmt.Fprintf(&sb, "%s=%q", p.Name, p.Value)
+	}
+	sb.WriteByte('}')
+	return sb.String()
+}
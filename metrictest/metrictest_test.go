@@ -0,0 +1,110 @@
+// Copyright (C) 2020-2026, Lux Industries Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package metrictest
+
+import (
+	"fmt"
+	"math"
+	"testing"
+
+	"github.com/luxfi/metric"
+)
+
+// fakeTB records errors instead of failing the enclosing test.
+type fakeTB struct {
+	testing.TB
+	errors []string
+}
+
+func (f *fakeTB) Helper() {}
+
+func (f *fakeTB) Errorf(format string, args ...any) {
+	f.errors = append(f.errors, fmt.Sprintf(format, args...))
+}
+
+type staticGatherer []*metric.MetricFamily
+
+func (g staticGatherer) Gather() ([]*metric.MetricFamily, error) {
+	return g, nil
+}
+
+var families = staticGatherer{
+	{
+		Name: "requests_total",
+		Type: metric.MetricTypeCounter,
+		Metrics: []metric.Metric{
+			{Labels: []metric.LabelPair{{Name: "method", Value: "GET"}}, Value: metric.MetricValue{Value: 3}},
+		},
+	},
+	{
+		Name:    "in_flight",
+		Type:    metric.MetricTypeGauge,
+		Metrics: []metric.Metric{{Value: metric.MetricValue{Value: 2}}},
+	},
+	{
+		Name: "latency_seconds",
+		Type: metric.MetricTypeHistogram,
+		Metrics: []metric.Metric{{Value: metric.MetricValue{
+			SampleCount: 4,
+			Buckets:     []metric.Bucket{{UpperBound: math.Inf(1), CumulativeCount: 4}},
+		}}},
+	},
+}
+
+func TestAssertionsPass(t *testing.T) {
+	tb := &fakeTB{}
+	ok := AssertCounterValue(tb, families, "requests_total", metric.Labels{"method": "GET"}, 3) &&
+		AssertGaugeValue(tb, families, "in_flight", nil, 2) &&
+		AssertHistogramCount(tb, families, "latency_seconds", nil, 4)
+	if !ok || len(tb.errors) != 0 {
+		t.Fatalf("assertions failed: %v", tb.errors)
+	}
+}
+
+func TestAssertionsFail(t *testing.T) {
+	tests := []struct {
+		name   string
+		assert func(testing.TB) bool
+		want   string
+	}{
+		{
+			name: "wrong value",
+			assert: func(tb testing.TB) bool {
+				return AssertCounterValue(tb, families, "requests_total", metric.Labels{"method": "GET"}, 4)
+			},
+			want: `counter requests_total{method="GET"} = 3, want 4`,
+		},
+		{
+			name:   "missing labels",
+			assert: func(tb testing.TB) bool { return AssertCounterValue(tb, families, "requests_total", nil, 3) },
+			want:   "requests_total has no series with labels {}",
+		},
+		{
+			name:   "missing family",
+			assert: func(tb testing.TB) bool { return AssertGaugeValue(tb, families, "absent", nil, 0) },
+			want:   `missing metric family "absent"`,
+		},
+		{
+			name:   "wrong type",
+			assert: func(tb testing.TB) bool { return AssertGaugeValue(tb, families, "requests_total", nil, 3) },
+			want:   "requests_total is a counter, want gauge",
+		},
+		{
+			name:   "wrong count",
+			assert: func(tb testing.TB) bool { return AssertHistogramCount(tb, families, "latency_seconds", nil, 5) },
+			want:   "histogram latency_seconds{} count = 4, want 5",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tb := &fakeTB{}
+			if tt.assert(tb) {
+				t.Fatal("assertion passed, want failure")
+			}
+			if len(tb.errors) != 1 || tb.errors[0] != tt.want {
+				t.Fatalf("errors = %q, want [%q]", tb.errors, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,201 @@
+// Copyright (C) 2020-2026, Lux Industries Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package metric
+
+import (
+	"math"
+	"sort"
+	"sync"
+)
+
+const (
+	// NativeHistogramMinSchema and NativeHistogramMaxSchema bound the
+	// resolution of a NativeHistogram. Each step up halves the bucket width
+	// on a log scale: schema 0 uses powers of 2, schema 8 uses 2^(2^-8).
+	NativeHistogramMinSchema = -4
+	NativeHistogramMaxSchema = 8
+
+	// DefNativeHistogramZeroThreshold is the zero bucket width used when none
+	// is given.
+	DefNativeHistogramZeroThreshold = 2.938735877055719e-39 // 2^-128
+)
+
+// NativeHistogram is a sparse histogram with exponential buckets, in the
+// style of Prometheus native histograms. Only buckets that have received
+// observations are stored, so high resolution costs memory proportional to
+// the spread of the data rather than to the bucket layout.
+//
+// Bucket i covers (base^(i-1), base^i] with base = 2^(2^-schema); negative
+// observations use the mirrored buckets and values within the zero threshold
+// land in a dedicated zero bucket.
+type NativeHistogram struct {
+	name          string
+	help          string
+	schema        int32
+	zeroThreshold float64
+
+	mu        sync.Mutex
+	count     uint64
+	sum       float64
+	zeroCount uint64
+	nanCount  uint64
+	positive  map[int]uint64
+	negative  map[int]uint64
+}
+
+// NewNativeHistogram creates a native histogram and registers it, like
+// NewHistogram, on DefaultRegistry. Creating the same name twice returns the
+// existing histogram. Use Registry.Register to expose one on another
+// registry. schema is clamped to [NativeHistogramMinSchema,
+// NativeHistogramMaxSchema]; a non-positive zeroThreshold selects
+// DefNativeHistogramZeroThreshold.
+func NewNativeHistogram(name, help string, schema int32, zeroThreshold float64) *NativeHistogram {
+	schema = min(max(schema, NativeHistogramMinSchema), NativeHistogramMaxSchema)
+	if zeroThreshold <= 0 {
+		zeroThreshold = DefNativeHistogramZeroThreshold
+	}
+	return newDerived(name, func() *NativeHistogram {
+		return &NativeHistogram{
+			name:          name,
+			help:          help,
+			schema:        schema,
+			zeroThreshold: zeroThreshold,
+			positive:      make(map[int]uint64),
+			negative:      make(map[int]uint64),
+		}
+	})
+}
+
+func (nh *NativeHistogram) registerLocked(hpr *registry) {
+	hpr.mustClaimName(nh.name, MetricTypeHistogram, nh.help)
+	hpr.natives[nh.name] = nh
+}
+
+// Observe records a value. Like metricHistogram, NaN is dropped and counted
+// separately, and ±Inf lands in the outermost bucket without being added to
+// the sum.
+func (nh *NativeHistogram) Observe(val float64) {
+	nh.mu.Lock()
+	defer nh.mu.Unlock()
+
+	if math.IsNaN(val) {
+		nh.nanCount++
+		return
+	}
+	nh.count++
+	if !math.IsInf(val, 0) {
+		nh.sum += val
+	}
+
+	abs := math.Abs(val)
+	switch {
+	case abs <= nh.zeroThreshold:
+		nh.zeroCount++
+	case val > 0:
+		nh.positive[nativeBucketIndex(abs, nh.schema)]++
+	default:
+		nh.negative[nativeBucketIndex(abs, nh.schema)]++
+	}
+}
+
+// GetCount returns the total count.
+func (nh *NativeHistogram) GetCount() uint64 {
+	nh.mu.Lock()
+	defer nh.mu.Unlock()
+	return nh.count
+}
+
+// GetSum returns the sum.
+func (nh *NativeHistogram) GetSum() float64 {
+	nh.mu.Lock()
+	defer nh.mu.Unlock()
+	return nh.sum
+}
+
+// GetNaNCount returns the number of NaN observations that were dropped.
+func (nh *NativeHistogram) GetNaNCount() uint64 {
+	nh.mu.Lock()
+	defer nh.mu.Unlock()
+	return nh.nanCount
+}
+
+// ToMetric returns a Metric representation with the native histogram fields
+// populated: populated buckets are encoded as spans plus count deltas.
+func (nh *NativeHistogram) ToMetric(labels []LabelPair) Metric {
+	nh.mu.Lock()
+	defer nh.mu.Unlock()
+
+	positiveSpans, positiveDeltas := encodeNativeBuckets(nh.positive)
+	negativeSpans, negativeDeltas := encodeNativeBuckets(nh.negative)
+	return Metric{
+		Labels: labels,
+		Value: MetricValue{
+			SampleCount:    nh.count,
+			SampleSum:      nh.sum,
+			Schema:         nh.schema,
+			ZeroThreshold:  nh.zeroThreshold,
+			ZeroCount:      nh.zeroCount,
+			PositiveSpans:  positiveSpans,
+			PositiveDeltas: positiveDeltas,
+			NegativeSpans:  negativeSpans,
+			NegativeDeltas: negativeDeltas,
+		},
+	}
+}
+
+// nativeBucketIndex returns the index of the bucket holding v (v > 0).
+// Powers of two are handled exactly via Frexp so bucket boundaries don't
+// drift with floating-point error in Log2.
+func nativeBucketIndex(v float64, schema int32) int {
+	if math.IsInf(v, 0) {
+		v = math.MaxFloat64
+	}
+	frac, exp := math.Frexp(v) // v = frac * 2^exp, frac in [0.5, 1)
+	if schema > 0 {
+		if frac == 0.5 {
+			return (exp - 1) << schema
+		}
+		return int(math.Ceil(math.Log2(v) * float64(int(1)<<schema)))
+	}
+	key := exp
+	if frac == 0.5 {
+		key--
+	}
+	// Arithmetic shift rounds towards -Inf, so the offset makes this ceil.
+	offset := (1 << -schema) - 1
+	return (key + offset) >> -schema
+}
+
+// encodeNativeBuckets encodes the populated buckets as spans of consecutive
+// indices and the deltas between successive bucket counts.
+func encodeNativeBuckets(buckets map[int]uint64) ([]BucketSpan, []int64) {
+	if len(buckets) == 0 {
+		return nil, nil
+	}
+	indices := make([]int, 0, len(buckets))
+	for i := range buckets {
+		indices = append(indices, i)
+	}
+	sort.Ints(indices)
+
+	var (
+		spans  []BucketSpan
+		deltas = make([]int64, 0, len(indices))
+		prev   int64
+	)
+	for n, i := range indices {
+		switch {
+		case n == 0:
+			spans = append(spans, BucketSpan{Offset: int32(i), Length: 1})
+		case i == indices[n-1]+1:
+			spans[len(spans)-1].Length++
+		default:
+			spans = append(spans, BucketSpan{Offset: int32(i - indices[n-1] - 1), Length: 1})
+		}
+		count := int64(buckets[i])
+		deltas = append(deltas, count-prev)
+		prev = count
+	}
+	return spans, deltas
+}
@@ -0,0 +1,64 @@
+// Copyright (C) 2020-2026, Lux Industries Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package metric
+
+import (
+	"math"
+	"reflect"
+	"testing"
+)
+
+func TestNativeHistogramSpans(t *testing.T) {
+	nh := NewNativeHistogram("native_spans_seconds", "latency", 0, 0)
+	// Schema 0 buckets are (2^(i-1), 2^i]: 1→0, 2→1, 3→2, 8→3, 100→7.
+	for _, v := range []float64{1, 2, 3, 3, 8, 100, 0, -1, math.NaN()} {
+		nh.Observe(v)
+	}
+
+	m := nh.ToMetric(nil).Value
+	if m.SampleCount != 8 || m.SampleSum != 116 {
+		t.Fatalf("count/sum: got %d/%v, want 8/116", m.SampleCount, m.SampleSum)
+	}
+	if m.ZeroCount != 1 {
+		t.Fatalf("zero count: got %d, want 1", m.ZeroCount)
+	}
+	if want := []BucketSpan{{Offset: 0, Length: 4}, {Offset: 3, Length: 1}}; !reflect.DeepEqual(m.PositiveSpans, want) {
+		t.Fatalf("positive spans: got %+v, want %+v", m.PositiveSpans, want)
+	}
+	if want := []int64{1, 0, 1, -1, 0}; !reflect.DeepEqual(m.PositiveDeltas, want) {
+		t.Fatalf("positive deltas: got %v, want %v", m.PositiveDeltas, want)
+	}
+	if want := []BucketSpan{{Offset: 0, Length: 1}}; !reflect.DeepEqual(m.NegativeSpans, want) {
+		t.Fatalf("negative spans: got %+v, want %+v", m.NegativeSpans, want)
+	}
+	if want := []int64{1}; !reflect.DeepEqual(m.NegativeDeltas, want) {
+		t.Fatalf("negative deltas: got %v, want %v", m.NegativeDeltas, want)
+	}
+	if nh.GetNaNCount() != 1 {
+		t.Fatalf("nan count: got %d, want 1", nh.GetNaNCount())
+	}
+}
+
+func TestNativeBucketIndex(t *testing.T) {
+	tests := []struct {
+		v      float64
+		schema int32
+		want   int
+	}{
+		{1, 0, 0},
+		{1.5, 0, 1},
+		{4, 0, 2},
+		{1.5, 1, 2}, // (√2, 2]
+		{1.2, 1, 1}, // (1, √2]
+		{4, 3, 16},
+		{4, -1, 1}, // (1, 4]
+		{5, -1, 2}, // (4, 16]
+		{0.25, -1, -1},
+	}
+	for _, tt := range tests {
+		if got := nativeBucketIndex(tt.v, tt.schema); got != tt.want {
+			t.Errorf("nativeBucketIndex(%v, %d) = %d, want %d", tt.v, tt.schema, got, tt.want)
+		}
+	}
+}
@@ -5,6 +5,7 @@ package metric
 
 import (
 	"math"
+	"net/http"
 	"sync/atomic"
 )
 
@@ -68,34 +69,54 @@ func (n *noopSummary) Observe(float64) {}
 // noopCounterVec is a counter vector that does nothing.
 type noopCounterVec struct{}
 
-func (n *noopCounterVec) With(Labels) Counter               { return &noopCounter{} }
-func (n *noopCounterVec) WithLabelValues(...string) Counter { return &noopCounter{} }
-func (n *noopCounterVec) MustCurryWith(Labels) CounterVec   { return n }
-func (n *noopCounterVec) Reset()                            {}
+func (n *noopCounterVec) With(Labels) Counter                   { return &noopCounter{} }
+func (n *noopCounterVec) WithLabelValues(...string) Counter     { return &noopCounter{} }
+func (n *noopCounterVec) MustCurryWith(Labels) CounterVec       { return n }
+func (n *noopCounterVec) GetMetricWith(Labels) (Counter, error) { return &noopCounter{}, nil }
+func (n *noopCounterVec) GetMetricWithLabelValues(...string) (Counter, error) {
+	return &noopCounter{}, nil
+}
+func (n *noopCounterVec) DeletePartialMatch(Labels) int { return 0 }
+func (n *noopCounterVec) Reset()                        {}
+func (n *noopCounterVec) Prewarm([][]string) error      { return nil }
 
 // noopGaugeVec is a gauge vector that does nothing.
 type noopGaugeVec struct{}
 
-func (n *noopGaugeVec) With(Labels) Gauge               { return &noopGauge{} }
-func (n *noopGaugeVec) WithLabelValues(...string) Gauge { return &noopGauge{} }
-func (n *noopGaugeVec) MustCurryWith(Labels) GaugeVec   { return n }
-func (n *noopGaugeVec) Reset()                          {}
+func (n *noopGaugeVec) With(Labels) Gauge                                 { return &noopGauge{} }
+func (n *noopGaugeVec) WithLabelValues(...string) Gauge                   { return &noopGauge{} }
+func (n *noopGaugeVec) MustCurryWith(Labels) GaugeVec                     { return n }
+func (n *noopGaugeVec) GetMetricWith(Labels) (Gauge, error)               { return &noopGauge{}, nil }
+func (n *noopGaugeVec) GetMetricWithLabelValues(...string) (Gauge, error) { return &noopGauge{}, nil }
+func (n *noopGaugeVec) DeletePartialMatch(Labels) int                     { return 0 }
+func (n *noopGaugeVec) Reset()                                            {}
+func (n *noopGaugeVec) Prewarm([][]string) error                          { return nil }
 
 // noopHistogramVec is a histogram vector that does nothing.
 type noopHistogramVec struct{}
 
-func (n *noopHistogramVec) With(Labels) Histogram               { return &noopHistogram{} }
-func (n *noopHistogramVec) WithLabelValues(...string) Histogram { return &noopHistogram{} }
-func (n *noopHistogramVec) MustCurryWith(Labels) HistogramVec   { return n }
-func (n *noopHistogramVec) Reset()                              {}
+func (n *noopHistogramVec) With(Labels) Histogram                   { return &noopHistogram{} }
+func (n *noopHistogramVec) WithLabelValues(...string) Histogram     { return &noopHistogram{} }
+func (n *noopHistogramVec) MustCurryWith(Labels) HistogramVec       { return n }
+func (n *noopHistogramVec) GetMetricWith(Labels) (Histogram, error) { return &noopHistogram{}, nil }
+func (n *noopHistogramVec) GetMetricWithLabelValues(...string) (Histogram, error) {
+	return &noopHistogram{}, nil
+}
+func (n *noopHistogramVec) Reset()                   {}
+func (n *noopHistogramVec) Prewarm([][]string) error { return nil }
 
 // noopSummaryVec is a summary vector that does nothing.
 type noopSummaryVec struct{}
 
-func (n *noopSummaryVec) With(Labels) Summary               { return &noopSummary{} }
-func (n *noopSummaryVec) WithLabelValues(...string) Summary { return &noopSummary{} }
-func (n *noopSummaryVec) MustCurryWith(Labels) SummaryVec   { return n }
-func (n *noopSummaryVec) Reset()                            {}
+func (n *noopSummaryVec) With(Labels) Summary                   { return &noopSummary{} }
+func (n *noopSummaryVec) WithLabelValues(...string) Summary     { return &noopSummary{} }
+func (n *noopSummaryVec) MustCurryWith(Labels) SummaryVec       { return n }
+func (n *noopSummaryVec) GetMetricWith(Labels) (Summary, error) { return &noopSummary{}, nil }
+func (n *noopSummaryVec) GetMetricWithLabelValues(...string) (Summary, error) {
+	return &noopSummary{}, nil
+}
+func (n *noopSummaryVec) Reset()                   {}
+func (n *noopSummaryVec) Prewarm([][]string) error { return nil }
 
 // noopRegistry provides a registry that gathers nothing.
 type noopRegistry struct{}
@@ -105,9 +126,58 @@ func newNoopRegistry() Registry { return &noopRegistry{} }
 func (r *noopRegistry) Register(_ Collector) error  { return nil }
 func (r *noopRegistry) MustRegister(_ ...Collector) {}
 func (r *noopRegistry) Unregister(_ Collector) bool { return false }
+func (r *noopRegistry) UnregisterByName(string) int { return 0 }
 func (r *noopRegistry) Gather() ([]*MetricFamily, error) {
 	return nil, nil
 }
+func (r *noopRegistry) GatherFunc(func(*MetricFamily) error) error { return nil }
+func (r *noopRegistry) Snapshot() ([]byte, error)                  { return nil, nil }
+func (r *noopRegistry) Restore([]byte) error                       { return nil }
+func (r *noopRegistry) Describe() []MetricDescriptor               { return nil }
+func (r *noopRegistry) Reset()                                     {}
+func (r *noopRegistry) Stats() RegistryStats                       { return RegistryStats{} }
+func (r *noopRegistry) Alias(string, string) error                 { return nil }
+func (r *noopRegistry) RemoveAlias(string) bool                    { return false }
+func (r *noopRegistry) NewConstHistogram(string, string, []Bucket, uint64, float64, Labels) error {
+	return nil
+}
+
+// RegisterBatch creates the no-op metric for each spec.
+func (r *noopRegistry) RegisterBatch(specs []MetricSpec) ([]interface{}, error) {
+	handles := make([]interface{}, len(specs))
+	for i, spec := range specs {
+		vec := len(spec.LabelNames) > 0
+		switch spec.Kind {
+		case MetricTypeCounter:
+			if vec {
+				handles[i] = r.NewCounterVec(spec.Name, spec.Help, spec.LabelNames)
+			} else {
+				handles[i] = r.NewCounter(spec.Name, spec.Help)
+			}
+		case MetricTypeGauge:
+			if vec {
+				handles[i] = r.NewGaugeVec(spec.Name, spec.Help, spec.LabelNames)
+			} else {
+				handles[i] = r.NewGauge(spec.Name, spec.Help)
+			}
+		case MetricTypeHistogram:
+			if vec {
+				handles[i] = r.NewHistogramVec(spec.Name, spec.Help, spec.LabelNames, spec.Buckets)
+			} else {
+				handles[i] = r.NewHistogram(spec.Name, spec.Help, spec.Buckets)
+			}
+		case MetricTypeSummary:
+			if vec {
+				handles[i] = r.NewSummaryVec(spec.Name, spec.Help, spec.LabelNames, spec.Objectives)
+			} else {
+				handles[i] = r.NewSummary(spec.Name, spec.Help, spec.Objectives)
+			}
+		default:
+			handles[i] = r.NewUntyped(spec.Name, spec.Help)
+		}
+	}
+	return handles, nil
+}
 
 func (r *noopRegistry) NewCounter(name, help string) Counter {
 	return &noopCounter{}
@@ -141,6 +211,10 @@ func (r *noopRegistry) NewSummaryVec(name, help string, labelNames []string, obj
 	return &noopSummaryVec{}
 }
 
+func (r *noopRegistry) NewUntyped(name, help string) Gauge {
+	return &noopGauge{}
+}
+
 func (r *noopRegistry) Registry() Registry {
 	return r
 }
@@ -201,6 +275,19 @@ func NewNoOp() Metrics {
 	return NewNoOpMetrics("")
 }
 
+// NewNoOpGatherer returns a gatherer that always gathers no families.
+func NewNoOpGatherer() Gatherer {
+	return newNoopRegistry()
+}
+
+// NoOpHandler returns a handler that answers every request with an empty
+// 200, for turning the metrics endpoint off while keeping it mounted.
+func NoOpHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+}
+
 // noopFactory produces no-op metrics.
 type noopFactory struct{}
 
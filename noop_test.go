@@ -4,6 +4,8 @@
 package metric
 
 import (
+	"net/http"
+	"net/http/httptest"
 	"sync"
 	"testing"
 )
@@ -56,3 +58,21 @@ func TestNoopGaugeConcurrent(t *testing.T) {
 		t.Fatalf("noopGauge lost updates under contention: got %v want 0", got)
 	}
 }
+
+func TestNoOpGatherer(t *testing.T) {
+	families, err := NewNoOpGatherer().Gather()
+	if err != nil || len(families) != 0 {
+		t.Fatalf("Gather() = %v, %v; want no families and no error", families, err)
+	}
+}
+
+func TestNoOpHandler(t *testing.T) {
+	rec := httptest.NewRecorder()
+	NoOpHandler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200", rec.Code)
+	}
+	if rec.Body.Len() != 0 {
+		t.Fatalf("body = %q, want empty", rec.Body.String())
+	}
+}
@@ -0,0 +1,109 @@
+// Copyright (C) 2020-2026, Lux Industries Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package metric
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// OpenMetricsContentType is the content type of EncodeOpenMetrics output.
+const OpenMetricsContentType = "application/openmetrics-text; version=1.0.0; charset=utf-8"
+
+// EncodeOpenMetrics encodes families in the OpenMetrics text format,
+// including # UNIT lines and the trailing # EOF. Counter families are
+// announced without their _total suffix, as OpenMetrics requires. A family
+// whose name does not end in its unit is an error.
+func EncodeOpenMetrics(out io.Writer, families []*MetricFamily) error {
+	return encodeOpenMetrics(out, families, func() {})
+}
+
+// encodeOpenMetrics is EncodeOpenMetrics, calling afterFamily once each
+// family has been written.
+func encodeOpenMetrics(out io.Writer, families []*MetricFamily, afterFamily func()) error {
+	w := &errWriter{w: out}
+	for _, mf := range families {
+		if mf == nil {
+			continue
+		}
+		if err := mf.validateUnit(); err != nil {
+			return err
+		}
+		writeOpenMetricsFamily(w, mf)
+		if w.err != nil {
+			return w.err
+		}
+		afterFamily()
+	}
+	fmt.Fprint(w, "# EOF\n")
+	return w.err
+}
+
+func writeOpenMetricsFamily(w io.Writer, mf *MetricFamily) {
+	name := mf.Name
+	typ := mf.Type.String()
+	switch mf.Type {
+	case MetricTypeCounter:
+		name = strings.TrimSuffix(name, "_total")
+	case MetricTypeUntyped:
+		typ = "unknown"
+	}
+
+	fmt.Fprintf(w, "# TYPE %s %s\n", name, typ)
+	if mf.Unit != "" {
+		fmt.Fprintf(w, "# UNIT %s %s\n", name, mf.Unit)
+	}
+	if mf.Help != "" {
+		fmt.Fprintf(w, "# HELP %s %s\n", name, escapeOpenMetricsHelp(mf.Help))
+	}
+
+	for _, m := range mf.Metrics {
+		ts := openMetricsTimestamp(m.TimestampMs)
+		switch mf.Type {
+		case MetricTypeCounter:
+			writeOpenMetricsLine(w, name+"_total", m.Labels, formatFloat(m.Value.Value), ts)
+		case MetricTypeHistogram:
+			buckets := make([]Bucket, len(m.Value.Buckets))
+			copy(buckets, m.Value.Buckets)
+			sort.Slice(buckets, func(i, j int) bool { return buckets[i].UpperBound < buckets[j].UpperBound })
+			for _, b := range buckets {
+				labels := append(append([]LabelPair(nil), m.Labels...), LabelPair{Name: "le", Value: formatFloat(b.UpperBound)})
+				writeOpenMetricsLine(w, name+"_bucket", labels, strconv.FormatUint(b.CumulativeCount, 10), ts)
+			}
+			writeOpenMetricsLine(w, name+"_count", m.Labels, strconv.FormatUint(m.Value.SampleCount, 10), ts)
+			writeOpenMetricsLine(w, name+"_sum", m.Labels, formatFloat(m.Value.SampleSum), ts)
+		case MetricTypeSummary:
+			for _, q := range m.Value.Quantiles {
+				labels := append(append([]LabelPair(nil), m.Labels...), LabelPair{Name: "quantile", Value: formatFloat(q.Quantile)})
+				writeOpenMetricsLine(w, name, labels, formatFloat(q.Value), ts)
+			}
+			writeOpenMetricsLine(w, name+"_count", m.Labels, strconv.FormatUint(m.Value.SampleCount, 10), ts)
+			writeOpenMetricsLine(w, name+"_sum", m.Labels, formatFloat(m.Value.SampleSum), ts)
+		default:
+			writeOpenMetricsLine(w, name, m.Labels, formatFloat(m.Value.Value), ts)
+		}
+	}
+}
+
+func writeOpenMetricsLine(w io.Writer, name string, labels []LabelPair, value, ts string) {
+	fmt.Fprintf(w, "%s%s %s%s\n", name, formatLabelsWithBraces(labels), value, ts)
+}
+
+// openMetricsTimestamp formats an optional millisecond timestamp in the
+// seconds OpenMetrics uses; zero means unset.
+func openMetricsTimestamp(ms int64) string {
+	if ms == 0 {
+		return ""
+	}
+	return " " + strconv.FormatFloat(float64(ms)/1000, 'f', -1, 64)
+}
+
+// escapeOpenMetricsHelp escapes HELP text; unlike the Prometheus text
+// format, OpenMetrics also escapes double quotes.
+func escapeOpenMetricsHelp(s string) string {
+	return strings.ReplaceAll(escapeHelp(s), `"`, `\"`)
+}
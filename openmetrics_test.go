@@ -0,0 +1,62 @@
+// Copyright (C) 2020-2026, Lux Industries Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package metric
+
+import (
+	"bytes"
+	"math"
+	"testing"
+)
+
+func TestEncodeOpenMetrics(t *testing.T) {
+	families := []*MetricFamily{
+		{
+			Name:    "requests_total",
+			Help:    "requests",
+			Type:    MetricTypeCounter,
+			Metrics: []Metric{{Labels: []LabelPair{{Name: "code", Value: "200"}}, Value: MetricValue{Value: 3}}},
+		},
+		{
+			Name: "latency_seconds",
+			Type: MetricTypeHistogram,
+			Unit: "seconds",
+			Metrics: []Metric{{
+				Value: MetricValue{
+					SampleCount: 2,
+					SampleSum:   0.75,
+					Buckets:     []Bucket{{UpperBound: 0.5, CumulativeCount: 1}, {UpperBound: math.Inf(1), CumulativeCount: 2}},
+				},
+				TimestampMs: 1500,
+			}},
+		},
+	}
+	var buf bytes.Buffer
+	if err := EncodeOpenMetrics(&buf, families); err != nil {
+		t.Fatalf("EncodeOpenMetrics: %v", err)
+	}
+	want := `# TYPE requests counter
+# HELP requests requests
+requests_total{code="200"} 3
+# TYPE latency_seconds histogram
+# UNIT latency_seconds seconds
+latency_seconds_bucket{le="0.5"} 1 1.5
+latency_seconds_bucket{le="+Inf"} 2 1.5
+latency_seconds_count 2 1.5
+latency_seconds_sum 0.75 1.5
+# EOF
+`
+	if got := buf.String(); got != want {
+		t.Fatalf("output:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestEncodeOpenMetricsUnitMismatch(t *testing.T) {
+	families := []*MetricFamily{{Name: "latency_ms", Type: MetricTypeGauge, Unit: "seconds"}}
+	if err := EncodeOpenMetrics(&bytes.Buffer{}, families); err == nil {
+		t.Fatal("expected an error for a name not ending in its unit")
+	}
+	if err := families[0].Validate(); err == nil {
+		t.Fatal("Validate accepted a name not ending in its unit")
+	}
+}
@@ -6,7 +6,9 @@ package metric
 import (
 	"bytes"
 	"context"
+	"errors"
 	"fmt"
+	"math/rand/v2"
 	"net/http"
 	"net/url"
 	"strings"
@@ -21,8 +23,33 @@ type PushOpts struct {
 	Gatherer Gatherer
 	Client   *http.Client
 	Timeout  time.Duration
+	// Retries is the number of additional attempts after a failed push.
+	// Network errors, 5xx, 408 and 429 responses are retried; other 4xx
+	// responses are returned immediately.
+	Retries int
+	// RetryBackoff is the base delay before the first retry. It doubles on
+	// each subsequent retry, up to 30s or RetryBackoff if larger, and is
+	// jittered. Defaults to 100ms.
+	RetryBackoff time.Duration
+	// ConstLabels are added to every pushed metric, e.g. datacenter and app
+	// for pushes from many instances. A metric that already has one of the
+	// labels fails the push.
+	ConstLabels Labels
+	// TextOpts can drop the HELP and TYPE comment lines from the pushed
+	// body.
+	TextOpts EncodeTextOpts
 }
 
+// defaultPushRetryBackoff is the base retry delay when RetryBackoff is unset.
+const defaultPushRetryBackoff = 100 * time.Millisecond
+
+// maxPushRetryBackoff caps the doubled retry delay, unless RetryBackoff
+// itself is larger.
+const maxPushRetryBackoff = 30 * time.Second
+
+// errPushNotRetryable marks a push failure that retrying cannot fix.
+var errPushNotRetryable = errors.New("not retryable")
+
 // Push gathers metrics and pushes them to a remote HTTP endpoint.
 func Push(opts PushOpts) error {
 	if opts.Gatherer == nil {
@@ -48,13 +75,22 @@ func Push(opts PushOpts) error {
 	}
 	base.Path = path
 
+	if err := ValidateLabels(opts.ConstLabels); err != nil {
+		return err
+	}
+
 	families, err := opts.Gatherer.Gather()
 	if err != nil {
 		return err
 	}
+	if len(opts.ConstLabels) > 0 {
+		if families, err = addLabels(families, labelsToLabelPairs(opts.ConstLabels)); err != nil {
+			return err
+		}
+	}
 
 	var buf bytes.Buffer
-	if err := EncodeText(&buf, families); err != nil {
+	if err := EncodeTextWithOpts(&buf, families, opts.TextOpts); err != nil {
 		return err
 	}
 
@@ -65,23 +101,63 @@ func Push(opts PushOpts) error {
 		defer cancel()
 	}
 
-	req, err := http.NewRequestWithContext(ctx, http.MethodPost, base.String(), &buf)
-	if err != nil {
-		return err
-	}
-	req.Header.Set("Content-Type", "text/plain; version=0.0.4")
-
 	client := opts.Client
 	if client == nil {
 		client = http.DefaultClient
 	}
+	backoff := opts.RetryBackoff
+	if backoff <= 0 {
+		backoff = defaultPushRetryBackoff
+	}
+	body := buf.Bytes()
+
+	for attempt := 0; ; attempt++ {
+		err = pushOnce(ctx, client, base.String(), body)
+		if err == nil || errors.Is(err, errPushNotRetryable) || attempt >= opts.Retries {
+			return err
+		}
+
+		timer := time.NewTimer(retryDelay(backoff, attempt))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return fmt.Errorf("%w (last error: %v)", ctx.Err(), err)
+		case <-timer.C:
+		}
+	}
+}
+
+// retryDelay returns the jittered delay before retry attempt+1: backoff
+// doubled attempt times, capped so the shift can't overflow, then jittered
+// into [delay/2, delay].
+func retryDelay(backoff time.Duration, attempt int) time.Duration {
+	limit := max(backoff, maxPushRetryBackoff)
+	delay := limit
+	if backoff <= limit>>attempt {
+		delay = backoff << attempt
+	}
+	return delay/2 + rand.N(delay/2+1)
+}
+
+// pushOnce performs a single push attempt.
+func pushOnce(ctx context.Context, client *http.Client, url string, body []byte) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("%w: %w", errPushNotRetryable, err)
+	}
+	req.Header.Set("Content-Type", "text/plain; version=0.0.4")
+
 	resp, err := client.Do(req)
 	if err != nil {
 		return err
 	}
 	defer resp.Body.Close()
-	if resp.StatusCode/100 != 2 {
+	switch {
+	case resp.StatusCode/100 == 2:
+		return nil
+	case resp.StatusCode/100 == 4 && resp.StatusCode != http.StatusRequestTimeout && resp.StatusCode != http.StatusTooManyRequests:
+		return fmt.Errorf("unexpected status %d: %w", resp.StatusCode, errPushNotRetryable)
+	default:
 		return fmt.Errorf("unexpected status %d", resp.StatusCode)
 	}
-	return nil
 }
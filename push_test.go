@@ -0,0 +1,121 @@
+// Copyright (C) 2020-2026, Lux Industries Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package metric
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// flakyServer answers the first n requests with status, then succeeds.
+func flakyServer(t *testing.T, n int32, status int) (*httptest.Server, *atomic.Int32) {
+	t.Helper()
+	var attempts atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		if attempts.Add(1) <= n {
+			w.WriteHeader(status)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	t.Cleanup(srv.Close)
+	return srv, &attempts
+}
+
+func TestPushRetries(t *testing.T) {
+	srv, attempts := flakyServer(t, 2, http.StatusServiceUnavailable)
+	err := Push(PushOpts{
+		URL:          srv.URL,
+		Job:          "test",
+		Gatherer:     testFamilies(),
+		Retries:      3,
+		RetryBackoff: time.Millisecond,
+	})
+	if err != nil {
+		t.Fatalf("Push: %v", err)
+	}
+	if got := attempts.Load(); got != 3 {
+		t.Fatalf("attempts: got %d, want 3", got)
+	}
+}
+
+func TestPushNoRetryOnClientError(t *testing.T) {
+	srv, attempts := flakyServer(t, 2, http.StatusBadRequest)
+	err := Push(PushOpts{
+		URL:          srv.URL,
+		Gatherer:     testFamilies(),
+		Retries:      3,
+		RetryBackoff: time.Millisecond,
+	})
+	if err == nil {
+		t.Fatal("expected error on 400")
+	}
+	if got := attempts.Load(); got != 1 {
+		t.Fatalf("attempts: got %d, want 1", got)
+	}
+}
+
+func TestPushConstLabels(t *testing.T) {
+	var body string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := io.ReadAll(r.Body)
+		body = string(b)
+	}))
+	defer srv.Close()
+
+	err := Push(PushOpts{
+		URL:         srv.URL,
+		Job:         "test",
+		Gatherer:    testFamilies(),
+		ConstLabels: Labels{"datacenter": "eu1", "app": "node"},
+	})
+	if err != nil {
+		t.Fatalf("Push: %v", err)
+	}
+	if want := `requests_total{app="node",datacenter="eu1"} 1`; !strings.Contains(body, want) {
+		t.Fatalf("missing %q in pushed body:\n%s", want, body)
+	}
+
+	labeled := staticGatherer{{Name: "jobs_total", Type: MetricTypeCounter, Metrics: []Metric{{Labels: []LabelPair{{Name: "app", Value: "other"}}}}}}
+	if err := Push(PushOpts{URL: srv.URL, Gatherer: labeled, ConstLabels: Labels{"app": "node"}}); err == nil {
+		t.Fatal("expected an error for a const label the metric already has")
+	}
+	if err := Push(PushOpts{URL: srv.URL, Gatherer: testFamilies(), ConstLabels: Labels{"bad-name": "x"}}); err == nil {
+		t.Fatal("expected an error for an invalid const label name")
+	}
+}
+
+func TestPushTextOpts(t *testing.T) {
+	var body string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := io.ReadAll(r.Body)
+		body = string(b)
+	}))
+	defer srv.Close()
+
+	err := Push(PushOpts{URL: srv.URL, Gatherer: testFamilies(), TextOpts: EncodeTextOpts{OmitHelp: true, OmitType: true}})
+	if err != nil {
+		t.Fatalf("Push: %v", err)
+	}
+	if body != "requests_total 1\n" {
+		t.Fatalf("pushed body = %q, want only the sample line", body)
+	}
+}
+
+func TestPushRetryDelayCapped(t *testing.T) {
+	for _, attempt := range []int{0, 5, 37, 64, 1000} {
+		delay := retryDelay(defaultPushRetryBackoff, attempt)
+		if delay <= 0 || delay > maxPushRetryBackoff {
+			t.Fatalf("attempt %d: delay %v outside (0, %v]", attempt, delay, maxPushRetryBackoff)
+		}
+	}
+	if delay := retryDelay(time.Minute, 3); delay < 30*time.Second || delay > time.Minute {
+		t.Fatalf("a base backoff above the cap should be kept, got %v", delay)
+	}
+}
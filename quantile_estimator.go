@@ -0,0 +1,270 @@
+// Copyright (C) 2020-2026, Lux Industries Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package metric
+
+import (
+	"math"
+	"sort"
+)
+
+// QuantileEstimator computes a summary's quantiles from its observations in
+// place of the default sample window. Calls are serialized by the summary,
+// so implementations need no locking of their own.
+type QuantileEstimator interface {
+	// Insert adds one finite observation.
+	Insert(v float64)
+	// Query returns the estimated value at quantile q, or false if nothing
+	// has been inserted.
+	Query(q float64) (float64, bool)
+}
+
+// ckmsBufferSize is how many observations a CKMS estimator buffers before
+// merging them into its summary.
+const ckmsBufferSize = 500
+
+// ckmsEstimator is the biased-quantile stream of Cormode, Korn,
+// Muthukrishnan and Srivastava, targeted at fixed quantiles: each target q
+// is answered within rank error epsilon*n, and memory stays bounded.
+type ckmsEstimator struct {
+	targets []ckmsTarget
+	samples []ckmsSample // sorted by value
+	n       float64
+	buf     []float64
+}
+
+type ckmsTarget struct {
+	quantile, epsilon float64
+}
+
+type ckmsSample struct {
+	value, width, delta float64
+}
+
+// NewCKMSEstimator returns a CKMS estimator for objectives, mapping each
+// target quantile to its allowed rank error, as in SummaryOpts.Objectives.
+// Empty objectives mean DefaultObjectives.
+func NewCKMSEstimator(objectives map[float64]float64) QuantileEstimator {
+	if len(objectives) == 0 {
+		objectives = DefaultObjectives()
+	}
+	e := &ckmsEstimator{buf: make([]float64, 0, ckmsBufferSize)}
+	for _, q := range sortedObjectives(objectives) {
+		e.targets = append(e.targets, ckmsTarget{quantile: q, epsilon: objectives[q]})
+	}
+	return e
+}
+
+// Insert buffers v, merging the buffer once it is full.
+func (e *ckmsEstimator) Insert(v float64) {
+	e.buf = append(e.buf, v)
+	if len(e.buf) == cap(e.buf) {
+		e.flush()
+	}
+}
+
+// Query returns the estimate for q. Quantiles other than the targets are
+// answered with the error bound of the nearest target.
+func (e *ckmsEstimator) Query(q float64) (float64, bool) {
+	e.flush()
+	if len(e.samples) == 0 {
+		return 0, false
+	}
+	t := math.Ceil(q * e.n)
+	t += math.Ceil(e.invariant(t) / 2)
+	prev := e.samples[0]
+	var r float64
+	for _, c := range e.samples[1:] {
+		r += prev.width
+		if r+c.width+c.delta > t {
+			return prev.value, true
+		}
+		prev = c
+	}
+	return prev.value, true
+}
+
+// invariant is the allowed width of a sample at rank r.
+func (e *ckmsEstimator) invariant(r float64) float64 {
+	limit := math.MaxFloat64
+	for _, t := range e.targets {
+		var f float64
+		if t.quantile*e.n <= r {
+			f = 2 * t.epsilon * r / t.quantile
+		} else {
+			f = 2 * t.epsilon * (e.n - r) / (1 - t.quantile)
+		}
+		if f < limit {
+			limit = f
+		}
+	}
+	return limit
+}
+
+// flush merges the buffered observations into the samples and compresses.
+func (e *ckmsEstimator) flush() {
+	if len(e.buf) == 0 {
+		return
+	}
+	sort.Float64s(e.buf)
+	var r float64
+	i := 0
+	for _, v := range e.buf {
+		inserted := false
+		for ; i < len(e.samples); i++ {
+			c := e.samples[i]
+			if c.value > v {
+				e.samples = append(e.samples, ckmsSample{})
+				copy(e.samples[i+1:], e.samples[i:])
+				e.samples[i] = ckmsSample{value: v, width: 1, delta: math.Max(0, math.Floor(e.invariant(r))-1)}
+				i++
+				inserted = true
+				break
+			}
+			r += c.width
+		}
+		if !inserted {
+			e.samples = append(e.samples, ckmsSample{value: v, width: 1})
+			i++
+		}
+		e.n++
+		r++
+	}
+	e.buf = e.buf[:0]
+	e.compress()
+}
+
+// compress merges adjacent samples whose combined width stays within the
+// invariant, walking from the top.
+func (e *ckmsEstimator) compress() {
+	if len(e.samples) < 2 {
+		return
+	}
+	xi := len(e.samples) - 1
+	x := e.samples[xi]
+	r := e.n - 1 - x.width
+	for i := len(e.samples) - 2; i >= 0; i-- {
+		c := e.samples[i]
+		if c.width+x.width+x.delta <= e.invariant(r) {
+			x.width += c.width
+			e.samples[xi] = x
+			copy(e.samples[i:], e.samples[i+1:])
+			e.samples = e.samples[:len(e.samples)-1]
+			xi--
+		} else {
+			x = c
+			xi = i
+		}
+		r -= c.width
+	}
+}
+
+// defaultTDigestCompression is the t-digest compression when none is given.
+const defaultTDigestCompression = 100
+
+// tdigestEstimator is a merging t-digest (Dunning): observations are
+// clustered into centroids that are small near the extremes and large in
+// the middle, so tail quantiles stay accurate in bounded memory.
+type tdigestEstimator struct {
+	compression float64
+	centroids   []tdigestCentroid // sorted by mean
+	buf         []tdigestCentroid
+	count       float64
+	min, max    float64
+}
+
+type tdigestCentroid struct {
+	mean, weight float64
+}
+
+// NewTDigestEstimator returns a t-digest estimator. Larger compression keeps
+// more centroids for better accuracy; zero or less means 100.
+func NewTDigestEstimator(compression float64) QuantileEstimator {
+	if !(compression > 0) {
+		compression = defaultTDigestCompression
+	}
+	return &tdigestEstimator{
+		compression: compression,
+		buf:         make([]tdigestCentroid, 0, int(5*compression)),
+		min:         math.Inf(1),
+		max:         math.Inf(-1),
+	}
+}
+
+// Insert buffers v, merging the buffer once it is full.
+func (e *tdigestEstimator) Insert(v float64) {
+	e.buf = append(e.buf, tdigestCentroid{mean: v, weight: 1})
+	e.min = math.Min(e.min, v)
+	e.max = math.Max(e.max, v)
+	if len(e.buf) == cap(e.buf) {
+		e.flush()
+	}
+}
+
+// Query interpolates between centroid centers, and between the outermost
+// centroids and the observed min and max.
+func (e *tdigestEstimator) Query(q float64) (float64, bool) {
+	e.flush()
+	if len(e.centroids) == 0 {
+		return 0, false
+	}
+	if q <= 0 {
+		return e.min, true
+	}
+	if q >= 1 {
+		return e.max, true
+	}
+	target := q * e.count
+	var cumulative float64
+	for i, c := range e.centroids {
+		mid := cumulative + c.weight/2
+		if target < mid {
+			if i == 0 {
+				return e.min + (c.mean-e.min)*target/mid, true
+			}
+			prev := e.centroids[i-1]
+			prevMid := cumulative - prev.weight/2
+			return prev.mean + (c.mean-prev.mean)*(target-prevMid)/(mid-prevMid), true
+		}
+		cumulative += c.weight
+	}
+	last := e.centroids[len(e.centroids)-1]
+	lastMid := e.count - last.weight/2
+	return last.mean + (e.max-last.mean)*(target-lastMid)/(e.count-lastMid), true
+}
+
+// scale is the k1 scale function, mapping a quantile to centroid index
+// space so that a centroid may span at most one unit of k.
+func (e *tdigestEstimator) scale(q float64) float64 {
+	return e.compression / (2 * math.Pi) * math.Asin(2*q-1)
+}
+
+// flush merges the buffered observations into the centroids.
+func (e *tdigestEstimator) flush() {
+	if len(e.buf) == 0 {
+		return
+	}
+	all := append(e.centroids, e.buf...)
+	sort.Slice(all, func(i, j int) bool { return all[i].mean < all[j].mean })
+	for _, c := range e.buf {
+		e.count += c.weight
+	}
+	e.buf = e.buf[:0]
+
+	merged := make([]tdigestCentroid, 0, len(e.centroids)+1)
+	cur := all[0]
+	var before float64
+	kLow := e.scale(0)
+	for _, c := range all[1:] {
+		if e.scale((before+cur.weight+c.weight)/e.count)-kLow <= 1 {
+			cur.weight += c.weight
+			cur.mean += (c.mean - cur.mean) * c.weight / cur.weight
+			continue
+		}
+		before += cur.weight
+		merged = append(merged, cur)
+		kLow = e.scale(before / e.count)
+		cur = c
+	}
+	e.centroids = append(merged, cur)
+}
@@ -0,0 +1,55 @@
+// Copyright (C) 2020-2026, Lux Industries Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package metric
+
+import (
+	"math"
+	"math/rand/v2"
+	"sort"
+	"testing"
+)
+
+// TestQuantileEstimatorTailAccuracy feeds a Pareto (heavy-tailed) stream to
+// both estimators and checks the rank error of their tail quantiles.
+func TestQuantileEstimatorTailAccuracy(t *testing.T) {
+	const n = 100000
+	rng := rand.New(rand.NewPCG(1, 2))
+	data := make([]float64, n)
+	for i := range data {
+		data[i] = math.Pow(1-rng.Float64(), -1/1.5) // Pareto, alpha 1.5
+	}
+	sorted := append([]float64(nil), data...)
+	sort.Float64s(sorted)
+	rankError := func(v, q float64) float64 {
+		rank := sort.SearchFloat64s(sorted, v)
+		return math.Abs(float64(rank)/n - q)
+	}
+
+	estimators := map[string]QuantileEstimator{
+		"ckms":    NewCKMSEstimator(map[float64]float64{0.5: 0.05, 0.99: 0.001, 0.999: 0.0001}),
+		"tdigest": NewTDigestEstimator(100),
+	}
+	for name, e := range estimators {
+		if _, ok := e.Query(0.5); ok {
+			t.Fatalf("%s: expected no estimate before any insert", name)
+		}
+		for _, v := range data {
+			e.Insert(v)
+		}
+		for _, q := range []float64{0.99, 0.999} {
+			v, ok := e.Query(q)
+			if !ok {
+				t.Fatalf("%s: no estimate for %v", name, q)
+			}
+			errRank := rankError(v, q)
+			t.Logf("%s q=%v: estimate %.3f, exact %.3f, rank error %.5f", name, q, v, sorted[int(q*n)], errRank)
+			if errRank > 0.002 {
+				t.Fatalf("%s q=%v: rank error %.5f above 0.002", name, q, errRank)
+			}
+		}
+		if lo, _ := e.Query(0); lo < sorted[0] {
+			t.Fatalf("%s: q=0 estimate %v below the minimum %v", name, lo, sorted[0])
+		}
+	}
+}
@@ -0,0 +1,122 @@
+// Copyright (C) 2020-2026, Lux Industries Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package metric
+
+import (
+	"sync"
+	"time"
+)
+
+// RecordingGatherer is a Gatherer that keeps its last few gathers in memory
+// so recent values can be queried back, e.g. to draw a small graph on a
+// debug page without running a TSDB.
+type RecordingGatherer struct {
+	gatherer Gatherer
+
+	mu   sync.Mutex
+	ring []recordedGather
+	next int
+	full bool
+	now  func() time.Time
+}
+
+type recordedGather struct {
+	time     time.Time
+	families []*MetricFamily
+}
+
+// NewRecordingGatherer wraps g, keeping the families of its last size
+// successful gathers. A size below 1 keeps one.
+func NewRecordingGatherer(g Gatherer, size int) *RecordingGatherer {
+	if size < 1 {
+		size = 1
+	}
+	return &RecordingGatherer{
+		gatherer: g,
+		ring:     make([]recordedGather, size),
+		now:      time.Now,
+	}
+}
+
+// Gather gathers from the wrapped gatherer and records the result unless it
+// failed. The families are stored as returned, so g must not mutate them
+// after returning; registries gather fresh families every time.
+func (rg *RecordingGatherer) Gather() ([]*MetricFamily, error) {
+	families, err := rg.gatherer.Gather()
+	if err != nil {
+		return families, err
+	}
+
+	rg.mu.Lock()
+	rg.ring[rg.next] = recordedGather{time: rg.now(), families: families}
+	rg.next++
+	if rg.next == len(rg.ring) {
+		rg.next = 0
+		rg.full = true
+	}
+	rg.mu.Unlock()
+	return families, nil
+}
+
+// QueryRange returns, oldest first, the recorded values of the series of
+// family name whose labels are exactly labels, from gathers at or after
+// since. Counters, gauges and untyped metrics record their value; histograms
+// and summaries record their sample count.
+func (rg *RecordingGatherer) QueryRange(name string, labels Labels, since time.Time) []Sample {
+	rg.mu.Lock()
+	defer rg.mu.Unlock()
+
+	var (
+		samples []Sample
+		start   = 0
+		n       = rg.next
+	)
+	if rg.full {
+		start, n = rg.next, len(rg.ring)
+	}
+	for i := 0; i < n; i++ {
+		rec := rg.ring[(start+i)%len(rg.ring)]
+		if rec.time.Before(since) {
+			continue
+		}
+		if v, ok := seriesValue(rec.families, name, labels); ok {
+			samples = append(samples, Sample{Time: rec.time, Value: v})
+		}
+	}
+	return samples
+}
+
+// seriesValue finds the series of family name labelled exactly labels.
+func seriesValue(families []*MetricFamily, name string, labels Labels) (float64, bool) {
+	for _, mf := range families {
+		if mf == nil || mf.Name != name {
+			continue
+		}
+		for _, m := range mf.Metrics {
+			if !labelsEqual(m.Labels, labels) {
+				continue
+			}
+			switch mf.Type {
+			case MetricTypeHistogram, MetricTypeSummary:
+				return float64(m.Value.SampleCount), true
+			default:
+				return m.Value.Value, true
+			}
+		}
+	}
+	return 0, false
+}
+
+// labelsEqual reports whether pairs holds exactly the labels in labels.
+func labelsEqual(pairs []LabelPair, labels Labels) bool {
+	if len(pairs) != len(labels) {
+		return false
+	}
+	for _, lp := range pairs {
+		if v, ok := labels[lp.Name]; !ok || v != lp.Value {
+			return false
+		}
+	}
+	return true
+}
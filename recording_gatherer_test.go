@@ -0,0 +1,76 @@
+// Copyright (C) 2020-2026, Lux Industries Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package metric
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+// counterGatherer gathers one labelled counter whose value the test moves.
+type counterGatherer struct{ value float64 }
+
+func (g *counterGatherer) Gather() ([]*MetricFamily, error) {
+	return []*MetricFamily{{
+		Name: "requests_total",
+		Type: MetricTypeCounter,
+		Metrics: []Metric{
+			{Labels: []LabelPair{{Name: "code", Value: "200"}}, Value: MetricValue{Value: g.value}},
+			{Labels: []LabelPair{{Name: "code", Value: "500"}}, Value: MetricValue{Value: 1}},
+		},
+	}}, nil
+}
+
+func TestRecordingGathererQueryRange(t *testing.T) {
+	src := &counterGatherer{}
+	rg := NewRecordingGatherer(src, 3)
+	start := time.Unix(1000, 0)
+	tick := 0
+	rg.now = func() time.Time {
+		tick++
+		return start.Add(time.Duration(tick) * time.Second)
+	}
+
+	// Four gathers into a ring of three: the first is evicted.
+	for _, v := range []float64{1, 4, 9, 16} {
+		src.value = v
+		if _, err := rg.Gather(); err != nil {
+			t.Fatalf("Gather: %v", err)
+		}
+	}
+
+	ok := Labels{"code": "200"}
+	samples := rg.QueryRange("requests_total", ok, time.Time{})
+	want := []float64{4, 9, 16}
+	if len(samples) != len(want) {
+		t.Fatalf("expected %d samples, got %v", len(want), samples)
+	}
+	for i, s := range samples {
+		if s.Value != want[i] || !s.Time.Equal(start.Add(time.Duration(i+2)*time.Second)) {
+			t.Fatalf("sample %d: got %v", i, s)
+		}
+	}
+
+	if samples := rg.QueryRange("requests_total", ok, start.Add(4*time.Second)); len(samples) != 1 || samples[0].Value != 16 {
+		t.Fatalf("expected only the last sample since t=4s, got %v", samples)
+	}
+	if samples := rg.QueryRange("requests_total", nil, time.Time{}); len(samples) != 0 {
+		t.Fatalf("expected no unlabelled series, got %v", samples)
+	}
+	if samples := rg.QueryRange("missing_total", ok, time.Time{}); len(samples) != 0 {
+		t.Fatalf("expected no samples for a missing family, got %v", samples)
+	}
+}
+
+func TestRecordingGathererSkipsErrors(t *testing.T) {
+	errBroken := errors.New("broken source")
+	rg := NewRecordingGatherer(failingGatherer{err: errBroken}, 2)
+	if _, err := rg.Gather(); !errors.Is(err, errBroken) {
+		t.Fatal("expected the wrapped error")
+	}
+	if samples := rg.QueryRange("requests_total", nil, time.Time{}); samples != nil {
+		t.Fatalf("expected nothing recorded, got %v", samples)
+	}
+}
@@ -9,3 +9,19 @@ package metric
 func NewRegistry() Registry {
 	return newRegistry()
 }
+
+// NewRegistryWithLabelLimits returns a new in-process registry that enforces
+// limits on the labels of every series.
+func NewRegistryWithLabelLimits(limits LabelLimits) Registry {
+	reg := newRegistry()
+	reg.labelLimits = limits
+	return reg
+}
+
+// NewRegistryWithLabelNormalizer returns a new in-process registry that
+// passes the labels of every series through normalize before storing them.
+func NewRegistryWithLabelNormalizer(normalize LabelNormalizer) Registry {
+	reg := newRegistry()
+	reg.labelNormalizer = normalize
+	return reg
+}
@@ -0,0 +1,153 @@
+// Copyright (C) 2020-2026, Lux Industries Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package metric
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+)
+
+// equalTolerance is the relative difference below which RegistriesEqual
+// treats two float values as equal, absorbing summation-order noise.
+const equalTolerance = 1e-9
+
+// RegistriesEqual gathers a and b and reports whether they hold the same
+// families, types, series labels and values, ignoring the order families
+// and series are gathered in. Float values are compared within a small
+// relative tolerance. On mismatch the string lists every difference, one
+// per line, for use in test failures.
+func RegistriesEqual(a, b Registry) (bool, string) {
+	fa, err := a.Gather()
+	if err != nil {
+		return false, fmt.Sprintf("gathering a: %v", err)
+	}
+	fb, err := b.Gather()
+	if err != nil {
+		return false, fmt.Sprintf("gathering b: %v", err)
+	}
+
+	var diffs []string
+	ma, mb := familiesByName(fa), familiesByName(fb)
+	for _, name := range unionKeys(ma, mb) {
+		x, y := ma[name], mb[name]
+		switch {
+		case y == nil:
+			diffs = append(diffs, fmt.Sprintf("%s: only in a", name))
+		case x == nil:
+			diffs = append(diffs, fmt.Sprintf("%s: only in b", name))
+		case x.Type != y.Type:
+			diffs = append(diffs, fmt.Sprintf("%s: type %s != %s", name, x.Type, y.Type))
+		default:
+			diffs = append(diffs, diffSeries(name, x, y)...)
+		}
+	}
+	return len(diffs) == 0, strings.Join(diffs, "\n")
+}
+
+// familiesByName indexes families by name, skipping nils.
+func familiesByName(families []*MetricFamily) map[string]*MetricFamily {
+	byName := make(map[string]*MetricFamily, len(families))
+	for _, mf := range families {
+		if mf != nil {
+			byName[mf.Name] = mf
+		}
+	}
+	return byName
+}
+
+// unionKeys returns the keys of a and b, sorted.
+func unionKeys[V any](a, b map[string]V) []string {
+	keys := make([]string, 0, len(a)+len(b))
+	for k := range a {
+		keys = append(keys, k)
+	}
+	for k := range b {
+		if _, ok := a[k]; !ok {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// diffSeries compares the series of two same-typed families by label set.
+func diffSeries(name string, x, y *MetricFamily) []string {
+	sa, sb := seriesByLabels(x), seriesByLabels(y)
+	var diffs []string
+	for _, key := range unionKeys(sa, sb) {
+		ma, okA := sa[key]
+		mb, okB := sb[key]
+		series := name + "{" + key + "}"
+		switch {
+		case !okB:
+			diffs = append(diffs, fmt.Sprintf("%s: only in a", series))
+		case !okA:
+			diffs = append(diffs, fmt.Sprintf("%s: only in b", series))
+		default:
+			if d := diffValue(x.Type, ma.Value, mb.Value); d != "" {
+				diffs = append(diffs, series+": "+d)
+			}
+		}
+	}
+	return diffs
+}
+
+// seriesByLabels indexes a family's series by their sorted label pairs.
+func seriesByLabels(mf *MetricFamily) map[string]Metric {
+	series := make(map[string]Metric, len(mf.Metrics))
+	for _, m := range mf.Metrics {
+		pairs := append([]LabelPair(nil), m.Labels...)
+		sort.Slice(pairs, func(i, j int) bool { return pairs[i].Name < pairs[j].Name })
+		series[formatLabels(pairs)] = m
+	}
+	return series
+}
+
+// diffValue describes how a and b differ, or returns "" if they match.
+func diffValue(typ MetricType, a, b MetricValue) string {
+	switch typ {
+	case MetricTypeHistogram, MetricTypeSummary:
+		if a.SampleCount != b.SampleCount {
+			return fmt.Sprintf("count %d != %d", a.SampleCount, b.SampleCount)
+		}
+		if !floatsEqual(a.SampleSum, b.SampleSum) {
+			return fmt.Sprintf("sum %v != %v", a.SampleSum, b.SampleSum)
+		}
+		if len(a.Buckets) != len(b.Buckets) {
+			return fmt.Sprintf("%d buckets != %d", len(a.Buckets), len(b.Buckets))
+		}
+		for i := range a.Buckets {
+			if a.Buckets[i] != b.Buckets[i] {
+				return fmt.Sprintf("bucket %d %+v != %+v", i, a.Buckets[i], b.Buckets[i])
+			}
+		}
+		if len(a.Quantiles) != len(b.Quantiles) {
+			return fmt.Sprintf("%d quantiles != %d", len(a.Quantiles), len(b.Quantiles))
+		}
+		for i := range a.Quantiles {
+			qa, qb := a.Quantiles[i], b.Quantiles[i]
+			if qa.Quantile != qb.Quantile || !floatsEqual(qa.Value, qb.Value) {
+				return fmt.Sprintf("quantile %+v != %+v", qa, qb)
+			}
+		}
+		return ""
+	default:
+		if !floatsEqual(a.Value, b.Value) {
+			return fmt.Sprintf("value %v != %v", a.Value, b.Value)
+		}
+		return ""
+	}
+}
+
+// floatsEqual reports whether a and b are within equalTolerance of each
+// other, relative to the larger magnitude. Equal infinities and two NaNs
+// match.
+func floatsEqual(a, b float64) bool {
+	if a == b || (math.IsNaN(a) && math.IsNaN(b)) {
+		return true
+	}
+	return math.Abs(a-b) <= equalTolerance*math.Max(math.Abs(a), math.Abs(b))
+}
@@ -9,3 +9,15 @@ package metric
 func NewRegistry() Registry {
 	return NewNoOpRegistry()
 }
+
+// NewRegistryWithLabelLimits returns a no-op registry when metrics are
+// disabled.
+func NewRegistryWithLabelLimits(LabelLimits) Registry {
+	return NewNoOpRegistry()
+}
+
+// NewRegistryWithLabelNormalizer returns a no-op registry when metrics are
+// disabled.
+func NewRegistryWithLabelNormalizer(LabelNormalizer) Registry {
+	return NewNoOpRegistry()
+}
@@ -0,0 +1,700 @@
+//go:build metrics
+
+// Copyright (C) 2020-2026, Lux Industries Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package metric
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// TestGatherDeterministic verifies that repeated gathers encode identically
+// and keep families grouped by type.
+func TestGatherDeterministic(t *testing.T) {
+	reg := NewRegistry()
+	for _, name := range []string{"c_total", "a_total", "b_total"} {
+		reg.NewCounter(name, "help").Inc()
+	}
+	gv := reg.NewGaugeVec("queue_depth", "help", []string{"queue"})
+	for _, q := range []string{"z", "m", "a", "q"} {
+		gv.WithLabelValues(q).Set(1)
+	}
+	reg.NewHistogram("latency_seconds", "help", nil).Observe(1)
+
+	first := encodeFamilies(t, gatherFamilies(t, reg))
+	for i := 0; i < 20; i++ {
+		if got := encodeFamilies(t, gatherFamilies(t, reg)); got != first {
+			t.Fatalf("gather %d produced different output:\n%s\nvs\n%s", i, got, first)
+		}
+	}
+
+	families := gatherFamilies(t, reg)
+	var names []string
+	for _, f := range families {
+		names = append(names, f.Name)
+	}
+	want := []string{"a_total", "b_total", "c_total", "queue_depth", "latency_seconds"}
+	if fmt.Sprint(names) != fmt.Sprint(want) {
+		t.Fatalf("family order: got %v, want %v", names, want)
+	}
+}
+
+func TestNewDuplicateName(t *testing.T) {
+	reg := NewRegistry()
+	c := reg.NewCounter("dup_total", "help")
+	c.Inc()
+	if again := reg.NewCounter("dup_total", "help"); again != c {
+		t.Fatal("expected the existing counter for a repeated name")
+	}
+
+	defer func() {
+		r := recover()
+		if r == nil {
+			t.Fatal("expected a panic creating a gauge under a counter's name")
+		}
+		if !strings.Contains(fmt.Sprint(r), "already registered as counter") {
+			t.Fatalf("unexpected panic: %v", r)
+		}
+	}()
+	reg.NewGauge("dup_total", "help")
+}
+
+func TestRegisterTypeConflict(t *testing.T) {
+	reg := NewRegistry()
+	reg.NewHistogram("conflict", "help", nil)
+	if err := reg.Register(newGauge("conflict", "help")); err == nil {
+		t.Fatal("expected Register to reject a gauge under a histogram's name")
+	}
+}
+
+func TestNewUntyped(t *testing.T) {
+	reg := NewRegistry()
+	u := reg.NewUntyped("imported_value", "third-party value")
+	u.Set(42.5)
+
+	f := findFamily(t, gatherFamilies(t, reg), "imported_value")
+	if f.Type != MetricTypeUntyped || f.Metrics[0].Value.Value != 42.5 {
+		t.Fatalf("unexpected untyped family %+v", f)
+	}
+	out := encodeFamilies(t, []*MetricFamily{f})
+	if !strings.Contains(out, "# TYPE imported_value untyped\n") {
+		t.Fatalf("missing untyped TYPE line:\n%s", out)
+	}
+	if !strings.Contains(out, "imported_value 42.5\n") {
+		t.Fatalf("missing value line:\n%s", out)
+	}
+}
+
+func TestUnregisterByName(t *testing.T) {
+	reg := NewRegistry()
+	reg.NewCounter("byname_total", "help").Inc()
+	cv := reg.NewCounterVec("byname_total", "help", []string{"code"})
+	cv.WithLabelValues("200").Inc()
+	cv.WithLabelValues("500").Inc()
+	reg.NewGauge("kept", "help")
+
+	if n := reg.UnregisterByName("byname_total"); n != 3 {
+		t.Fatalf("expected 3 series removed, got %d", n)
+	}
+	for _, f := range gatherFamilies(t, reg) {
+		if f.Name == "byname_total" {
+			t.Fatal("family still present after UnregisterByName")
+		}
+	}
+	if n := reg.UnregisterByName("byname_total"); n != 0 {
+		t.Fatalf("expected nothing left to remove, got %d", n)
+	}
+	// The name is free again, even for a different type.
+	reg.NewGauge("byname_total", "help")
+}
+
+// TestSnapshotRestore verifies that a snapshot re-applied onto a fresh
+// registry with the same metrics reproduces the original exposition.
+func TestSnapshotRestore(t *testing.T) {
+	populate := func(reg Registry) (Counter, CounterVec, Gauge, Histogram, Summary) {
+		return reg.NewCounter("requests_total", "requests"),
+			reg.NewCounterVec("errors_total", "errors", []string{"code"}),
+			reg.NewGauge("inflight", "inflight"),
+			reg.NewHistogram("latency_seconds", "latency", []float64{0.1, 1}),
+			reg.NewSummary("size_bytes", "size", map[float64]float64{0.5: 0.05})
+	}
+
+	src := NewRegistry()
+	counter, vec, gauge, histogram, summary := populate(src)
+	counter.Add(42)
+	vec.WithLabelValues("500").Add(3)
+	vec.WithLabelValues("404").Inc()
+	gauge.Set(-7)
+	for _, v := range []float64{0.05, 0.5, 5, math.NaN()} {
+		histogram.Observe(v)
+		summary.Observe(v)
+	}
+
+	data, err := src.Snapshot()
+	if err != nil {
+		t.Fatalf("Snapshot: %v", err)
+	}
+
+	dst := NewRegistry()
+	_, dstVec, _, _, _ := populate(dst)
+	if err := dst.Restore(data); err != nil {
+		t.Fatalf("Restore: %v", err)
+	}
+
+	want := encodeFamilies(t, gatherFamilies(t, src))
+	if got := encodeFamilies(t, gatherFamilies(t, dst)); got != want {
+		t.Fatalf("restored exposition differs:\ngot:\n%s\nwant:\n%s", got, want)
+	}
+
+	// Restored vec children are adopted rather than shadowed.
+	dstVec.WithLabelValues("500").Inc()
+	if got := dstVec.WithLabelValues("500").Get(); got != 4 {
+		t.Fatalf("restored vec child: got %v, want 4", got)
+	}
+}
+
+func TestRestoreTypeMismatch(t *testing.T) {
+	src := NewRegistry()
+	src.NewCounter("value", "help").Inc()
+	data, err := src.Snapshot()
+	if err != nil {
+		t.Fatalf("Snapshot: %v", err)
+	}
+
+	dst := NewRegistry()
+	dst.NewGauge("value", "help")
+	if err := dst.Restore(data); err == nil {
+		t.Fatal("expected error restoring a counter onto a gauge")
+	}
+}
+
+func TestRestoreVecLayoutMismatch(t *testing.T) {
+	src := NewRegistry()
+	src.NewHistogramVec("latency_seconds", "latency", []string{"path"}, []float64{0.1, 1}).WithLabelValues("/").Observe(0.5)
+	src.NewSummaryVec("size_bytes", "size", []string{"path"}, map[float64]float64{0.5: 0.05}).WithLabelValues("/").Observe(1)
+	data, err := src.Snapshot()
+	if err != nil {
+		t.Fatalf("Snapshot: %v", err)
+	}
+
+	// The vecs have no children yet, so the layout to check against is the
+	// vec's own rather than the snapshot's.
+	histograms := NewRegistry()
+	histograms.NewHistogramVec("latency_seconds", "latency", []string{"path"}, []float64{5})
+	if err := histograms.Restore(data); err == nil {
+		t.Fatal("expected error restoring onto a histogram vec with different buckets")
+	}
+	summaries := NewRegistry()
+	summaries.NewSummaryVec("size_bytes", "size", []string{"path"}, map[float64]float64{0.9: 0.01})
+	if err := summaries.Restore(data); err == nil {
+		t.Fatal("expected error restoring onto a summary vec with different objectives")
+	}
+}
+
+func TestRestoreRejectsBeforeWriting(t *testing.T) {
+	src := NewRegistry()
+	src.NewCounter("a_total", "a").Add(5)
+	src.NewCounterVec("b_total", "b", []string{"path"}).WithLabelValues("/a/very/long/path").Inc()
+	data, err := src.Snapshot()
+	if err != nil {
+		t.Fatalf("Snapshot: %v", err)
+	}
+
+	dst := NewRegistryWithLabelLimits(LabelLimits{MaxLabelValueLength: 8, Policy: LabelLimitReject})
+	counter := dst.NewCounter("a_total", "a")
+	dst.NewCounterVec("b_total", "b", []string{"path"})
+	if err := dst.Restore(data); !errors.Is(err, ErrLabelLimitExceeded) {
+		t.Fatalf("expected ErrLabelLimitExceeded, got %v", err)
+	}
+	if got := counter.Get(); got != 0 {
+		t.Fatalf("failed Restore wrote a_total = %v", got)
+	}
+}
+
+// TestRegistryConcurrentRegisterAndRead exercises the registry's RWMutex:
+// creating and updating series while gathering and snapshotting must be
+// race-free under -race.
+func TestRegistryConcurrentRegisterAndRead(t *testing.T) {
+	reg := NewRegistry()
+	vec := reg.NewCounterVec("ops_total", "ops", []string{"worker"})
+
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(2)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				reg.NewGauge(fmt.Sprintf("gauge_%d_%d", i, j), "gauge").Set(float64(j))
+				vec.WithLabelValues(fmt.Sprint(j % 10)).Inc()
+			}
+		}(i)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 50; j++ {
+				if _, err := reg.Gather(); err != nil {
+					t.Errorf("Gather: %v", err)
+					return
+				}
+				if _, err := reg.Snapshot(); err != nil {
+					t.Errorf("Snapshot: %v", err)
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	var total float64
+	for j := 0; j < 10; j++ {
+		total += vec.WithLabelValues(fmt.Sprint(j)).Get()
+	}
+	if total != 400 {
+		t.Fatalf("ops_total: got %v, want 400", total)
+	}
+}
+
+func TestGatherFunc(t *testing.T) {
+	reg := NewRegistry()
+	reg.NewCounter("a_total", "a").Inc()
+	reg.NewGauge("b", "b").Set(1)
+	reg.NewHistogram("c_seconds", "c", nil).Observe(1)
+
+	sg, ok := reg.(StreamingGatherer)
+	if !ok {
+		t.Fatal("registry does not implement StreamingGatherer")
+	}
+	families := gatherFamilies(t, reg)
+	var calls int
+	if err := sg.GatherFunc(func(*MetricFamily) error { calls++; return nil }); err != nil {
+		t.Fatalf("GatherFunc: %v", err)
+	}
+	if calls != len(families) {
+		t.Fatalf("callbacks: got %d, want %d", calls, len(families))
+	}
+
+	errStop := fmt.Errorf("stop")
+	calls = 0
+	err := sg.GatherFunc(func(*MetricFamily) error {
+		calls++
+		return errStop
+	})
+	if err != errStop {
+		t.Fatalf("expected fn's error, got %v", err)
+	}
+	if calls != 1 {
+		t.Fatalf("expected iteration to stop after the first error, got %d calls", calls)
+	}
+}
+
+func TestRegistryDescribe(t *testing.T) {
+	reg := NewRegistry()
+	reg.NewCounterVec("described_requests_total", "requests", []string{"method", "code"})
+	reg.NewGauge("described_up", "up").Set(1)
+
+	descriptors := reg.Describe()
+	if len(descriptors) != 2 {
+		t.Fatalf("expected 2 descriptors, got %+v", descriptors)
+	}
+	vec := descriptors[0]
+	if vec.Name != "described_requests_total" || vec.Type != MetricTypeCounter || vec.Help != "requests" {
+		t.Fatalf("unexpected vec descriptor %+v", vec)
+	}
+	if len(vec.LabelNames) != 2 || vec.LabelNames[0] != "method" || vec.LabelNames[1] != "code" {
+		t.Fatalf("expected label names [method code] before any series, got %v", vec.LabelNames)
+	}
+	if up := descriptors[1]; up.Name != "described_up" || up.Type != MetricTypeGauge || up.LabelNames != nil {
+		t.Fatalf("unexpected gauge descriptor %+v", up)
+	}
+}
+
+func TestLabelLimitsReject(t *testing.T) {
+	reg := NewRegistryWithLabelLimits(LabelLimits{MaxLabelValueLength: 8, Policy: LabelLimitReject})
+	cv := reg.NewCounterVec("limited_total", "limited", []string{"path"})
+
+	if _, err := cv.GetMetricWithLabelValues(strings.Repeat("x", 9)); !errors.Is(err, ErrLabelLimitExceeded) {
+		t.Fatalf("expected ErrLabelLimitExceeded, got %v", err)
+	}
+	// With stays usable but the series is never exposed.
+	cv.WithLabelValues(strings.Repeat("y", 64)).Inc()
+	cv.WithLabelValues("ok").Inc()
+
+	f := findFamily(t, gatherFamilies(t, reg), "limited_total")
+	if len(f.Metrics) != 1 {
+		t.Fatalf("expected only the in-limit series, got %d", len(f.Metrics))
+	}
+	if _, ok := findMetricWithLabels(f, Labels{"path": "ok"}); !ok {
+		t.Fatal("missing in-limit series")
+	}
+}
+
+func TestLabelLimitsTruncate(t *testing.T) {
+	reg := NewRegistryWithLabelLimits(LabelLimits{
+		MaxLabelValueLength: 4,
+		MaxLabelsPerMetric:  1,
+		Policy:              LabelLimitTruncate,
+	})
+	cv := reg.NewCounterVec("truncated_total", "truncated", []string{"a", "b"})
+
+	c, err := cv.GetMetricWithLabelValues("abcdefgh", "dropped")
+	if err != nil {
+		t.Fatalf("unexpected error under truncate policy: %v", err)
+	}
+	c.Inc()
+
+	f := findFamily(t, gatherFamilies(t, reg), "truncated_total")
+	if _, ok := findMetricWithLabels(f, Labels{"a": "abcd"}); !ok {
+		t.Fatalf("expected truncated series {a=\"abcd\"}, got %+v", f.Metrics)
+	}
+}
+
+func TestLabelNormalizer(t *testing.T) {
+	reg := NewRegistryWithLabelNormalizer(func(name, value string) (string, string) {
+		return name, strings.ToLower(strings.TrimSpace(value))
+	})
+	cv := reg.NewCounterVec("normalized_total", "normalized", []string{"region"})
+	cv.WithLabelValues("US ").Inc()
+	cv.With(Labels{"region": "us"}).Inc()
+
+	f := findFamily(t, gatherFamilies(t, reg), "normalized_total")
+	if len(f.Metrics) != 1 {
+		t.Fatalf("expected the inputs to collapse to one series, got %+v", f.Metrics)
+	}
+	m, ok := findMetricWithLabels(f, Labels{"region": "us"})
+	if !ok || m.Value.Value != 2 {
+		t.Fatalf("expected {region=\"us\"} 2, got %+v", f.Metrics)
+	}
+}
+
+func TestSetNameSeparator(t *testing.T) {
+	SetNameSeparator(":")
+	defer SetNameSeparator("_")
+
+	reg := NewRegistry()
+	m := NewFactoryWithRegistry(reg).New("app")
+	m.NewCounter("requests_total", "requests").Inc()
+	findFamily(t, gatherFamilies(t, reg), "app:requests_total")
+
+	if got := AppendNamespace("app", "db"); got != "app:db" {
+		t.Fatalf("AppendNamespace = %q, want app:db", got)
+	}
+	NewCounter(CounterOpts{Namespace: "sep", Subsystem: "db", Name: "queries_total", Help: "queries"}).Inc()
+	findFamily(t, gatherFamilies(t, DefaultRegistry), "sep:db:queries_total")
+
+	SetNameSeparator("")
+	m.NewGauge("inflight", "inflight").Set(1)
+	findFamily(t, gatherFamilies(t, reg), "appinflight")
+}
+
+func TestBuildInfoCollector(t *testing.T) {
+	reg := NewRegistry()
+	info := map[string]string{"version": "v1.2.3", "commit": "abc123"}
+	if err := reg.Register(NewBuildInfoCollector("node", info)); err != nil {
+		t.Fatalf("Register: %v", err)
+	}
+
+	f := findFamily(t, gatherFamilies(t, reg), "node_build_info")
+	if f.Type != MetricTypeGauge || len(f.Metrics) != 1 {
+		t.Fatalf("expected a single gauge series, got %s with %d", f.Type, len(f.Metrics))
+	}
+	m, ok := findMetricWithLabels(f, info)
+	if !ok {
+		t.Fatalf("missing series labeled %v: %+v", info, f.Metrics)
+	}
+	if m.Value.Value != 1 {
+		t.Fatalf("value: got %v, want 1", m.Value.Value)
+	}
+}
+
+func TestRegistryHelpFixedAtFirstRegistration(t *testing.T) {
+	reg := newRegistry()
+	vec := reg.NewCounterVec("jobs_total", "jobs run", []string{"queue"})
+	vec.WithLabelValues("a").Inc()
+	// A series registered directly with other help text must not change the
+	// family's HELP line, whichever series sorts first.
+	reg.RegisterLabeledCounter("jobs_total", Labels{"queue": "0"}, newCounter("jobs_total", "stale help"))
+
+	family := findFamily(t, gatherFamilies(t, reg), "jobs_total")
+	if family.Help != "jobs run" {
+		t.Fatalf("help = %q, want %q", family.Help, "jobs run")
+	}
+
+	err := reg.Register(newCounter("jobs_total", "jobs started"))
+	if err == nil || !strings.Contains(err.Error(), "help") {
+		t.Fatalf("expected help conflict, got %v", err)
+	}
+	if err := reg.Register(newCounter("jobs_total", "jobs run")); err != nil {
+		t.Fatalf("register with matching help: %v", err)
+	}
+}
+
+func TestConstructorHelpConflict(t *testing.T) {
+	reg := NewRegistry()
+	counter := reg.NewCounter("retries_total", "retries")
+	// Constructors have no error to return, so they keep the first help
+	// rather than fail.
+	if again := reg.NewCounter("retries_total", "retry attempts"); again != counter {
+		t.Fatal("differing help should still return the existing counter")
+	}
+	if got := findFamily(t, gatherFamilies(t, reg), "retries_total").Help; got != "retries" {
+		t.Fatalf("help = %q, want the first help %q", got, "retries")
+	}
+
+	if err := reg.Register(newCounter("retries_total", "retry attempts")); err == nil || !strings.Contains(err.Error(), "help") {
+		t.Fatalf("Register: expected help conflict, got %v", err)
+	}
+	_, err := reg.RegisterBatch([]MetricSpec{{Kind: MetricTypeCounter, Name: "retries_total", Help: "retry attempts"}})
+	if err == nil || !strings.Contains(err.Error(), "help") {
+		t.Fatalf("RegisterBatch: expected help conflict, got %v", err)
+	}
+	err = reg.NewConstHistogram("wait_seconds", "wait", []Bucket{{UpperBound: 1, CumulativeCount: 1}}, 1, 0.5, Labels{"pool": "a"})
+	if err != nil {
+		t.Fatalf("NewConstHistogram: %v", err)
+	}
+	err = reg.NewConstHistogram("wait_seconds", "queue wait", []Bucket{{UpperBound: 1, CumulativeCount: 1}}, 1, 0.5, Labels{"pool": "b"})
+	if err == nil || !strings.Contains(err.Error(), "help") {
+		t.Fatalf("NewConstHistogram: expected help conflict, got %v", err)
+	}
+}
+
+func TestOpenMetricsUnitFromOpts(t *testing.T) {
+	NewHistogram(HistogramOpts{Name: "unit_test_wait_seconds", Help: "wait", Unit: "seconds"}).Observe(1)
+	NewCounterVec(CounterOpts{Name: "unit_test_read_bytes_total", Help: "read", Unit: "bytes"}, []string{"disk"}).
+		WithLabelValues("sda").Add(512)
+
+	families := gatherFamilies(t, DefaultRegistry)
+	if got := findFamily(t, families, "unit_test_read_bytes_total").Unit; got != "bytes" {
+		t.Fatalf("counter unit = %q, want bytes", got)
+	}
+	var buf bytes.Buffer
+	if err := EncodeOpenMetrics(&buf, []*MetricFamily{findFamily(t, families, "unit_test_wait_seconds")}); err != nil {
+		t.Fatalf("EncodeOpenMetrics: %v", err)
+	}
+	if !strings.Contains(buf.String(), "# UNIT unit_test_wait_seconds seconds\n") {
+		t.Fatalf("missing UNIT line:\n%s", buf.String())
+	}
+}
+
+func TestRegisterBatch(t *testing.T) {
+	reg := NewRegistry()
+	handles, err := reg.RegisterBatch([]MetricSpec{
+		{Kind: MetricTypeCounter, Name: "batch_requests_total", Help: "requests"},
+		{Kind: MetricTypeGauge, Name: "batch_in_flight", Help: "in flight", LabelNames: []string{"route"}},
+		{Kind: MetricTypeHistogram, Name: "batch_latency_seconds", Help: "latency", Buckets: []float64{0.1, 1}},
+		{Kind: MetricTypeSummary, Name: "batch_size_bytes", Help: "size", LabelNames: []string{"op"}},
+	})
+	if err != nil {
+		t.Fatalf("RegisterBatch: %v", err)
+	}
+	if len(handles) != 4 {
+		t.Fatalf("got %d handles, want 4", len(handles))
+	}
+	handles[0].(Counter).Inc()
+	handles[1].(GaugeVec).WithLabelValues("/api").Set(3)
+	handles[2].(Histogram).Observe(0.5)
+	handles[3].(SummaryVec).WithLabelValues("read").Observe(128)
+
+	families := gatherFamilies(t, reg)
+	for _, name := range []string{"batch_requests_total", "batch_in_flight", "batch_latency_seconds", "batch_size_bytes"} {
+		findFamily(t, families, name)
+	}
+	if again := reg.NewCounter("batch_requests_total", "requests"); again != handles[0] {
+		t.Fatal("batch counter is not the one the constructor returns")
+	}
+
+	_, err = reg.RegisterBatch([]MetricSpec{
+		{Kind: MetricTypeGauge, Name: "batch_new_gauge"},
+		{Kind: MetricTypeGauge, Name: "batch_requests_total"},
+	})
+	if err == nil {
+		t.Fatal("expected a type conflict error")
+	}
+	for _, family := range gatherFamilies(t, reg) {
+		if family.Name == "batch_new_gauge" {
+			t.Fatal("failed batch registered some of its metrics")
+		}
+	}
+}
+
+func TestNewConstHistogram(t *testing.T) {
+	reg := NewRegistry()
+	buckets := []Bucket{{UpperBound: 0.1, CumulativeCount: 2}, {UpperBound: 1, CumulativeCount: 5}}
+	if err := reg.NewConstHistogram("query_seconds", "query latency", buckets, 7, 3.5, Labels{"db": "main"}); err != nil {
+		t.Fatalf("NewConstHistogram: %v", err)
+	}
+
+	text := encodeFamilies(t, gatherFamilies(t, reg))
+	for _, want := range []string{
+		"# TYPE query_seconds histogram\n",
+		`query_seconds_bucket{db="main",le="0.1"} 2` + "\n",
+		`query_seconds_bucket{db="main",le="1"} 5` + "\n",
+		`query_seconds_bucket{db="main",le="+Inf"} 7` + "\n",
+		`query_seconds_sum{db="main"} 3.5` + "\n",
+		`query_seconds_count{db="main"} 7` + "\n",
+	} {
+		if !strings.Contains(text, want) {
+			t.Fatalf("missing %q in exposition:\n%s", want, text)
+		}
+	}
+
+	parsed, err := ParseText(strings.NewReader(text))
+	if err != nil {
+		t.Fatalf("ParseText: %v", err)
+	}
+	if parsed["query_seconds"] == nil || parsed["query_seconds"].Type != MetricTypeHistogram {
+		t.Fatalf("round trip lost the histogram family: %+v", parsed["query_seconds"])
+	}
+
+	if err := reg.NewConstHistogram("query_seconds", "query latency", []Bucket{{UpperBound: 1, CumulativeCount: 9}}, 7, 0, nil); err == nil {
+		t.Fatal("expected an error for a bucket count above the total")
+	}
+	if err := reg.NewConstHistogram("query_seconds", "query latency", []Bucket{{UpperBound: 0.1, CumulativeCount: 3}, {UpperBound: 1, CumulativeCount: 1}}, 7, 0, nil); err == nil {
+		t.Fatal("expected an error for decreasing cumulative counts")
+	}
+	reg.NewCounter("jobs_total", "jobs")
+	if err := reg.NewConstHistogram("jobs_total", "jobs", nil, 0, 0, nil); err == nil {
+		t.Fatal("expected a type conflict error")
+	}
+}
+
+func TestRegistryReset(t *testing.T) {
+	reg := NewRegistry()
+	reg.NewCounter("reset_total", "first help").Inc()
+	if err := reg.Register(newGauge("reset_gauge", "help")); err != nil {
+		t.Fatalf("Register: %v", err)
+	}
+
+	reg.Reset()
+	if families := gatherFamilies(t, reg); len(families) != 0 {
+		t.Fatalf("expected an empty registry after Reset, got %d families", len(families))
+	}
+
+	// Names, types and help are all free again.
+	if err := reg.Register(newGauge("reset_gauge", "help")); err != nil {
+		t.Fatalf("re-registering after Reset: %v", err)
+	}
+	if err := reg.Register(newGauge("reset_total", "second help")); err != nil {
+		t.Fatalf("registering a new type under a reset name: %v", err)
+	}
+	if f := findFamily(t, gatherFamilies(t, reg), "reset_total"); f.Type != MetricTypeGauge || f.Help != "second help" {
+		t.Fatalf("family after Reset = %+v", f)
+	}
+}
+
+func TestRegistryStats(t *testing.T) {
+	reg := NewRegistry()
+	reg.NewCounter("stats_total", "help").Inc()
+	requests := reg.NewCounterVec("stats_requests_total", "help", []string{"code"})
+	requests.WithLabelValues("200").Inc()
+	requests.WithLabelValues("500").Inc()
+	reg.NewGauge("stats_gauge", "help")
+	reg.NewGaugeVec("stats_idle", "help", []string{"pool"}) // no series yet
+	reg.NewHistogramVec("stats_seconds", "help", []string{"op"}, nil).WithLabelValues("read").Observe(1)
+	reg.NewSummary("stats_bytes", "help", nil).Observe(1)
+	reg.NewUntyped("stats_untyped", "help")
+
+	want := RegistryStats{Counters: 2, Gauges: 2, Histograms: 1, Summaries: 1, Untyped: 1, Series: 7}
+	if got := reg.Stats(); got != want {
+		t.Fatalf("Stats() = %+v, want %+v", got, want)
+	}
+
+	reg.Reset()
+	if got := reg.Stats(); got != (RegistryStats{}) {
+		t.Fatalf("Stats() after Reset = %+v, want zero", got)
+	}
+}
+
+func TestRegistriesEqual(t *testing.T) {
+	build := func(get float64) Registry {
+		reg := NewRegistry()
+		cv := reg.NewCounterVec("eq_requests_total", "help", []string{"method"})
+		// Create series in a different order than the other registry.
+		if get == 3 {
+			cv.WithLabelValues("post").Add(1)
+			cv.WithLabelValues("get").Add(get)
+		} else {
+			cv.WithLabelValues("get").Add(get)
+			cv.WithLabelValues("post").Add(1)
+		}
+		reg.NewHistogram("eq_seconds", "help", []float64{1}).Observe(0.5)
+		return reg
+	}
+
+	if ok, diff := RegistriesEqual(build(3), build(3+1e-12)); !ok {
+		t.Fatalf("expected equal registries, got diff:\n%s", diff)
+	}
+
+	ok, diff := RegistriesEqual(build(3), build(4))
+	if ok {
+		t.Fatal("expected registries with a differing value to differ")
+	}
+	if want := `eq_requests_total{method="get"}: value 3 != 4`; diff != want {
+		t.Fatalf("diff = %q, want %q", diff, want)
+	}
+}
+
+func TestRegistryAlias(t *testing.T) {
+	reg := NewRegistry()
+	c := reg.NewCounter("http_requests_total", "requests")
+	c.Add(3)
+	if err := reg.Alias("requests_total", "http_requests_total"); err != nil {
+		t.Fatalf("Alias: %v", err)
+	}
+
+	families := gatherFamilies(t, reg)
+	renamed := findFamily(t, families, "http_requests_total")
+	old := findFamily(t, families, "requests_total")
+	if old.Type != MetricTypeCounter || old.Metrics[0].Value.Value != 3 || renamed.Metrics[0].Value.Value != 3 {
+		t.Fatalf("expected both names at 3, got %+v and %+v", renamed, old)
+	}
+	c.Inc()
+	if got := findFamily(t, gatherFamilies(t, reg), "requests_total").Metrics[0].Value.Value; got != 4 {
+		t.Fatalf("alias = %v, want it to follow the counter to 4", got)
+	}
+
+	if err := reg.Alias("http_requests_total", "other_total"); err == nil {
+		t.Fatal("expected an error aliasing a registered name")
+	}
+	if !reg.RemoveAlias("requests_total") || reg.RemoveAlias("requests_total") {
+		t.Fatal("expected RemoveAlias to report the alias exactly once")
+	}
+	for _, f := range gatherFamilies(t, reg) {
+		if f.Name == "requests_total" {
+			t.Fatal("alias still gathered after RemoveAlias")
+		}
+	}
+}
+
+func TestVecLabelNamesValidated(t *testing.T) {
+	constructors := map[string]func(reg Registry, labelNames []string){
+		"counter":   func(reg Registry, l []string) { reg.NewCounterVec("dup_labels_total", "help", l) },
+		"gauge":     func(reg Registry, l []string) { reg.NewGaugeVec("dup_labels", "help", l) },
+		"histogram": func(reg Registry, l []string) { reg.NewHistogramVec("dup_labels_seconds", "help", l, nil) },
+		"summary":   func(reg Registry, l []string) { reg.NewSummaryVec("dup_labels_bytes", "help", l, nil) },
+	}
+	for kind, create := range constructors {
+		for _, labelNames := range [][]string{{"a", "a"}, {"a", "b", "a"}, {"1a"}, {""}} {
+			t.Run(kind+"/"+strings.Join(labelNames, ","), func(t *testing.T) {
+				defer func() {
+					if recover() == nil {
+						t.Fatalf("expected a panic for label names %q", labelNames)
+					}
+				}()
+				create(NewRegistry(), labelNames)
+			})
+		}
+	}
+
+	_, err := NewRegistry().RegisterBatch([]MetricSpec{
+		{Kind: MetricTypeCounter, Name: "batch_dup_total", LabelNames: []string{"code", "code"}},
+	})
+	if err == nil || !strings.Contains(err.Error(), "duplicate label name") {
+		t.Fatalf("RegisterBatch error = %v, want a duplicate label name error", err)
+	}
+}
@@ -12,12 +12,65 @@ type Registerer interface {
 	// to be registered again. Returns true if it was present. Mirrors
 	// prometheus.Registerer.Unregister.
 	Unregister(Collector) bool
+	// UnregisterByName removes every series of the named family, for
+	// callers that no longer hold the collector. Returns the number of
+	// series removed.
+	UnregisterByName(name string) int
+	// NewUntyped creates a gauge-like value exposed with an untyped TYPE
+	// line, for passthrough values whose semantics are unknown.
+	NewUntyped(name, help string) Gauge
 }
 
 // Registry is a registerer that can also gather metric families.
 type Registry interface {
 	Registerer
 	Gatherer
+	// Snapshot serializes the current value of every series so it can be
+	// carried across a process restart.
+	Snapshot() ([]byte, error)
+	// Restore re-applies a Snapshot onto the registered metrics. A name
+	// registered with a different type than in the snapshot is an error.
+	Restore([]byte) error
+	// Describe returns the schema of every family without its values, e.g.
+	// for generating metric documentation.
+	Describe() []MetricDescriptor
+	// RegisterBatch creates many metrics under one lock acquisition and
+	// returns their handles in order. See MetricSpec.
+	RegisterBatch([]MetricSpec) ([]interface{}, error)
+	// NewConstHistogram registers a histogram series with precomputed
+	// cumulative bucket counts, count and sum.
+	NewConstHistogram(name, help string, buckets []Bucket, count uint64, sum float64, labels Labels) error
+	// Reset forgets every registered metric so the registry can be reused
+	// from scratch, e.g. between test cases.
+	Reset()
+	// Stats reports how many families and series the registry holds.
+	Stats() RegistryStats
+	// Alias also exposes family newName under oldName, for renaming a
+	// metric without breaking dashboards. RemoveAlias undoes it.
+	Alias(oldName, newName string) error
+	// RemoveAlias drops an alias, reporting whether oldName was one.
+	RemoveAlias(oldName string) bool
+}
+
+// RegistryStats sizes a registry, e.g. for a self-metric such as
+// metric_registry_series_total. Family counts include vecs that have no
+// series yet.
+type RegistryStats struct {
+	Counters   int
+	Gauges     int
+	Histograms int
+	Summaries  int
+	Untyped    int
+	// Series is the number of label sets across all families.
+	Series int
+}
+
+// MetricDescriptor is the schema of one metric family.
+type MetricDescriptor struct {
+	Name       string
+	Help       string
+	Type       MetricType
+	LabelNames []string
 }
 
 // WrapRegistererWithPrefix returns a Registerer that prefixes every
@@ -48,6 +101,9 @@ type prefixRegisterer struct {
 func (p *prefixRegisterer) Register(c Collector) error   { return p.next.Register(c) }
 func (p *prefixRegisterer) MustRegister(cs ...Collector) { p.next.MustRegister(cs...) }
 func (p *prefixRegisterer) Unregister(c Collector) bool  { return p.next.Unregister(c) }
+func (p *prefixRegisterer) UnregisterByName(name string) int {
+	return p.next.UnregisterByName(p.prefix + name)
+}
 func (p *prefixRegisterer) NewCounter(name, help string) Counter {
 	return p.next.NewCounter(p.prefix+name, help)
 }
@@ -69,6 +125,9 @@ func (p *prefixRegisterer) NewGaugeVec(name, help string, labelNames []string) G
 func (p *prefixRegisterer) NewHistogramVec(name, help string, labelNames []string, buckets []float64) HistogramVec {
 	return p.next.NewHistogramVec(p.prefix+name, help, labelNames, buckets)
 }
+func (p *prefixRegisterer) NewUntyped(name, help string) Gauge {
+	return p.next.NewUntyped(p.prefix+name, help)
+}
 func (p *prefixRegisterer) NewSummaryVec(name, help string, labelNames []string, objectives map[float64]float64) SummaryVec {
 	return p.next.NewSummaryVec(p.prefix+name, help, labelNames, objectives)
 }
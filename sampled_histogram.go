@@ -0,0 +1,87 @@
+// Copyright (C) 2020-2026, Lux Industries Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package metric
+
+import (
+	"math"
+	"math/rand/v2"
+)
+
+// SampledHistogram is a histogram for hot paths that records only a fraction
+// of observations. Counts and the sum are scaled by 1/rate when read, so the
+// exposed distribution approximates the full one at a fraction of the cost.
+type SampledHistogram struct {
+	h    *metricHistogram
+	rate float64
+}
+
+// NewHistogramWithSampleRate creates a histogram that records each
+// observation with probability rate and registers it, like NewHistogram, on
+// DefaultRegistry, where it is gathered with the scaled counts. Creating the
+// same name twice returns the existing histogram. A rate outside (0, 1]
+// records every observation.
+func NewHistogramWithSampleRate(name, help string, buckets []float64, rate float64) *SampledHistogram {
+	if !(rate > 0 && rate <= 1) {
+		rate = 1
+	}
+	return newDerived(name, func() *SampledHistogram {
+		h := newHistogram(name, help, buckets)
+		h.sampleRate = rate
+		return &SampledHistogram{h: h, rate: rate}
+	})
+}
+
+func (sh *SampledHistogram) registerLocked(hpr *registry) {
+	hpr.mustClaimName(sh.h.name, MetricTypeHistogram, sh.h.help)
+	hpr.histograms[sh.h.name] = map[string]*labeledHistogram{"": {histogram: sh.h}}
+}
+
+// Observe records val if it is selected by the sample rate.
+func (sh *SampledHistogram) Observe(val float64) {
+	if sh.rate < 1 && rand.Float64() >= sh.rate {
+		return
+	}
+	sh.h.Observe(val)
+}
+
+// Reset zeroes the histogram.
+func (sh *SampledHistogram) Reset() {
+	sh.h.Reset()
+}
+
+// SampleRate returns the fraction of observations that are recorded.
+func (sh *SampledHistogram) SampleRate() float64 {
+	return sh.rate
+}
+
+// GetCount returns the estimated total count.
+func (sh *SampledHistogram) GetCount() uint64 {
+	return scaleSampled(sh.h.GetCount(), sh.rate)
+}
+
+// GetSum returns the estimated sum.
+func (sh *SampledHistogram) GetSum() float64 {
+	return sh.h.GetSum() / sh.rate
+}
+
+// GetCumulativeCounts returns the estimated cumulative bucket counts.
+func (sh *SampledHistogram) GetCumulativeCounts() []Bucket {
+	buckets := sh.h.GetCumulativeCounts()
+	for i := range buckets {
+		buckets[i].CumulativeCount = scaleSampled(buckets[i].CumulativeCount, sh.rate)
+	}
+	return buckets
+}
+
+// ToMetric returns a Metric representation with counts and sum scaled by
+// 1/rate.
+func (sh *SampledHistogram) ToMetric(labels []LabelPair) Metric {
+	return sh.h.ToMetric(labels)
+}
+
+// scaleSampled converts a count recorded at rate to an estimate of the true
+// count.
+func scaleSampled(n uint64, rate float64) uint64 {
+	return uint64(math.Round(float64(n) / rate))
+}
@@ -3,7 +3,11 @@
 
 package metric
 
-import "io"
+import (
+	"context"
+	"io"
+	"net/http"
+)
 
 // Set groups metrics under a shared registry.
 //
@@ -63,11 +67,46 @@ func (s *Set) NewSummaryVec(name, help string, labelNames []string, objectives m
 	return s.reg.NewSummaryVec(name, help, labelNames, objectives)
 }
 
+// Handler returns an HTTP handler serving the set's metrics, honoring scrape
+// timeouts like HandlerForWithOpts.
+func (s *Set) Handler() http.Handler {
+	return HandlerFor(s.reg)
+}
+
 // Write writes the set metrics to w in the text exposition format.
 func (s *Set) Write(w io.Writer) error {
+	return s.WriteContext(context.Background(), w)
+}
+
+// WriteContext is like Write but checks ctx between families, so encoding
+// a large set aborts promptly with ctx's error once it is canceled.
+func (s *Set) WriteContext(ctx context.Context, w io.Writer) error {
+	return s.WriteContextWithOpts(ctx, w, EncodeTextOpts{})
+}
+
+// WriteContextWithOpts is WriteContext with the comment lines adjusted by
+// opts.
+func (s *Set) WriteContextWithOpts(ctx context.Context, w io.Writer, opts EncodeTextOpts) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	write := func(family *MetricFamily) error {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		return encodeTextFamily(w, family, opts)
+	}
+	if sg, ok := s.reg.(StreamingGatherer); ok {
+		return sg.GatherFunc(write)
+	}
 	families, err := s.reg.Gather()
 	if err != nil {
 		return err
 	}
-	return EncodeText(w, families)
+	for _, family := range families {
+		if err := write(family); err != nil {
+			return err
+		}
+	}
+	return nil
 }
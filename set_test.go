@@ -0,0 +1,70 @@
+// Copyright (C) 2020-2026, Lux Industries Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+//go:build metrics
+
+package metric
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// cancelingWriter cancels its context on the first write.
+type cancelingWriter struct {
+	bytes.Buffer
+	cancel context.CancelFunc
+}
+
+func (w *cancelingWriter) Write(p []byte) (int, error) {
+	w.cancel()
+	return w.Buffer.Write(p)
+}
+
+func TestSetWriteContextCanceled(t *testing.T) {
+	set := NewSet()
+	set.NewCounter("a_total", "a").Inc()
+	set.NewCounter("b_total", "b").Inc()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	w := &cancelingWriter{cancel: cancel}
+	if err := set.WriteContext(ctx, w); !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected context.Canceled, got %v", err)
+	}
+	if bytes.Contains(w.Bytes(), []byte("b_total")) {
+		t.Fatalf("encoding continued after cancellation:\n%s", w.String())
+	}
+
+	var buf bytes.Buffer
+	if err := set.Write(&buf); err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+	if !bytes.Contains(buf.Bytes(), []byte("b_total")) {
+		t.Fatalf("Write missing families:\n%s", buf.String())
+	}
+}
+
+func TestSetHandler(t *testing.T) {
+	s := NewSet()
+	s.NewCounter("set_requests_total", "requests").Add(2)
+
+	for name, h := range map[string]http.Handler{
+		"Set.Handler":       s.Handler(),
+		"HandlerForMetrics": HandlerForMetrics(NewFactoryWithRegistry(s.Registry()).New("")),
+	} {
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+		if rec.Code != http.StatusOK {
+			t.Fatalf("%s: status %d", name, rec.Code)
+		}
+		if !strings.Contains(rec.Body.String(), "set_requests_total 2\n") {
+			t.Fatalf("%s: counter missing from scrape:\n%s", name, rec.Body.String())
+		}
+	}
+}
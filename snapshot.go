@@ -0,0 +1,302 @@
+// Copyright (C) 2020-2026, Lux Industries Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package metric
+
+import (
+	"bytes"
+	"encoding/gob"
+	"fmt"
+	"math"
+	"sync/atomic"
+	"unsafe"
+)
+
+// registrySnapshot is the serialized form produced by Snapshot. gob is used
+// rather than JSON so NaN and ±Inf gauge values survive the round trip.
+type registrySnapshot struct {
+	Series []seriesSnapshot
+}
+
+// seriesSnapshot is the state of a single labeled series.
+type seriesSnapshot struct {
+	Name   string
+	Help   string
+	Type   MetricType
+	Labels Labels
+
+	// Counter, gauge and untyped value.
+	Value float64
+
+	// Histogram and summary state.
+	Count        uint64
+	Sum          float64
+	Buckets      []float64 // histogram upper bounds, without +Inf
+	BucketCounts []uint64  // per-bucket (non-cumulative), including +Inf
+	Objectives   []float64 // summary quantiles
+	Samples      []float64 // summary sample window, oldest first
+	NaNCount     uint64    // histogram and summary NaN observations dropped
+	TimestampMs  int64     // histogram ObserveAt timestamp, 0 if unset
+}
+
+// Snapshot serializes the name, labels and current value of every series in
+// the registry so they can be re-applied with Restore after a restart.
+func (hpr *registry) Snapshot() ([]byte, error) {
+	hpr.mu.RLock()
+	var snap registrySnapshot
+	for _, name := range sortedKeys(hpr.counters) {
+		for _, key := range sortedKeys(hpr.counters[name]) {
+			entry := hpr.counters[name][key]
+			snap.Series = append(snap.Series, seriesSnapshot{
+				Name:   name,
+				Help:   entry.counter.help,
+				Type:   MetricTypeCounter,
+				Labels: entry.labels,
+				Value:  entry.counter.Get(),
+			})
+		}
+	}
+	for _, name := range sortedKeys(hpr.gauges) {
+		for _, key := range sortedKeys(hpr.gauges[name]) {
+			entry := hpr.gauges[name][key]
+			snap.Series = append(snap.Series, seriesSnapshot{
+				Name:   name,
+				Help:   entry.gauge.help,
+				Type:   MetricTypeGauge,
+				Labels: entry.labels,
+				Value:  entry.gauge.Get(),
+			})
+		}
+	}
+	for _, name := range sortedKeys(hpr.histograms) {
+		for _, key := range sortedKeys(hpr.histograms[name]) {
+			entry := hpr.histograms[name][key]
+			snap.Series = append(snap.Series, entry.histogram.snapshot(name, entry.labels))
+		}
+	}
+	for _, name := range sortedKeys(hpr.summaries) {
+		for _, key := range sortedKeys(hpr.summaries[name]) {
+			entry := hpr.summaries[name][key]
+			snap.Series = append(snap.Series, entry.summary.snapshot(name, entry.labels))
+		}
+	}
+	for _, name := range sortedKeys(hpr.untyped) {
+		untyped := hpr.untyped[name]
+		snap.Series = append(snap.Series, seriesSnapshot{
+			Name:  name,
+			Help:  untyped.help,
+			Type:  MetricTypeUntyped,
+			Value: untyped.Get(),
+		})
+	}
+	hpr.mu.RUnlock()
+
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(&snap); err != nil {
+		return nil, fmt.Errorf("encoding snapshot: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// Restore re-applies a Snapshot onto the metrics registered in this registry.
+// Series of names that aren't registered are skipped; labeled series missing
+// from a registered vec are created with the vec's buckets or objectives so
+// the vec picks them up on first use. A name registered with a different
+// type, a histogram or summary whose layout differs from the snapshot, or a
+// series the registry's LabelLimits reject is an error and nothing is
+// restored.
+func (hpr *registry) Restore(data []byte) error {
+	var snap registrySnapshot
+	if err := gob.NewDecoder(bytes.NewReader(data)).Decode(&snap); err != nil {
+		return fmt.Errorf("decoding snapshot: %w", err)
+	}
+
+	// Validate every series before writing any, so a failure leaves the
+	// registry untouched.
+	hpr.mu.RLock()
+	series := make([]seriesSnapshot, 0, len(snap.Series))
+	for _, s := range snap.Series {
+		typ, ok := hpr.types[s.Name]
+		if !ok {
+			continue
+		}
+		if typ != s.Type {
+			hpr.mu.RUnlock()
+			return fmt.Errorf("restoring %q: registered as %s, snapshot has %s", s.Name, typ.String(), s.Type.String())
+		}
+		labels, err := hpr.seriesLabels(s.Labels)
+		if err != nil {
+			hpr.mu.RUnlock()
+			return fmt.Errorf("restoring %q: %w", s.Name, err)
+		}
+		key := labelsKeyFromLabels(labels)
+		switch s.Type {
+		case MetricTypeHistogram:
+			var buckets []float64
+			if entry, ok := hpr.histograms[s.Name][key]; ok {
+				buckets = entry.histogram.buckets
+			} else if vec, ok := hpr.vecs[s.Name]; ok {
+				buckets = bucketLayout(vec.buckets)
+			} else {
+				continue
+			}
+			if !equalBuckets(buckets, s.Buckets) {
+				hpr.mu.RUnlock()
+				return fmt.Errorf("restoring %q: bucket layout differs from snapshot", s.Name)
+			}
+		case MetricTypeSummary:
+			var objectives []float64
+			if entry, ok := hpr.summaries[s.Name][key]; ok {
+				objectives = entry.summary.objectives
+			} else if vec, ok := hpr.vecs[s.Name]; ok {
+				objectives = sortedObjectives(vec.objectives)
+				if len(vec.objectives) == 0 {
+					objectives = sortedObjectives(DefaultObjectives())
+				}
+			} else {
+				continue
+			}
+			if !equalBuckets(objectives, s.Objectives) {
+				hpr.mu.RUnlock()
+				return fmt.Errorf("restoring %q: objectives differ from snapshot", s.Name)
+			}
+		}
+		series = append(series, s)
+	}
+	hpr.mu.RUnlock()
+
+	for _, s := range series {
+		hpr.mu.RLock()
+		vec := hpr.vecs[s.Name]
+		hpr.mu.RUnlock()
+
+		var err error
+		switch s.Type {
+		case MetricTypeCounter:
+			var counter *metricCounter
+			if counter, err = hpr.counterFor(s.Name, s.Help, s.Labels); err == nil {
+				atomic.StoreUint64(&counter.value, math.Float64bits(s.Value))
+			}
+		case MetricTypeGauge:
+			var gauge *metricGauge
+			if gauge, err = hpr.gaugeFor(s.Name, s.Help, s.Labels); err == nil {
+				gauge.Set(s.Value)
+			}
+		case MetricTypeHistogram:
+			var histogram *metricHistogram
+			if histogram, err = hpr.histogramFor(s.Name, s.Help, s.Labels, vec.buckets); err == nil {
+				histogram.restore(s)
+			}
+		case MetricTypeSummary:
+			var summary *metricSummary
+			if summary, err = hpr.summaryFor(s.Name, s.Help, s.Labels, vec.objectives); err == nil {
+				summary.restore(s)
+			}
+		case MetricTypeUntyped:
+			hpr.mu.RLock()
+			untyped := hpr.untyped[s.Name]
+			hpr.mu.RUnlock()
+			if untyped != nil {
+				untyped.Set(s.Value)
+			}
+		}
+		if err != nil {
+			return fmt.Errorf("restoring %q: %w", s.Name, err)
+		}
+	}
+	return nil
+}
+
+func (vh *metricHistogram) snapshot(name string, labels Labels) seriesSnapshot {
+	// One read lock, as in ToMetric, so the count always matches the buckets.
+	vh.mu.RLock()
+	defer vh.mu.RUnlock()
+	counts := make([]uint64, len(vh.bucketCounts))
+	for i := range vh.bucketCounts {
+		counts[i] = atomic.LoadUint64(&vh.bucketCounts[i])
+	}
+	return seriesSnapshot{
+		Name:         name,
+		Help:         vh.help,
+		Type:         MetricTypeHistogram,
+		Labels:       labels,
+		Count:        atomic.LoadUint64(&vh.count),
+		Sum:          math.Float64frombits(atomic.LoadUint64((*uint64)(unsafe.Pointer(&vh.sum)))),
+		Buckets:      append([]float64(nil), vh.buckets...),
+		BucketCounts: counts,
+		NaNCount:     atomic.LoadUint64(&vh.nanCount),
+		TimestampMs:  atomic.LoadInt64(&vh.timestampMs),
+	}
+}
+
+func (vh *metricHistogram) restore(s seriesSnapshot) {
+	vh.mu.Lock()
+	defer vh.mu.Unlock()
+	for i := range vh.bucketCounts {
+		var count uint64
+		if i < len(s.BucketCounts) {
+			count = s.BucketCounts[i]
+		}
+		atomic.StoreUint64(&vh.bucketCounts[i], count)
+	}
+	atomic.StoreUint64(&vh.count, s.Count)
+	atomic.StoreUint64((*uint64)(unsafe.Pointer(&vh.sum)), math.Float64bits(s.Sum))
+	atomic.StoreUint64(&vh.nanCount, s.NaNCount)
+	atomic.StoreInt64(&vh.timestampMs, s.TimestampMs)
+}
+
+func (vs *metricSummary) snapshot(name string, labels Labels) seriesSnapshot {
+	vs.mu.RLock()
+	defer vs.mu.RUnlock()
+	// Unroll the ring buffer so the oldest sample comes first.
+	samples := make([]float64, 0, len(vs.samples))
+	samples = append(samples, vs.samples[vs.sampleIdx:]...)
+	samples = append(samples, vs.samples[:vs.sampleIdx]...)
+	return seriesSnapshot{
+		Name:       name,
+		Help:       vs.help,
+		Type:       MetricTypeSummary,
+		Labels:     labels,
+		Count:      atomic.LoadUint64(&vs.count),
+		Sum:        math.Float64frombits(atomic.LoadUint64((*uint64)(unsafe.Pointer(&vs.sum)))),
+		Objectives: append([]float64(nil), vs.objectives...),
+		Samples:    samples,
+		NaNCount:   atomic.LoadUint64(&vs.nanCount),
+	}
+}
+
+func (vs *metricSummary) restore(s seriesSnapshot) {
+	vs.mu.Lock()
+	defer vs.mu.Unlock()
+	samples := s.Samples
+	if vs.estimator != nil {
+		// Estimator state isn't snapshotted; seed it from any window samples.
+		for _, v := range samples {
+			if !math.IsInf(v, 0) {
+				vs.estimator.Insert(v)
+			}
+		}
+		samples = nil
+	}
+	if len(samples) > vs.maxSamples {
+		samples = samples[len(samples)-vs.maxSamples:]
+	}
+	vs.samples = append(vs.samples[:0], samples...)
+	vs.sampleIdx = 0
+	vs.sortedValid = false
+	atomic.StoreUint64(&vs.count, s.Count)
+	atomic.StoreUint64((*uint64)(unsafe.Pointer(&vs.sum)), math.Float64bits(s.Sum))
+	atomic.StoreUint64(&vs.nanCount, s.NaNCount)
+}
+
+func equalBuckets(a, b []float64) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
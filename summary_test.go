@@ -0,0 +1,262 @@
+//go:build metrics
+
+package metric
+
+import (
+	"math"
+	"sort"
+	"testing"
+	"time"
+)
+
+func TestSummaryNonFinite(t *testing.T) {
+	reg := NewRegistry()
+	s := reg.NewSummary("nonfinite_summary", "summary", map[float64]float64{0.5: 0.05})
+	s.Observe(3)
+	s.Observe(math.NaN())
+	s.Observe(math.Inf(1))
+	s.Observe(math.Inf(-1))
+
+	families := gatherFamilies(t, reg)
+	m := findFamily(t, families, "nonfinite_summary").Metrics[0]
+	if m.Value.SampleCount != 3 {
+		t.Fatalf("expected NaN to be dropped from count, got %d", m.Value.SampleCount)
+	}
+	if m.Value.SampleSum != 3 {
+		t.Fatalf("expected sum to ignore non-finite values, got %v", m.Value.SampleSum)
+	}
+	for _, q := range m.Value.Quantiles {
+		if math.IsNaN(q.Value) {
+			t.Fatalf("quantile %v is NaN", q.Quantile)
+		}
+	}
+
+	nan := findFamily(t, families, "nonfinite_summary_nan_total")
+	if nan.Metrics[0].Value.Value != 1 {
+		t.Fatalf("expected nan_total of 1, got %v", nan.Metrics[0].Value.Value)
+	}
+}
+
+func TestSummaryGetQuantile(t *testing.T) {
+	s := newSummary("quantile_summary", "summary", nil)
+	if _, ok := s.GetQuantile(0.5); ok {
+		t.Fatal("expected no quantile before any observation")
+	}
+	// Observe 101..1 so the buffer is not already sorted.
+	for v := 101; v >= 1; v-- {
+		s.Observe(float64(v))
+	}
+	median, ok := s.GetQuantile(0.5)
+	if !ok || median != 51 {
+		t.Fatalf("median: got %v (ok=%v), want 51", median, ok)
+	}
+	if maxV, _ := s.GetQuantile(1); maxV != 101 {
+		t.Fatalf("max: got %v, want 101", maxV)
+	}
+
+	registered, ok := NewRegistry().NewSummary("quantile_registered", "summary", nil).(QuantileSummary)
+	if !ok {
+		t.Fatal("native summary does not implement QuantileSummary")
+	}
+	registered.Observe(7)
+	if got, ok := registered.GetQuantile(0.5); !ok || got != 7 {
+		t.Fatalf("registered median: got %v (ok=%v), want 7", got, ok)
+	}
+}
+
+func TestSummaryTimer(t *testing.T) {
+	s := newSummary("timer_summary", "summary", nil)
+	timer := NewSummaryTimer(s)
+
+	stop := timer.Start()
+	time.Sleep(10 * time.Millisecond)
+	stop()
+	if got := s.GetCount(); got != 1 {
+		t.Fatalf("expected count 1 after Start/stop, got %d", got)
+	}
+	if got := s.GetSum(); got < 0.01 {
+		t.Fatalf("expected at least 10ms recorded, got %vs", got)
+	}
+
+	timer.ObserveTime(time.Second)
+	if got := s.GetCount(); got != 2 {
+		t.Fatalf("expected count 2 after ObserveTime, got %d", got)
+	}
+}
+
+func TestSummaryVecGetMetricWithLabelValues(t *testing.T) {
+	reg := NewRegistry()
+	sv := reg.NewSummaryVec("checked_bytes", "checked", []string{"method"}, nil)
+
+	if _, err := sv.GetMetricWithLabelValues(); err == nil {
+		t.Fatal("expected an error for too few label values")
+	}
+	if _, err := sv.GetMetricWithLabelValues("GET", "extra"); err == nil {
+		t.Fatal("expected an error for too many label values")
+	}
+	cur := sv.MustCurryWith(Labels{"method": "GET"})
+	if _, err := cur.GetMetricWithLabelValues("extra"); err == nil {
+		t.Fatal("expected an error for too many curried label values")
+	}
+	if _, err := cur.GetMetricWithLabelValues(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+func TestSummaryDefaultObjectives(t *testing.T) {
+	reg := NewRegistry()
+	reg.NewSummary("default_objectives", "summary", nil).Observe(1)
+
+	m := findFamily(t, gatherFamilies(t, reg), "default_objectives").Metrics[0]
+	defaults := DefaultObjectives()
+	if len(m.Value.Quantiles) != len(defaults) {
+		t.Fatalf("quantiles: got %d, want %d", len(m.Value.Quantiles), len(defaults))
+	}
+	for _, q := range m.Value.Quantiles {
+		if _, ok := defaults[q.Quantile]; !ok {
+			t.Fatalf("unexpected quantile %v", q.Quantile)
+		}
+	}
+
+	// Callers get a private copy.
+	defaults[0.75] = 0.01
+	if _, ok := DefaultObjectives()[0.75]; ok {
+		t.Fatal("DefaultObjectives returned a shared map")
+	}
+}
+
+func TestSummarySetObjectives(t *testing.T) {
+	reg := NewRegistry()
+	s := reg.NewSummary("adjustable", "summary", map[float64]float64{0.5: 0.05})
+	for i := 1; i <= 100; i++ {
+		s.Observe(float64(i))
+	}
+
+	ms := s.(*metricSummary)
+	if err := ms.SetObjectives(nil); err == nil {
+		t.Fatal("expected an error for empty objectives")
+	}
+	if err := ms.SetObjectives(map[float64]float64{0.25: 0.05, 0.75: 0.05}); err != nil {
+		t.Fatalf("SetObjectives: %v", err)
+	}
+
+	m := findFamily(t, gatherFamilies(t, reg), "adjustable").Metrics[0]
+	if len(m.Value.Quantiles) != 2 || m.Value.Quantiles[0].Quantile != 0.25 || m.Value.Quantiles[1].Quantile != 0.75 {
+		t.Fatalf("quantiles: got %+v, want 0.25 and 0.75", m.Value.Quantiles)
+	}
+	if m.Value.Quantiles[1].Value < 70 {
+		t.Fatalf("expected the sample window to be kept, got p75=%v", m.Value.Quantiles[1].Value)
+	}
+}
+
+func TestSummaryObjectivesOutOfRange(t *testing.T) {
+	mustPanic := func(name string, fn func()) {
+		t.Helper()
+		defer func() {
+			if recover() == nil {
+				t.Fatalf("%s: expected a panic for an out-of-range objective", name)
+			}
+		}()
+		fn()
+	}
+	reg := NewRegistry()
+	for _, q := range []float64{1.5, -0.1, math.NaN()} {
+		objectives := map[float64]float64{0.5: 0.05, q: 0.01}
+		mustPanic("NewSummary", func() { reg.NewSummary("bad_summary", "summary", objectives) })
+		mustPanic("NewSummaryVec", func() { reg.NewSummaryVec("bad_summary_vec", "summary", []string{"op"}, objectives) })
+	}
+
+	s := reg.NewSummary("edges", "summary", map[float64]float64{0: 0.01, 1: 0.01}).(*metricSummary)
+	if err := s.SetObjectives(map[float64]float64{2: 0.01}); err == nil {
+		t.Fatal("SetObjectives accepted an out-of-range objective")
+	}
+}
+
+func TestSummaryEstimator(t *testing.T) {
+	reg := NewRegistry()
+	setEstimator(reg, "estimated_seconds", func() QuantileEstimator { return NewTDigestEstimator(0) })
+	sv := reg.NewSummaryVec("estimated_seconds", "estimated", []string{"op"}, map[float64]float64{0.5: 0.05, 0.9: 0.01})
+	for i := 1; i <= 1000; i++ {
+		sv.WithLabelValues("read").Observe(float64(i))
+	}
+	sv.WithLabelValues("read").Observe(math.Inf(1))
+
+	m := findFamily(t, gatherFamilies(t, reg), "estimated_seconds").Metrics[0]
+	if m.Value.SampleCount != 1001 {
+		t.Fatalf("count = %d, want 1001", m.Value.SampleCount)
+	}
+	want := map[float64]float64{0.5: 500, 0.9: 900}
+	if len(m.Value.Quantiles) != len(want) {
+		t.Fatalf("quantiles = %+v", m.Value.Quantiles)
+	}
+	for _, q := range m.Value.Quantiles {
+		if math.Abs(q.Value-want[q.Quantile]) > 10 {
+			t.Fatalf("q=%v: got %v, want about %v", q.Quantile, q.Value, want[q.Quantile])
+		}
+	}
+
+	// Summaries of other families keep the sample window.
+	plain := reg.NewSummary("plain_seconds", "plain", nil).(*metricSummary)
+	if plain.estimator != nil {
+		t.Fatal("estimator leaked into another family")
+	}
+}
+
+func TestSummaryObserveSorted(t *testing.T) {
+	objectives := map[float64]float64{0.5: 0.05, 0.9: 0.01, 0.99: 0.001}
+	sorted := newSummary("sorted_summary", "summary", objectives)
+	plain := newSummary("plain_summary", "summary", objectives)
+
+	// Three batches of 500 overflow the 1024-sample window, so the merge has
+	// to drop evicted values.
+	for b := 0; b < 3; b++ {
+		batch := make([]float64, 500)
+		for i := range batch {
+			batch[i] = float64(b*7+i*13%500) / 10
+		}
+		sort.Float64s(batch)
+		for _, v := range batch {
+			plain.Observe(v)
+		}
+		sorted.ObserveSorted(batch)
+	}
+	// An unsorted batch is detected and sorted rather than corrupting the
+	// window, so it lands like the same values observed in order.
+	sorted.ObserveSorted([]float64{90, 3, 41, math.NaN(), 7})
+	for _, v := range []float64{math.NaN(), 3, 7, 41, 90} {
+		plain.Observe(v)
+	}
+
+	window := append([]float64(nil), sorted.samples...)
+	sort.Float64s(window)
+	if !sorted.sortedValid || len(sorted.sortedSamples) != len(window) {
+		t.Fatalf("expected a valid sorted window of %d, got %d (valid %v)", len(window), len(sorted.sortedSamples), sorted.sortedValid)
+	}
+	for i := range window {
+		if sorted.sortedSamples[i] != window[i] {
+			t.Fatalf("sorted window differs at %d: %v != %v", i, sorted.sortedSamples[i], window[i])
+		}
+	}
+
+	if sorted.GetCount() != plain.GetCount() || math.Abs(sorted.GetSum()-plain.GetSum()) > 1e-6 {
+		t.Fatalf("expected count %d sum %v, got count %d sum %v", plain.GetCount(), plain.GetSum(), sorted.GetCount(), sorted.GetSum())
+	}
+	for q := range objectives {
+		want, _ := plain.GetQuantile(q)
+		got, ok := sorted.GetQuantile(q)
+		if !ok || got != want {
+			t.Fatalf("quantile %v: expected %v, got %v", q, want, got)
+		}
+	}
+
+	// A plain Observe drops the sorted copy; gathers fall back to sorting.
+	sorted.Observe(1000)
+	plain.Observe(1000)
+	if sorted.sortedValid {
+		t.Fatal("expected Observe to invalidate the sorted window")
+	}
+	want, _ := plain.GetQuantile(0.99)
+	if got, _ := sorted.GetQuantile(0.99); got != want {
+		t.Fatalf("quantile 0.99 after Observe: expected %v, got %v", want, got)
+	}
+}
@@ -3,10 +3,81 @@
 
 package metric
 
-// TimingMetric measures durations and records them in a histogram.
+import "time"
+
+// TimingMetric measures durations and records them in a histogram or summary.
 type TimingMetric = timingMetric
 
 // NewTimingMetric creates a timing metric bound to the provided histogram.
 func NewTimingMetric(histogram Histogram) *TimingMetric {
 	return newTimingMetric(histogram)
 }
+
+// NewSummaryTimer creates a Timer that records seconds into s, for latency
+// objectives expressed as quantiles rather than buckets.
+func NewSummaryTimer(s Summary) Timer {
+	return newTimingMetric(s)
+}
+
+// CappedTimer is a Timer that can discard implausible durations, e.g. from a
+// timer that spanned a suspend or long pause, counting them instead.
+type CappedTimer interface {
+	Timer
+	// ObserveTimeCapped observes d unless it exceeds maxDuration, in which
+	// case it is discarded and the dropped counter is incremented.
+	ObserveTimeCapped(d, maxDuration time.Duration)
+}
+
+// NewTimingMetricWithDropped creates a CappedTimer recording into histogram
+// that counts the durations it discards in dropped, conventionally a counter
+// named after the histogram with a _dropped_total suffix and registered
+// alongside it.
+func NewTimingMetricWithDropped(histogram Histogram, dropped Counter) CappedTimer {
+	return &cappedTimer{timingMetric: newTimingMetric(histogram), dropped: dropped}
+}
+
+// NewSummaryTimerWithDropped is NewTimingMetricWithDropped for summaries.
+func NewSummaryTimerWithDropped(s Summary, dropped Counter) CappedTimer {
+	return &cappedTimer{timingMetric: newTimingMetric(s), dropped: dropped}
+}
+
+// cappedTimer is a timingMetric with the counter ObserveTimeCapped needs.
+type cappedTimer struct {
+	*timingMetric
+	dropped Counter
+}
+
+func (ct *cappedTimer) ObserveTimeCapped(d, maxDuration time.Duration) {
+	if d > maxDuration {
+		ct.dropped.Inc()
+		return
+	}
+	ct.ObserveTime(d)
+}
+
+// Time records the wall-clock duration of fn, in seconds, into h.
+func Time(h Histogram, fn func()) {
+	defer StartTimer(h)()
+	fn()
+}
+
+// StartTimer starts timing and returns a function that records the elapsed
+// seconds into h when called.
+func StartTimer(h Histogram) func() {
+	start := time.Now()
+	return func() {
+		h.Observe(time.Since(start).Seconds())
+	}
+}
+
+// ObserveSince records the seconds elapsed since start into h, for the
+// start := time.Now(); defer ObserveSince(h, start) pattern.
+func ObserveSince(h Histogram, start time.Time) {
+	h.Observe(time.Since(start).Seconds())
+}
+
+// ObserveSinceMillis is ObserveSince in milliseconds, for histograms whose
+// buckets are laid out in ms.
+func ObserveSinceMillis(h Histogram, start time.Time) {
+	h.Observe(float64(time.Since(start)) / float64(time.Millisecond))
+}
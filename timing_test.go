@@ -0,0 +1,88 @@
+// Copyright (C) 2020-2026, Lux Industries Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package metric
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+// recordingObserver captures observations, independent of build tags.
+type recordingObserver struct {
+	mu     sync.Mutex
+	values []float64
+}
+
+func (r *recordingObserver) Observe(v float64) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.values = append(r.values, v)
+}
+
+func (r *recordingObserver) observed(t *testing.T) float64 {
+	t.Helper()
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if len(r.values) != 1 {
+		t.Fatalf("expected 1 observation, got %d", len(r.values))
+	}
+	return r.values[0]
+}
+
+func TestTime(t *testing.T) {
+	h := &recordingObserver{}
+	Time(h, func() { time.Sleep(10 * time.Millisecond) })
+	if got := h.observed(t); got < 0.01 {
+		t.Fatalf("expected at least 10ms observed, got %vs", got)
+	}
+}
+
+func TestStartTimer(t *testing.T) {
+	h := &recordingObserver{}
+	stop := StartTimer(h)
+	time.Sleep(10 * time.Millisecond)
+	stop()
+	if got := h.observed(t); got < 0.01 {
+		t.Fatalf("expected at least 10ms observed, got %vs", got)
+	}
+}
+
+func TestObserveSince(t *testing.T) {
+	start := time.Now().Add(-250 * time.Millisecond)
+
+	secs := &recordingObserver{}
+	ObserveSince(secs, start)
+	if got := secs.observed(t); got < 0.25 || got > 5 {
+		t.Fatalf("expected about 0.25s observed, got %vs", got)
+	}
+
+	millis := &recordingObserver{}
+	ObserveSinceMillis(millis, start)
+	if got := millis.observed(t); got < 250 || got > 5000 {
+		t.Fatalf("expected about 250ms observed, got %vms", got)
+	}
+}
+
+func TestObserveTimeCapped(t *testing.T) {
+	h := &recordingObserver{}
+	dropped := NewNoopCounter()
+	timer := NewTimingMetricWithDropped(h, dropped)
+
+	timer.ObserveTimeCapped(2*time.Hour, time.Minute)
+	timer.ObserveTimeCapped(500*time.Millisecond, time.Minute)
+
+	if got := h.observed(t); got != 0.5 {
+		t.Fatalf("expected the in-cap 0.5s observed, got %vs", got)
+	}
+	if got := dropped.Get(); got != 1 {
+		t.Fatalf("expected 1 dropped observation, got %v", got)
+	}
+
+	s := &recordingObserver{}
+	NewSummaryTimerWithDropped(s, dropped).ObserveTimeCapped(time.Hour, time.Minute)
+	if got := dropped.Get(); got != 2 {
+		t.Fatalf("expected the summary timer to count its drop, got %v", got)
+	}
+}
@@ -3,6 +3,12 @@
 
 package metric
 
+import (
+	"fmt"
+	"math"
+	"strings"
+)
+
 // MetricType defines the type of a metric.
 type MetricType int32
 
@@ -45,6 +51,15 @@ type MetricValue struct {
 	SampleSum   float64
 	Buckets     []Bucket
 
+	// For native (sparse, exponential) histograms; see NativeHistogram.
+	Schema         int32
+	ZeroThreshold  float64
+	ZeroCount      uint64
+	PositiveSpans  []BucketSpan
+	PositiveDeltas []int64
+	NegativeSpans  []BucketSpan
+	NegativeDeltas []int64
+
 	// For summary
 	Quantiles []Quantile
 }
@@ -55,6 +70,19 @@ type Bucket struct {
 	CumulativeCount uint64
 }
 
+// BucketSpan is a run of consecutive native histogram buckets. Offset is the
+// gap to the previous span's last bucket, or the first bucket's index for the
+// first span.
+type BucketSpan struct {
+	Offset int32
+	Length uint32
+}
+
+// IsNativeHistogram reports whether v carries native histogram buckets.
+func (v MetricValue) IsNativeHistogram() bool {
+	return v.ZeroThreshold > 0 || v.ZeroCount > 0 || len(v.PositiveSpans) > 0 || len(v.NegativeSpans) > 0
+}
+
 // Quantile represents a summary quantile.
 type Quantile struct {
 	Quantile float64
@@ -65,16 +93,76 @@ type Quantile struct {
 type Metric struct {
 	Labels []LabelPair
 	Value  MetricValue
+	// TimestampMs is the sample time in milliseconds since the epoch, for
+	// backfilled data. Zero means unset: the scraper assigns scrape time.
+	TimestampMs int64
 }
 
 // MetricFamily is a collection of metrics with the same name and type.
 type MetricFamily struct {
-	Name    string
-	Help    string
-	Type    MetricType
+	Name string
+	Help string
+	Type MetricType
+	// Unit is the OpenMetrics unit, e.g. "seconds". The name must end in it.
+	Unit    string
 	Metrics []Metric
 }
 
+// Validate reports the first structural problem in mf: an invalid family or
+// label name, a name that does not end in its unit, a classic histogram
+// without a +Inf bucket, or a summary quantile outside [0, 1].
+func (mf *MetricFamily) Validate() error {
+	if err := ValidateMetricName(mf.Name); err != nil {
+		return err
+	}
+	if err := mf.validateUnit(); err != nil {
+		return err
+	}
+	for _, m := range mf.Metrics {
+		for _, l := range m.Labels {
+			if err := ValidateLabelName(l.Name); err != nil {
+				return fmt.Errorf("%s: %w", mf.Name, err)
+			}
+		}
+		switch mf.Type {
+		case MetricTypeHistogram:
+			if m.Value.IsNativeHistogram() {
+				continue
+			}
+			hasInf := false
+			for _, b := range m.Value.Buckets {
+				hasInf = hasInf || math.IsInf(b.UpperBound, 1)
+			}
+			if !hasInf {
+				return fmt.Errorf("%s: histogram has no +Inf bucket", mf.Name)
+			}
+		case MetricTypeSummary:
+			for _, q := range m.Value.Quantiles {
+				if q.Quantile < 0 || q.Quantile > 1 || math.IsNaN(q.Quantile) {
+					return fmt.Errorf("%s: quantile %v outside [0, 1]", mf.Name, q.Quantile)
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// validateUnit checks that a family with a unit is named for it, ignoring a
+// counter's _total suffix.
+func (mf *MetricFamily) validateUnit() error {
+	if mf.Unit == "" {
+		return nil
+	}
+	name := mf.Name
+	if mf.Type == MetricTypeCounter {
+		name = strings.TrimSuffix(name, "_total")
+	}
+	if !strings.HasSuffix(name, "_"+mf.Unit) {
+		return fmt.Errorf("%s: name does not end in unit %q", mf.Name, mf.Unit)
+	}
+	return nil
+}
+
 // ptr returns a pointer to the string (helper for compatibility).
 func ptr(s string) *string {
 	return &s
@@ -0,0 +1,49 @@
+// Copyright (C) 2020-2026, Lux Industries Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package metric
+
+import (
+	"math"
+	"testing"
+)
+
+func TestMetricFamilyValidate(t *testing.T) {
+	valid := func() *MetricFamily {
+		return &MetricFamily{
+			Name: "latency_seconds",
+			Type: MetricTypeHistogram,
+			Metrics: []Metric{{
+				Labels: []LabelPair{{Name: "method", Value: "GET"}},
+				Value: MetricValue{Buckets: []Bucket{
+					{UpperBound: 1, CumulativeCount: 1},
+					{UpperBound: math.Inf(1), CumulativeCount: 2},
+				}},
+			}},
+		}
+	}
+	if err := valid().Validate(); err != nil {
+		t.Fatalf("valid family: %v", err)
+	}
+
+	tests := map[string]func(*MetricFamily){
+		"empty name":         func(mf *MetricFamily) { mf.Name = "" },
+		"invalid label name": func(mf *MetricFamily) { mf.Metrics[0].Labels[0].Name = "bad-label" },
+		"missing +Inf": func(mf *MetricFamily) {
+			mf.Metrics[0].Value.Buckets = mf.Metrics[0].Value.Buckets[:1]
+		},
+		"quantile out of range": func(mf *MetricFamily) {
+			mf.Type = MetricTypeSummary
+			mf.Metrics[0].Value.Quantiles = []Quantile{{Quantile: 1.5}}
+		},
+	}
+	for name, corrupt := range tests {
+		t.Run(name, func(t *testing.T) {
+			mf := valid()
+			corrupt(mf)
+			if err := mf.Validate(); err == nil {
+				t.Fatal("expected an error")
+			}
+		})
+	}
+}
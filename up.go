@@ -0,0 +1,19 @@
+// Copyright (C) 2020-2026, Lux Industries Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package metric
+
+// UpMetricName is the gauge SetUp reports liveness in.
+const UpMetricName = "up"
+
+const upHelp = "1 if the job is up, 0 if it is down."
+
+// SetUp reports the liveness of job as the canonical up{job="..."} gauge in
+// reg: 1 when up, 0 when down.
+func SetUp(reg Registry, job string, up bool) {
+	value := 0.0
+	if up {
+		value = 1
+	}
+	reg.NewGaugeVec(UpMetricName, upHelp, []string{"job"}).WithLabelValues(job).Set(value)
+}
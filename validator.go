@@ -54,3 +54,27 @@ func IsValidMetricName(name string) bool {
 func IsValidLabelName(name string) bool {
 	return ValidateLabelName(name) == nil
 }
+
+// validateLabelNames reports an invalid or repeated label name in a vec's
+// label names. Duplicates would make two label sets share a key.
+func validateLabelNames(name string, labelNames []string) error {
+	seen := make(map[string]struct{}, len(labelNames))
+	for _, label := range labelNames {
+		if err := ValidateLabelName(label); err != nil {
+			return fmt.Errorf("metric %q: %w", name, err)
+		}
+		if _, ok := seen[label]; ok {
+			return fmt.Errorf("metric %q: duplicate label name %q", name, label)
+		}
+		seen[label] = struct{}{}
+	}
+	return nil
+}
+
+// mustValidLabelNames is validateLabelNames for constructors, which have no
+// error return.
+func mustValidLabelNames(name string, labelNames []string) {
+	if err := validateLabelNames(name, labelNames); err != nil {
+		panic(err)
+	}
+}